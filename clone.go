@@ -0,0 +1,191 @@
+package schema
+
+import "regexp"
+
+// Clone returns a deep copy of the schema tree rooted at `s`.
+//
+// The parent pointers within the copy refer to the copied schemas,
+// and the copy gets its own resolver and resolution cache, so that
+// it can be modified and resolved independently of the original.
+// Schemas that appear more than once in the tree (including those
+// that refer back to their ancestors) are copied once, and are shared
+// within the copy in the same manner as in the original.
+//
+// The copy is detached from the schemas that contain `s`: if `s` is
+// a subschema, the copy becomes a root of its own.
+func (s *Schema) Clone() *Schema {
+	if s == nil {
+		return nil
+	}
+
+	c := cloner{copies: make(map[*Schema]*Schema)}
+	root := c.clone(s)
+
+	// Re-wire the parents, now that all schemas have been copied.
+	// Parents that are not part of the copied tree are dropped
+	for orig, dup := range c.copies {
+		if p, ok := c.copies[orig.parent]; ok && orig != s {
+			dup.parent = p
+			dup.location = copyStrings(orig.location)
+		}
+	}
+	return root
+}
+
+// cloner keeps track of the schemas that have already been copied
+type cloner struct {
+	copies map[*Schema]*Schema
+}
+
+func (c *cloner) clone(s *Schema) *Schema {
+	if s == nil {
+		return nil
+	}
+	if dup, ok := c.copies[s]; ok {
+		return dup
+	}
+
+	dup := New()
+	c.copies[s] = dup
+
+	dup.position = s.position
+	dup.keywordPos = copyPositions(s.keywordPos)
+	dup.keywords = copyStrings(s.keywords)
+	dup.roundTrip = s.roundTrip
+	dup.keepOrder = s.keepOrder
+	if s.memberOrder != nil {
+		dup.memberOrder = make(map[string][]string, len(s.memberOrder))
+		for k, v := range s.memberOrder {
+			dup.memberOrder[k] = copyStrings(v)
+		}
+	}
+
+	dup.ID = s.ID
+	dup.Title = s.Title
+	dup.Description = s.Description
+	dup.Default = copyValue(s.Default)
+	if s.Type != nil {
+		dup.Type = append(PrimitiveTypes(nil), s.Type...)
+	}
+	dup.SchemaRef = s.SchemaRef
+	dup.Definitions = c.cloneMap(s.Definitions)
+	dup.Reference = s.Reference
+	dup.Format = s.Format
+
+	dup.MultipleOf = s.MultipleOf
+	dup.Minimum = s.Minimum
+	dup.Maximum = s.Maximum
+	dup.ExclusiveMinimum = s.ExclusiveMinimum
+	dup.ExclusiveMaximum = s.ExclusiveMaximum
+
+	dup.MaxLength = s.MaxLength
+	dup.MinLength = s.MinLength
+	// Compiled regular expressions are immutable, and safe to share
+	dup.Pattern = s.Pattern
+
+	if s.AdditionalItems != nil {
+		dup.AdditionalItems = &AdditionalItems{Schema: c.clone(s.AdditionalItems.Schema)}
+	}
+	if s.Items != nil {
+		dup.Items = &ItemSpec{
+			TupleMode: s.Items.TupleMode,
+			Schemas:   c.cloneList(s.Items.Schemas),
+		}
+	}
+	dup.MinItems = s.MinItems
+	dup.MaxItems = s.MaxItems
+	dup.UniqueItems = s.UniqueItems
+
+	dup.MaxProperties = s.MaxProperties
+	dup.MinProperties = s.MinProperties
+	dup.Required = copyStrings(s.Required)
+	if s.Dependencies.Names != nil {
+		dup.Dependencies.Names = make(map[string][]string, len(s.Dependencies.Names))
+		for k, v := range s.Dependencies.Names {
+			dup.Dependencies.Names[k] = copyStrings(v)
+		}
+	}
+	dup.Dependencies.Schemas = c.cloneMap(s.Dependencies.Schemas)
+	dup.Properties = c.cloneMap(s.Properties)
+	if s.AdditionalProperties != nil {
+		dup.AdditionalProperties = &AdditionalProperties{Schema: c.clone(s.AdditionalProperties.Schema)}
+	}
+	if s.PatternProperties != nil {
+		dup.PatternProperties = make(map[*regexp.Regexp]*Schema, len(s.PatternProperties))
+		for rx, v := range s.PatternProperties {
+			dup.PatternProperties[rx] = c.clone(v)
+		}
+	}
+
+	if s.Enum != nil {
+		dup.Enum = copyValue(s.Enum).([]interface{})
+	}
+	dup.AllOf = c.cloneList(s.AllOf)
+	dup.AnyOf = c.cloneList(s.AnyOf)
+	dup.OneOf = c.cloneList(s.OneOf)
+	dup.Not = c.clone(s.Not)
+	if s.Extras != nil {
+		dup.Extras = copyValue(s.Extras).(map[string]interface{})
+	}
+
+	return dup
+}
+
+func (c *cloner) cloneMap(m map[string]*Schema) map[string]*Schema {
+	if m == nil {
+		return nil
+	}
+	dup := make(map[string]*Schema, len(m))
+	for k, v := range m {
+		dup[k] = c.clone(v)
+	}
+	return dup
+}
+
+func (c *cloner) cloneList(l SchemaList) SchemaList {
+	if l == nil {
+		return nil
+	}
+	dup := make(SchemaList, len(l))
+	for i, v := range l {
+		dup[i] = c.clone(v)
+	}
+	return dup
+}
+
+func copyStrings(l []string) []string {
+	if l == nil {
+		return nil
+	}
+	return append([]string(nil), l...)
+}
+
+func copyPositions(m map[string]Position) map[string]Position {
+	if m == nil {
+		return nil
+	}
+	dup := make(map[string]Position, len(m))
+	for k, v := range m {
+		dup[k] = v
+	}
+	return dup
+}
+
+// copyValue returns a deep copy of a value decoded from JSON
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		dup := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			dup[k] = copyValue(e)
+		}
+		return dup
+	case []interface{}:
+		dup := make([]interface{}, len(v))
+		for i, e := range v {
+			dup[i] = copyValue(e)
+		}
+		return dup
+	}
+	return v
+}
@@ -0,0 +1,100 @@
+package schema_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClone(t *testing.T) {
+	const src = `{
+  "definitions": {
+    "name": {"type": "string", "enum": ["foo", "bar"]}
+  },
+  "properties": {
+    "first": {"$ref": "#/definitions/name"},
+    "tags": {"type": "array", "items": {"type": "string"}},
+    "extra": {"additionalProperties": {"type": "integer"}}
+  },
+  "patternProperties": {
+    "^x-": {"type": "string"}
+  },
+  "required": ["first"]
+}`
+	s, err := schema.Read(strings.NewReader(src))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	c := s.Clone()
+	if !assert.Equal(t, s, c, "clone should be identical to the original") {
+		return
+	}
+
+	first := c.Properties["first"]
+	if !assert.True(t, first != s.Properties["first"], "subschemas should be copied") {
+		return
+	}
+	if !assert.True(t, first.Root() == c, "parents should point to the copy") {
+		return
+	}
+	if !assert.Equal(t, "#/properties/tags/items", c.Properties["tags"].Items.Schemas[0].Pointer(), "locations should be copied") {
+		return
+	}
+
+	resolved, err := first.Resolve(nil)
+	if !assert.NoError(t, err, "Resolve should succeed") {
+		return
+	}
+	if !assert.True(t, resolved == c.Definitions["name"], "references should resolve within the copy") {
+		return
+	}
+
+	// Modifying the copy should leave the original alone
+	c.Required[0] = "second"
+	c.Definitions["name"].Enum[0] = "baz"
+	c.Properties["extra"].AdditionalProperties.Type = schema.PrimitiveTypes{schema.StringType}
+	if !assert.Equal(t, []string{"first"}, s.Required, "required should be copied") {
+		return
+	}
+	if !assert.Equal(t, "foo", s.Definitions["name"].Enum[0], "enum should be copied") {
+		return
+	}
+	if !assert.Equal(t, schema.PrimitiveTypes{schema.IntegerType}, s.Properties["extra"].AdditionalProperties.Type, "additionalProperties should be copied") {
+		return
+	}
+
+	// A subschema becomes a root of its own
+	sub := s.Properties["tags"].Clone()
+	if !assert.True(t, sub.Root() == sub, "cloned subschema should be a root") {
+		return
+	}
+	if !assert.Equal(t, "#/items", sub.Items.Schemas[0].Pointer(), "cloned subschema pointers should be relative to the copy") {
+		return
+	}
+}
+
+func TestCloneSharedReferences(t *testing.T) {
+	shared := schema.New()
+	shared.Type = schema.PrimitiveTypes{schema.StringType}
+
+	s := schema.New()
+	s.Properties = map[string]*schema.Schema{
+		"a":    shared,
+		"b":    shared,
+		"self": s,
+	}
+
+	c := s.Clone()
+	if !assert.True(t, c.Properties["a"] == c.Properties["b"], "shared schemas should remain shared") {
+		return
+	}
+	if !assert.True(t, c.Properties["a"] != shared, "shared schemas should be copied") {
+		return
+	}
+	if !assert.True(t, c.Properties["self"] == c, "recursive references should point to the copy") {
+		return
+	}
+}
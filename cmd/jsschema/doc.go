@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/lestrrat-go/jsschema/docgen"
+)
+
+// docMain writes the documentation of a schema file
+func docMain(args []string) int {
+	fs := flag.NewFlagSet("doc", flag.ContinueOnError)
+	format := fs.String("format", "", "output format, markdown or html (default: html if the output file has a .html extension, markdown otherwise)")
+	title := fs.String("title", "", "title of the document")
+	output := fs.String("o", "", "file to write to, instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	if fs.NArg() != 1 {
+		usage()
+		return 1
+	}
+
+	generate := docgen.GenerateMarkdown
+	switch f := *format; {
+	case f == "html", f == "" && isHTMLFile(*output):
+		generate = docgen.GenerateHTML
+	case f == "", f == "markdown", f == "md":
+	default:
+		log.Printf("unknown format %s", f)
+		return 1
+	}
+
+	s, err := schema.ReadFile(fs.Arg(0))
+	if err != nil {
+		log.Printf("failed to read schema: %s", err)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Printf("failed to create %s: %s", *output, err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+
+	var options []docgen.Option
+	if *title != "" {
+		options = append(options, docgen.WithTitle(*title))
+	}
+	if err := generate(w, s, options...); err != nil {
+		log.Printf("failed to generate documentation: %s", err)
+		return 1
+	}
+	return 0
+}
+
+func isHTMLFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".html", ".htm":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/lestrrat-go/jsschema"
+)
+
+// fmtMain rewrites the given schema files in canonical form.
+// Files with a .yaml or .yml extension are formatted as YAML.
+// If no files are given, the schema is read from stdin and
+// written to stdout
+func fmtMain(args []string) int {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	list := fs.Bool("l", false, "list files whose formatting differs, instead of rewriting them")
+	indent := fs.String("indent", "  ", "string used for each level of indentation")
+	yamlInput := fs.Bool("yaml", false, "read and write YAML when formatting stdin")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	if fs.NArg() == 0 {
+		buf, err := formatSchema(os.Stdin, *indent, *yamlInput)
+		if err != nil {
+			log.Printf("failed to format schema: %s", err)
+			return 1
+		}
+		os.Stdout.Write(buf)
+		return 0
+	}
+
+	status := 0
+	for _, file := range fs.Args() {
+		if err := formatFile(file, *indent, *list); err != nil {
+			log.Printf("failed to format %s: %s", file, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+func formatFile(file, indent string, list bool) error {
+	src, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	buf, err := formatSchema(bytes.NewReader(src), indent, schema.IsYAMLFile(file))
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(src, buf) {
+		return nil
+	}
+
+	if list {
+		os.Stdout.Write([]byte(file + "\n"))
+		return nil
+	}
+
+	fi, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, buf, fi.Mode())
+}
+
+func formatSchema(in io.Reader, indent string, yamlFormat bool) ([]byte, error) {
+	read := schema.Read
+	if yamlFormat {
+		read = schema.ReadYAML
+	}
+
+	// Read in round-trip mode, with exact numbers, so that
+	// formatting does not change the content of the schema
+	s, err := read(in, schema.WithPreserveKeyOrder(true), schema.WithUseNumber(true))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := schema.NewEncoder(&buf)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(s); err != nil {
+		return nil, err
+	}
+
+	if yamlFormat {
+		// YAML indentation is always made of spaces
+		return schema.JSONToYAML(buf.Bytes(), len(indent))
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatFileNumbers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsschema-fmt")
+	if !assert.NoError(t, err, "ioutil.TempDir should succeed") {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"schema.json": `{"enum": [12345678901234567890, 0.30000000000000001], "default": 9007199254740993, "x-big": 12345678901234567890}`,
+		"schema.yaml": "enum: [12345678901234567890, 0.30000000000000001]\ndefault: 9007199254740993\nx-big: 12345678901234567890\n",
+	}
+	for name, src := range files {
+		file := filepath.Join(dir, name)
+		if !assert.NoError(t, ioutil.WriteFile(file, []byte(src), 0644), "ioutil.WriteFile should succeed") {
+			return
+		}
+		if !assert.NoError(t, formatFile(file, "  ", false), "formatFile(%s) should succeed", name) {
+			return
+		}
+		buf, err := ioutil.ReadFile(file)
+		if !assert.NoError(t, err, "ioutil.ReadFile should succeed") {
+			return
+		}
+		for _, literal := range []string{"12345678901234567890", "0.30000000000000001", "9007199254740993"} {
+			if !assert.True(t, strings.Contains(string(buf), literal), "%s should keep %s:\n%s", name, literal, buf) {
+				return
+			}
+		}
+	}
+}
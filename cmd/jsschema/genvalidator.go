@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/lestrrat-go/jsschema/codegen"
+)
+
+// genValidatorMain writes Go code that validates values against a schema file
+func genValidatorMain(args []string) int {
+	fs := flag.NewFlagSet("gen-validator", flag.ContinueOnError)
+	pkg := fs.String("package", "schema", "name of the generated package")
+	typ := fs.String("type", "", "name of the type generated for the root schema")
+	output := fs.String("o", "", "file to write to, instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	if fs.NArg() != 1 {
+		usage()
+		return 1
+	}
+
+	s, err := schema.ReadFile(fs.Arg(0))
+	if err != nil {
+		log.Printf("failed to read schema: %s", err)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Printf("failed to create %s: %s", *output, err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := codegen.GenerateGoValidator(w, s, codegen.WithPackageName(*pkg), codegen.WithTypeName(*typ)); err != nil {
+		log.Printf("failed to generate code: %s", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/lestrrat-go/jsschema/infer"
+)
+
+// inferMain writes a schema describing the JSON documents in the given
+// files. Each file may contain several documents, one after another.
+// If no files are given, the documents are read from stdin
+func inferMain(args []string) int {
+	fs := flag.NewFlagSet("infer", flag.ContinueOnError)
+	enum := fs.Int("enum", 0, "maximum number of distinct values for strings to be described as an enum")
+	formats := fs.Bool("formats", true, "detect the formats of strings")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	i := infer.New(infer.WithEnumThreshold(*enum), infer.WithFormats(*formats))
+	if fs.NArg() == 0 {
+		if err := addSamples(i, os.Stdin); err != nil {
+			log.Printf("failed to read samples: %s", err)
+			return 1
+		}
+	}
+	for _, file := range fs.Args() {
+		f, err := os.Open(file)
+		if err != nil {
+			log.Printf("failed to open %s: %s", file, err)
+			return 1
+		}
+		err = addSamples(i, f)
+		f.Close()
+		if err != nil {
+			log.Printf("failed to read samples from %s: %s", file, err)
+			return 1
+		}
+	}
+
+	s, err := i.Schema()
+	if err != nil {
+		log.Printf("failed to infer schema: %s", err)
+		return 1
+	}
+
+	enc := schema.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		log.Printf("failed to encode schema: %s", err)
+		return 1
+	}
+	return 0
+}
+
+func addSamples(i *infer.Inferrer, in io.Reader) error {
+	dec := json.NewDecoder(in)
+	dec.UseNumber()
+	for {
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err := i.Add(v); err != nil {
+			return err
+		}
+	}
+}
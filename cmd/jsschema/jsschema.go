@@ -17,6 +17,13 @@ func main() {
 
 func usage() {
 	fmt.Printf("jsschema [schema file] [target file]\n")
+	fmt.Printf("  (files with a .yaml or .yml extension are read and written as YAML)\n")
+	fmt.Printf("jsschema doc [-format markdown|html] [-title title] [-o file] [schema file]\n")
+	fmt.Printf("jsschema fmt [-l] [-indent string] [-yaml] [schema file...]\n")
+	fmt.Printf("jsschema gen-go [-package name] [-type name] [-o file] [schema file]\n")
+	fmt.Printf("jsschema gen-ts [-type name] [-o file] [schema file]\n")
+	fmt.Printf("jsschema gen-validator [-package name] [-type name] [-o file] [schema file]\n")
+	fmt.Printf("jsschema infer [-enum n] [-formats=false] [sample file...]\n")
 }
 
 func dumpJSON(v interface{}) error {
@@ -31,26 +38,55 @@ func dumpJSON(v interface{}) error {
 	return nil
 }
 
+func dumpYAML(v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err == nil {
+		buf, err = schema.JSONToYAML(buf, 4)
+	}
+	if err != nil {
+		log.Printf("failed to encode to YAML: %s", err)
+		return err
+	}
+
+	os.Stdout.Write(buf)
+	return nil
+}
+
 func _main() int {
 	if len(os.Args) < 2 {
 		usage()
 		return 1
 	}
 
-	schemaf, err := os.Open(os.Args[1])
-	if err != nil {
-		log.Printf("failed to open schema: %s", err)
-		return 1
+	switch os.Args[1] {
+	case "doc":
+		return docMain(os.Args[2:])
+	case "fmt":
+		return fmtMain(os.Args[2:])
+	case "gen-go":
+		return genGoMain(os.Args[2:])
+	case "gen-ts":
+		return genTSMain(os.Args[2:])
+	case "gen-validator":
+		return genValidatorMain(os.Args[2:])
+	case "infer":
+		return inferMain(os.Args[2:])
 	}
-	defer schemaf.Close()
 
-	s, err := schema.Read(schemaf)
+	// The schema and the data are emitted in the same format
+	// as the schema file (JSON or YAML)
+	dump := dumpJSON
+	if schema.IsYAMLFile(os.Args[1]) {
+		dump = dumpYAML
+	}
+
+	s, err := schema.ReadFile(os.Args[1])
 	if err != nil {
 		log.Printf("failed to read schema: %s", err)
 		return 1
 	}
 
-	if err := dumpJSON(s); err != nil {
+	if err := dump(s); err != nil {
 		return 1
 	}
 
@@ -72,18 +108,23 @@ func _main() int {
 	}
 
 	var v interface{}
-	if err := json.Unmarshal(in, &v); err != nil {
+	if schema.IsYAMLFile(os.Args[2]) {
+		v, err = schema.DecodeYAML(in)
+	} else {
+		err = json.Unmarshal(in, &v)
+	}
+	if err != nil {
 		log.Printf("failed to decode data: %s", err)
 		return 1
 	}
 
 	valid := validator.New(s)
 	if err := valid.Validate(v); err != nil {
-		log.Printf("validation failed")
+		log.Printf("validation failed: %s", err)
 		return 1
 	}
 
-	if err := dumpJSON(v); err != nil {
+	if err := dump(v); err != nil {
 		return 1
 	}
 
@@ -0,0 +1,85 @@
+// Package codegen generates source code from JSON schemas.
+package codegen
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lestrrat-go/jsschema/internal/option"
+)
+
+// Option is an option that can be passed to the generators
+type Option interface {
+	option.Interface
+}
+
+const (
+	optkeyPackageName = "package-name"
+	optkeyTypeName    = "type-name"
+)
+
+// WithPackageName specifies the name of the package that the generated
+// Go code belongs to. The default is "schema".
+func WithPackageName(s string) Option {
+	return option.New(optkeyPackageName, s)
+}
+
+// WithTypeName specifies the name of the type generated for the root
+// schema. By default the name is derived from the title of the schema,
+// or "Root" if it has none.
+func WithTypeName(s string) Option {
+	return option.New(optkeyTypeName, s)
+}
+
+var commonInitialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true,
+	"DNS": true, "EOF": true, "GUID": true, "HTML": true, "HTTP": true,
+	"HTTPS": true, "ID": true, "IP": true, "JSON": true, "RPC": true,
+	"SQL": true, "SSH": true, "TCP": true, "TLS": true, "TTL": true,
+	"UDP": true, "UI": true, "UID": true, "UUID": true, "URI": true,
+	"URL": true, "UTF8": true, "VM": true, "XML": true,
+}
+
+// exportedName converts an arbitrary string, such as a property name,
+// into an exported identifier: "user_id" becomes "UserID", and
+// "first-name" becomes "FirstName".
+func exportedName(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+	for _, word := range words {
+		if up := strings.ToUpper(word); commonInitialisms[up] {
+			b.WriteString(up)
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(word)
+		b.WriteRune(unicode.ToUpper(r))
+		b.WriteString(word[size:])
+	}
+
+	name := b.String()
+	if name == "" {
+		return ""
+	}
+	if r, _ := utf8.DecodeRuneInString(name); !unicode.IsLetter(r) {
+		name = "X" + name
+	}
+	return name
+}
+
+// commentLines formats `text` as a line comment
+func commentLines(text string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			b.WriteString("//\n")
+			continue
+		}
+		b.WriteString("// " + line + "\n")
+	}
+	return b.String()
+}
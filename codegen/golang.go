@@ -0,0 +1,569 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/pkg/errors"
+)
+
+// GenerateGo writes Go type declarations corresponding to the schema
+// `s` to `w`:
+//
+//   - the root schema and each of its definitions become named types
+//   - properties become struct fields, with json tags that include
+//     "omitempty" unless the property is required. Fields of struct
+//     types, and those of union types that are not required, are
+//     pointers
+//   - subschemas of allOf that are references are embedded, and the
+//     properties of the others are merged into the struct
+//   - enums of strings or integers become typed constants
+//   - oneOf and anyOf become tagged unions: structs with one field per
+//     variant, of which only one is expected to be set, along with
+//     MarshalJSON and UnmarshalJSON methods
+//   - descriptions become doc comments
+//
+// Other objects that are nested within properties are given names
+// derived from the type and field that contain them. References must
+// be resolvable, and are followed to the named type of their target.
+func GenerateGo(w io.Writer, s *schema.Schema, options ...Option) error {
+	g := goGenerator{
+		pkg:     "schema",
+		names:   make(map[*schema.Schema]string),
+		used:    make(map[string]struct{}),
+		imports: make(map[string]struct{}),
+	}
+
+	var rootName string
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyPackageName:
+			g.pkg = o.Value().(string)
+		case optkeyTypeName:
+			rootName = o.Value().(string)
+		}
+	}
+	if rootName == "" {
+		rootName = exportedName(s.Title)
+	}
+	if rootName == "" {
+		rootName = "Root"
+	}
+
+	// Names are assigned to the definitions upfront, so that references
+	// to them use the names of the definitions
+	g.name(s, rootName)
+	keys := make([]string, 0, len(s.Definitions))
+	for k := range s.Definitions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		g.name(s.Definitions[k], nameOr(exportedName(k), "Definition"))
+	}
+
+	for len(g.queue) > 0 {
+		v := g.queue[0]
+		g.queue = g.queue[1:]
+		g.declare(g.names[v], v)
+	}
+	if g.err != nil {
+		return g.err
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by jsschema. DO NOT EDIT.\n\n")
+	out.WriteString("package " + g.pkg + "\n\n")
+	if len(g.imports) > 0 {
+		imports := make([]string, 0, len(g.imports))
+		for pkg := range g.imports {
+			imports = append(imports, strconv.Quote(pkg))
+		}
+		sort.Strings(imports)
+		out.WriteString("import (\n" + strings.Join(imports, "\n") + "\n)\n\n")
+	}
+	out.Write(g.buf.Bytes())
+	if g.hasUnions {
+		out.WriteString(goUnmarshalVariant)
+	}
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return errors.Wrap(err, "failed to format generated code")
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+const goUnmarshalVariant = `
+// unmarshalVariant decodes data into v, rejecting unknown fields, so
+// that objects only match the variant of a union that describes them
+func unmarshalVariant(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+`
+
+type goGenerator struct {
+	pkg       string
+	names     map[*schema.Schema]string // named types
+	used      map[string]struct{}       // names that are taken
+	queue     []*schema.Schema          // named types yet to be declared
+	imports   map[string]struct{}
+	hasUnions bool
+	buf       bytes.Buffer
+	err       error
+}
+
+func nameOr(name, fallback string) string {
+	if name == "" {
+		return fallback
+	}
+	return name
+}
+
+// unique returns `name`, or `name` followed by a number if it is taken
+func unique(used map[string]struct{}, name string) string {
+	candidate := name
+	for i := 2; ; i++ {
+		if _, ok := used[candidate]; !ok {
+			used[candidate] = struct{}{}
+			return candidate
+		}
+		candidate = name + strconv.Itoa(i)
+	}
+}
+
+// name returns the name of the type declared for `s`, assigning it a
+// name based on `hint` if it does not have one yet
+func (g *goGenerator) name(s *schema.Schema, hint string) string {
+	if name, ok := g.names[s]; ok {
+		return name
+	}
+	name := unique(g.used, hint)
+	g.names[s] = name
+	g.queue = append(g.queue, s)
+	return name
+}
+
+// deref follows the references of `s`, if any
+func (g *goGenerator) deref(s *schema.Schema) *schema.Schema {
+	if s == nil || s.Reference == "" {
+		return s
+	}
+	t, err := s.Resolve(nil)
+	if err != nil {
+		if g.err == nil {
+			g.err = errors.Wrapf(err, "failed to resolve reference at %s", s.Pointer())
+		}
+		return nil
+	}
+	return t
+}
+
+func hasType(s *schema.Schema, t schema.PrimitiveType) bool {
+	for _, v := range s.Type {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+func isObjectLike(s *schema.Schema) bool {
+	return len(s.Type) == 0 || hasType(s, schema.ObjectType)
+}
+
+// isStruct returns true if `s` is declared as a struct: an object with
+// properties, or with subschemas of allOf of which one is a struct
+func isStruct(s *schema.Schema) bool {
+	return isStructWithin(s, make(map[*schema.Schema]bool))
+}
+
+func isStructWithin(s *schema.Schema, visited map[*schema.Schema]bool) bool {
+	if !isObjectLike(s) {
+		return false
+	}
+	if len(s.Properties) > 0 {
+		return true
+	}
+	if visited[s] {
+		return false
+	}
+	visited[s] = true
+
+	for _, v := range s.AllOf {
+		// References that can not be resolved are reported by deref
+		if t, err := v.Resolve(nil); err == nil && isStructWithin(t, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+func isUnion(s *schema.Schema) bool {
+	return len(s.Properties) == 0 && (len(s.OneOf) > 0 || len(s.AnyOf) > 0)
+}
+
+// enumKind returns "string" or "int64" if the enum of `s` can be
+// declared as typed constants, or the empty string otherwise
+func enumKind(s *schema.Schema) string {
+	if len(s.Enum) == 0 {
+		return ""
+	}
+
+	strs, ints := 0, 0
+	for _, v := range s.Enum {
+		switch v := v.(type) {
+		case string:
+			strs++
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+				ints++
+			}
+		case json.Number:
+			if _, err := v.Int64(); err == nil {
+				ints++
+			}
+		}
+	}
+	switch len(s.Enum) {
+	case strs:
+		return "string"
+	case ints:
+		if len(s.Type) == 0 || hasType(s, schema.IntegerType) || hasType(s, schema.NumberType) {
+			return "int64"
+		}
+	}
+	return ""
+}
+
+// typeExpr returns the Go type to be used for the schema `s`, declaring
+// a named type based on `hint` if it requires one
+func (g *goGenerator) typeExpr(s *schema.Schema, hint string) string {
+	if s == nil {
+		return "interface{}"
+	}
+	if name, ok := g.names[s]; ok {
+		return name
+	}
+
+	if s.Reference != "" {
+		t := g.deref(s)
+		if t == nil {
+			return "interface{}"
+		}
+		if name, ok := g.names[t]; ok {
+			return name
+		}
+		// Schemas that are referenced are named after their location,
+		// as they may be referenced from several places
+		if tokens, err := schema.Pointer(t.Pointer()).Tokens(); err == nil && len(tokens) > 0 {
+			hint = nameOr(exportedName(tokens[len(tokens)-1]), hint)
+		}
+		return g.typeExpr(t, hint)
+	}
+
+	if isStruct(s) || isUnion(s) || enumKind(s) != "" {
+		return g.name(s, hint)
+	}
+	return g.shapeExpr(s, hint)
+}
+
+// shapeExpr returns the Go type for schemas that do not require a
+// named type
+func (g *goGenerator) shapeExpr(s *schema.Schema, hint string) string {
+	var types []schema.PrimitiveType
+	nullable := false
+	for _, t := range s.Type {
+		if t == schema.NullType {
+			nullable = true
+			continue
+		}
+		types = append(types, t)
+	}
+
+	if len(types) == 0 {
+		switch {
+		case s.Items != nil:
+			types = append(types, schema.ArrayType)
+		case s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil:
+			types = append(types, schema.ObjectType)
+		case len(s.Type) == 0:
+			// The type may be given by a subschema of allOf
+			for _, v := range s.AllOf {
+				if expr := g.typeExpr(v, hint); expr != "interface{}" {
+					return expr
+				}
+			}
+		}
+	}
+	if len(types) != 1 {
+		return "interface{}"
+	}
+
+	var expr string
+	switch types[0] {
+	case schema.StringType:
+		expr = "string"
+	case schema.IntegerType:
+		expr = "int64"
+	case schema.NumberType:
+		expr = "float64"
+	case schema.BooleanType:
+		expr = "bool"
+	case schema.ArrayType:
+		if s.Items == nil || s.Items.TupleMode || len(s.Items.Schemas) == 0 {
+			return "[]interface{}"
+		}
+		return "[]" + g.typeExpr(s.Items.Schemas[0], hint+"Item")
+	case schema.ObjectType:
+		if ap := s.AdditionalProperties; ap != nil && ap.Schema != nil {
+			return "map[string]" + g.typeExpr(ap.Schema, hint+"Value")
+		}
+		return "map[string]interface{}"
+	default:
+		return "interface{}"
+	}
+
+	if nullable {
+		expr = "*" + expr
+	}
+	return expr
+}
+
+func (g *goGenerator) writeDoc(name string, s *schema.Schema) {
+	fmt.Fprintf(&g.buf, "// %s corresponds to the schema at %s\n", name, s.Pointer())
+	if s.Description != "" {
+		g.buf.WriteString("//\n")
+		g.buf.WriteString(commentLines(s.Description))
+	}
+}
+
+func (g *goGenerator) declare(name string, s *schema.Schema) {
+	if s.Reference != "" {
+		t := g.deref(s)
+		if t == nil {
+			return
+		}
+		g.writeDoc(name, s)
+		fmt.Fprintf(&g.buf, "type %s = %s\n\n", name, g.typeExpr(t, name+"Target"))
+		return
+	}
+
+	g.writeDoc(name, s)
+	switch {
+	case isUnion(s):
+		g.declareUnion(name, s)
+	case isStruct(s):
+		g.declareStruct(name, s)
+	case enumKind(s) != "":
+		g.declareEnum(name, s)
+	default:
+		fmt.Fprintf(&g.buf, "type %s %s\n\n", name, g.shapeExpr(s, name))
+	}
+}
+
+// collectFields gathers the properties of `s`, including those of the
+// subschemas of allOf that are not embedded
+func (g *goGenerator) collectFields(s *schema.Schema, props map[string]*schema.Schema, required map[string]bool, embeds *[]string) {
+	for k, v := range s.Properties {
+		if _, ok := props[k]; !ok {
+			props[k] = v
+		}
+	}
+	for _, k := range s.Required {
+		required[k] = true
+	}
+
+	for _, v := range s.AllOf {
+		t := g.deref(v)
+		if t == nil || !isStruct(t) {
+			continue
+		}
+		if v.Reference != "" {
+			*embeds = append(*embeds, g.typeExpr(v, "Embedded"))
+			continue
+		}
+		g.collectFields(t, props, required, embeds)
+	}
+}
+
+func (g *goGenerator) declareStruct(name string, s *schema.Schema) {
+	props := make(map[string]*schema.Schema)
+	required := make(map[string]bool)
+	var embeds []string
+	g.collectFields(s, props, required, &embeds)
+
+	fields := make(map[string]struct{})
+	fmt.Fprintf(&g.buf, "type %s struct {\n", name)
+	for _, embed := range embeds {
+		fields[embed] = struct{}{}
+		g.buf.WriteString(embed + "\n")
+	}
+
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := props[k]
+		if !isValidTagName(k) {
+			fmt.Fprintf(&g.buf, "// property %s can not be represented as a field\n", strconv.Quote(k))
+			continue
+		}
+
+		field := unique(fields, nameOr(exportedName(k), "Field"))
+		tag := `json:"` + k
+		if !required[k] {
+			tag += ",omitempty"
+		}
+		tag += `"`
+
+		if v != nil && v.Description != "" {
+			g.buf.WriteString(commentLines(v.Description))
+		}
+		// Structs are referred to by pointers, as they may be recursive,
+		// and so are optional unions, so that omitempty leaves them out
+		expr := g.typeExpr(v, name+field)
+		if t := g.deref(v); t != nil && (isStruct(t) || (isUnion(t) && !required[k])) {
+			expr = "*" + expr
+		}
+		fmt.Fprintf(&g.buf, "%s %s `%s`\n", field, expr, tag)
+	}
+	g.buf.WriteString("}\n\n")
+}
+
+// isValidTagName returns true if `s` can be used as the name within
+// a json struct tag (see encoding/json)
+func isValidTagName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+		case c > 127 || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (g *goGenerator) declareEnum(name string, s *schema.Schema) {
+	kind := enumKind(s)
+	fmt.Fprintf(&g.buf, "type %s %s\n\n", name, kind)
+	fmt.Fprintf(&g.buf, "// Possible values of %s\nconst (\n", name)
+	for _, v := range s.Enum {
+		var suffix, literal string
+		if kind == "string" {
+			str := v.(string)
+			suffix = nameOr(exportedName(str), "Empty")
+			literal = strconv.Quote(str)
+		} else {
+			literal = fmt.Sprint(v)
+			if f, ok := v.(float64); ok {
+				literal = strconv.FormatInt(int64(f), 10)
+			}
+			suffix = strings.Replace(literal, "-", "Minus", 1)
+		}
+		fmt.Fprintf(&g.buf, "%s %s = %s\n", unique(g.used, name+suffix), name, literal)
+	}
+	g.buf.WriteString(")\n\n")
+}
+
+func (g *goGenerator) declareUnion(name string, s *schema.Schema) {
+	g.hasUnions = true
+	g.imports["bytes"] = struct{}{}
+	g.imports["encoding/json"] = struct{}{}
+	g.imports["fmt"] = struct{}{}
+
+	variants := s.OneOf
+	if len(variants) == 0 {
+		variants = s.AnyOf
+	}
+
+	type variant struct {
+		field string
+		expr  string
+	}
+	fields := make(map[string]struct{})
+	list := make([]variant, len(variants))
+	for i, v := range variants {
+		expr := g.typeExpr(v, name+"Option"+strconv.Itoa(i+1))
+		list[i] = variant{field: unique(fields, variantFieldName(expr, i)), expr: expr}
+	}
+
+	g.buf.WriteString("//\n// Only one of the fields is expected to be set\n")
+	fmt.Fprintf(&g.buf, "type %s struct {\n", name)
+	for _, v := range list {
+		switch {
+		case v.expr == "interface{}", strings.HasPrefix(v.expr, "*"):
+			fmt.Fprintf(&g.buf, "%s %s\n", v.field, v.expr)
+		default:
+			fmt.Fprintf(&g.buf, "%s *%s\n", v.field, v.expr)
+		}
+	}
+	g.buf.WriteString("}\n\n")
+
+	fmt.Fprintf(&g.buf, "// MarshalJSON encodes the variant that is set\nfunc (v %s) MarshalJSON() ([]byte, error) {\nswitch {\n", name)
+	for _, v := range list {
+		fmt.Fprintf(&g.buf, "case v.%s != nil:\nreturn json.Marshal(v.%s)\n", v.field, v.field)
+	}
+	g.buf.WriteString("}\nreturn []byte(\"null\"), nil\n}\n\n")
+
+	fmt.Fprintf(&g.buf, "// UnmarshalJSON decodes data into the first variant that accepts it\nfunc (v *%s) UnmarshalJSON(data []byte) error {\n", name)
+	fmt.Fprintf(&g.buf, "*v = %s{}\nif string(bytes.TrimSpace(data)) == \"null\" {\nreturn nil\n}\n", name)
+	for _, v := range list {
+		switch {
+		case v.expr == "interface{}":
+			fmt.Fprintf(&g.buf, "{\nvar x interface{}\nif err := json.Unmarshal(data, &x); err == nil {\nv.%s = x\nreturn nil\n}\n}\n", v.field)
+			continue
+		case strings.HasPrefix(v.expr, "*"):
+			fmt.Fprintf(&g.buf, "{\nvar x %s\nif err := unmarshalVariant(data, &x); err == nil {\nv.%s = x\nreturn nil\n}\n}\n", v.expr, v.field)
+			continue
+		}
+		fmt.Fprintf(&g.buf, "{\nvar x %s\nif err := unmarshalVariant(data, &x); err == nil {\nv.%s = &x\nreturn nil\n}\n}\n", v.expr, v.field)
+	}
+	fmt.Fprintf(&g.buf, "return fmt.Errorf(\"value does not match any variant of %s\")\n}\n\n", name)
+}
+
+// variantFieldName returns the name of the union field that holds
+// values of type `expr`
+func variantFieldName(expr string, i int) string {
+	switch expr {
+	case "string":
+		return "String"
+	case "int64":
+		return "Int64"
+	case "float64":
+		return "Float64"
+	case "bool":
+		return "Bool"
+	case "interface{}":
+		return "Value"
+	}
+	switch {
+	case strings.HasPrefix(expr, "[]"):
+		return "Array"
+	case strings.HasPrefix(expr, "map["):
+		return "Object"
+	case strings.HasPrefix(expr, "*"):
+		return variantFieldName(expr[1:], i)
+	}
+	if exportedName(expr) == expr {
+		return expr
+	}
+	return "Option" + strconv.Itoa(i+1)
+}
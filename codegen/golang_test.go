@@ -0,0 +1,244 @@
+package codegen_test
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/lestrrat-go/jsschema/codegen"
+	"github.com/stretchr/testify/assert"
+)
+
+const petStore = `{
+  "title": "pet store",
+  "description": "A pet store",
+  "type": "object",
+  "required": ["name"],
+  "properties": {
+    "name": {"type": "string", "description": "Name of the store"},
+    "user_id": {"type": "integer"},
+    "pets": {"type": "array", "items": {"$ref": "#/definitions/pet"}},
+    "address": {
+      "type": "object",
+      "properties": {"zip": {"type": "string"}, "city": {"type": ["string", "null"]}}
+    },
+    "status": {"enum": ["open", "closed"]},
+    "tags": {"type": "object", "additionalProperties": {"type": "string"}},
+    "owner": {"$ref": "#/definitions/person"},
+    "extra": {"description": "Anything goes"},
+    "counts": {"additionalProperties": {"type": "integer"}}
+  },
+  "definitions": {
+    "pet": {"oneOf": [{"$ref": "#/definitions/cat"}, {"$ref": "#/definitions/dog"}, {"type": "string"}]},
+    "animal": {"type": "object", "properties": {"age": {"type": "integer"}}},
+    "cat": {"allOf": [{"$ref": "#/definitions/animal"}, {"properties": {"lives": {"type": "integer"}}}]},
+    "dog": {"type": "object", "properties": {"breed": {"type": "string"}}, "required": ["breed"]},
+    "person": {"type": "object", "properties": {"friend": {"$ref": "#/definitions/person"}}}
+  }
+}`
+
+func TestGenerateGo(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(petStore))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, codegen.GenerateGo(&buf, s, codegen.WithPackageName("petstore")), "GenerateGo should succeed") {
+		return
+	}
+	src := buf.String()
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "petstore.go", src, parser.ParseComments); !assert.NoError(t, err, "generated code should parse") {
+		return
+	}
+
+	expected := []string{
+		"package petstore",
+		"// PetStore corresponds to the schema at #\n//\n// A pet store\ntype PetStore struct {",
+		"\t// Name of the store\n\tName ",
+		"`json:\"name\"`",
+		"UserID int64 ",
+		"`json:\"user_id,omitempty\"`",
+		"Pets []Pet ",
+		"Address *PetStoreAddress ",
+		"City *string ",
+		"Tags map[string]string ",
+		"Owner *Person ",
+		"Extra interface{} ",
+		"Counts map[string]int64 ",
+		"Friend *Person ",
+		"type PetStoreStatus string",
+		"PetStoreStatusOpen PetStoreStatus = \"open\"",
+		"type Cat struct {\n\tAnimal\n\tLives int64 ",
+		"type Pet struct {\n\tCat    *Cat\n\tDog    *Dog\n\tString *string\n}",
+		"func (v Pet) MarshalJSON() ([]byte, error) {",
+		"func (v *Pet) UnmarshalJSON(data []byte) error {",
+	}
+	for _, e := range expected {
+		if !assert.Contains(t, strings.Join(strings.Fields(src), " "), strings.Join(strings.Fields(e), " "), "generated code should contain %s", e) {
+			return
+		}
+	}
+}
+
+func TestGenerateGoTypeName(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(`{"type": "array", "items": {"type": "string"}}`))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, codegen.GenerateGo(&buf, s, codegen.WithTypeName("Names")), "GenerateGo should succeed") {
+		return
+	}
+	if !assert.Contains(t, buf.String(), "package schema\n", "default package name should be used") {
+		return
+	}
+	if !assert.Contains(t, buf.String(), "type Names []string\n", "root type should be named") {
+		return
+	}
+}
+
+func TestGenerateGoOptionalUnion(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(`{
+  "type": "object",
+  "required": ["id"],
+  "properties": {
+    "id": {"anyOf": [{"type": "string"}, {"type": "integer"}]},
+    "payload": {"oneOf": [{"type": "string"}, {"type": "object", "properties": {"a": {"type": "string"}}}]}
+  }
+}`))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, codegen.GenerateGo(&buf, s), "GenerateGo should succeed") {
+		return
+	}
+	src := strings.Join(strings.Fields(buf.String()), " ")
+	for _, e := range []string{"ID RootID `json:\"id\"`", "Payload *RootPayload `json:\"payload,omitempty\"`"} {
+		if !assert.Contains(t, src, e, "generated code should contain %s", e) {
+			return
+		}
+	}
+}
+
+func TestGenerateGoUnresolvable(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(`{"properties": {"a": {"$ref": "#/definitions/missing"}}}`))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.Error(t, codegen.GenerateGo(&buf, s), "GenerateGo should fail") {
+		return
+	}
+}
+
+// typeCheck parses and type-checks the generated source `src`
+func typeCheck(name, src string) error {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = conf.Check(f.Name.Name, fset, []*ast.File{f}, nil)
+	return err
+}
+
+// TestGenerateGoDecode type-checks the types generated for some
+// schemas, and decodes documents with them
+func TestGenerateGoDecode(t *testing.T) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command is not available")
+	}
+
+	meta, err := ioutil.ReadFile(filepath.Join("..", "test", "schema.json"))
+	if !assert.NoError(t, err, "ioutil.ReadFile should succeed") {
+		return
+	}
+	tests := []struct {
+		pkg    string
+		schema string
+		doc    string
+	}{
+		{"petstore", petStore, `{"name": "store", "pets": [{"age": 3, "lives": 9}, {"breed": "collie"}, "goldfish"], "owner": {"friend": {}}, "status": "open"}`},
+		{"meta", string(meta), `{"type": "object", "properties": {"tags": {"type": "array", "items": {"type": "string"}, "minItems": 1, "uniqueItems": true}}, "required": ["tags"]}`},
+	}
+
+	dir, err := ioutil.TempDir("", "jsschema-types")
+	if !assert.NoError(t, err, "ioutil.TempDir should succeed") {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	var main bytes.Buffer
+	main.WriteString("package main\n\nimport (\n\t\"bytes\"\n\t\"encoding/json\"\n\t\"fmt\"\n\n")
+	for _, test := range tests {
+		main.WriteString("\t\"generated/" + test.pkg + "\"\n")
+	}
+	main.WriteString(")\n\n// decode decodes data into v, and prints it encoded again\nfunc decode(data string, v interface{}) {\n\tdec := json.NewDecoder(bytes.NewReader([]byte(data)))\n\tdec.DisallowUnknownFields()\n\tif err := dec.Decode(v); err != nil {\n\t\tpanic(err)\n\t}\n\tbuf, err := json.Marshal(v)\n\tif err != nil {\n\t\tpanic(err)\n\t}\n\tfmt.Println(string(buf))\n}\n\nfunc main() {\n")
+	for _, test := range tests {
+		s, err := schema.Read(strings.NewReader(test.schema))
+		if !assert.NoError(t, err, "schema.Read(%s) should succeed", test.pkg) {
+			return
+		}
+
+		var buf bytes.Buffer
+		if !assert.NoError(t, codegen.GenerateGo(&buf, s, codegen.WithPackageName(test.pkg), codegen.WithTypeName("Root")), "GenerateGo(%s) should succeed", test.pkg) {
+			return
+		}
+		if !assert.NoError(t, typeCheck(test.pkg+".go", buf.String()), "generated code for %s should type-check", test.pkg) {
+			return
+		}
+
+		if !assert.NoError(t, os.Mkdir(filepath.Join(dir, test.pkg), 0755), "os.Mkdir should succeed") {
+			return
+		}
+		if !assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, test.pkg, "types.go"), buf.Bytes(), 0644), "ioutil.WriteFile should succeed") {
+			return
+		}
+		main.WriteString("\tdecode(" + strconv.Quote(test.doc) + ", &" + test.pkg + ".Root{})\n")
+	}
+	main.WriteString("}\n")
+	if !assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "main.go"), main.Bytes(), 0644), "ioutil.WriteFile should succeed") {
+		return
+	}
+	if !assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module generated\n\ngo 1.13\n"), 0644), "ioutil.WriteFile should succeed") {
+		return
+	}
+
+	cmd := exec.Command(gobin, "run", ".")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if !assert.NoError(t, err, "documents should be decoded: %s", stderr.String()) {
+		return
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if !assert.Len(t, lines, len(tests), "each document should be printed") {
+		return
+	}
+	for i, test := range tests {
+		if !assert.JSONEq(t, test.doc, lines[i], "document for %s should survive decoding", test.pkg) {
+			return
+		}
+	}
+}
@@ -0,0 +1,504 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/pkg/errors"
+)
+
+// GenerateTypeScript writes TypeScript type declarations corresponding
+// to the schema `s` to `w`:
+//
+//   - the root schema and each of its definitions become exported named
+//     types, as do the targets of references
+//   - objects with properties become interfaces, whose members are
+//     optional ("?") unless the property is required
+//   - additionalProperties and patternProperties schemas become index
+//     signatures
+//   - enums become unions of literal types
+//   - allOf becomes an intersection, and oneOf and anyOf become unions
+//   - descriptions become doc comments
+//
+// Other schemas are declared inline. WithTypeName specifies the name
+// of the root type, as it does for GenerateGo.
+func GenerateTypeScript(w io.Writer, s *schema.Schema, options ...Option) error {
+	g := tsGenerator{
+		names: make(map[*schema.Schema]string),
+		used:  make(map[string]struct{}),
+	}
+
+	var rootName string
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyTypeName:
+			rootName = o.Value().(string)
+		}
+	}
+	if rootName == "" {
+		rootName = exportedName(s.Title)
+	}
+	if rootName == "" {
+		rootName = "Root"
+	}
+
+	g.name(s, rootName)
+	keys := make([]string, 0, len(s.Definitions))
+	for k := range s.Definitions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		g.name(s.Definitions[k], nameOr(exportedName(k), "Definition"))
+	}
+
+	for len(g.queue) > 0 {
+		v := g.queue[0]
+		g.queue = g.queue[1:]
+		g.declare(g.names[v], v)
+	}
+	if g.err != nil {
+		return g.err
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by jsschema. DO NOT EDIT.\n\n")
+	out.Write(bytes.TrimRight(g.buf.Bytes(), "\n"))
+	out.WriteString("\n")
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+type tsGenerator struct {
+	names map[*schema.Schema]string // named types
+	used  map[string]struct{}       // names that are taken
+	queue []*schema.Schema          // named types yet to be declared
+	buf   bytes.Buffer
+	err   error
+}
+
+// name returns the name of the type declared for `s`, assigning it a
+// name based on `hint` if it does not have one yet
+func (g *tsGenerator) name(s *schema.Schema, hint string) string {
+	if name, ok := g.names[s]; ok {
+		return name
+	}
+	name := unique(g.used, hint)
+	g.names[s] = name
+	g.queue = append(g.queue, s)
+	return name
+}
+
+// deref follows the references of `s`, if any
+func (g *tsGenerator) deref(s *schema.Schema) *schema.Schema {
+	if s == nil || s.Reference == "" {
+		return s
+	}
+	t, err := s.Resolve(nil)
+	if err != nil {
+		if g.err == nil {
+			g.err = errors.Wrapf(err, "failed to resolve reference at %s", s.Pointer())
+		}
+		return nil
+	}
+	return t
+}
+
+// isInterface returns true if `s` can be declared as an interface
+func isInterface(s *schema.Schema) bool {
+	if s.Reference != "" || len(s.Enum) > 0 || len(s.AllOf) > 0 || len(s.AnyOf) > 0 || len(s.OneOf) > 0 {
+		return false
+	}
+	switch len(s.Type) {
+	case 0:
+		return len(s.Properties) > 0
+	case 1:
+		return s.Type[0] == schema.ObjectType
+	}
+	return false
+}
+
+// docComment formats the description of `s` as a doc comment
+func docComment(indent string, s *schema.Schema) string {
+	if s.Description == "" {
+		return ""
+	}
+	text := strings.Replace(strings.TrimSpace(s.Description), "*/", "*\\/", -1)
+	lines := strings.Split(text, "\n")
+	if len(lines) == 1 {
+		return indent + "/** " + lines[0] + " */\n"
+	}
+
+	var b strings.Builder
+	b.WriteString(indent + "/**\n")
+	for _, line := range lines {
+		b.WriteString(strings.TrimRight(indent+" * "+line, " \t\r") + "\n")
+	}
+	b.WriteString(indent + " */\n")
+	return b.String()
+}
+
+func (g *tsGenerator) declare(name string, s *schema.Schema) {
+	g.buf.WriteString(docComment("", s))
+	if isInterface(s) {
+		// Objects without any member are not declared as interfaces
+		if expr := g.objectExpr(s, ""); strings.HasPrefix(expr, "{\n") {
+			fmt.Fprintf(&g.buf, "export interface %s %s\n\n", name, expr)
+			return
+		}
+	}
+
+	var expr string
+	if t := g.deref(s); t != nil && t != s {
+		expr = g.typeExpr(t, "")
+	} else {
+		expr = g.shapeOf(s, "")
+	}
+	fmt.Fprintf(&g.buf, "export type %s = %s;\n\n", name, expr)
+}
+
+// typeExpr returns the TypeScript type to be used for the schema `s`,
+// indented by `indent` if it spans several lines
+func (g *tsGenerator) typeExpr(s *schema.Schema, indent string) string {
+	if s == nil {
+		return "unknown"
+	}
+	if name, ok := g.names[s]; ok {
+		return name
+	}
+
+	if s.Reference != "" {
+		t := g.deref(s)
+		if t == nil {
+			return "unknown"
+		}
+		if name, ok := g.names[t]; ok {
+			return name
+		}
+		// Schemas that are referenced are named after their location,
+		// as they may be referenced from several places
+		hint := "Definition"
+		if tokens, err := schema.Pointer(t.Pointer()).Tokens(); err == nil && len(tokens) > 0 {
+			hint = nameOr(exportedName(tokens[len(tokens)-1]), hint)
+		}
+		return g.name(t, hint)
+	}
+	return g.shapeOf(s, indent)
+}
+
+// shapeOf returns the type of `s`, without looking up its name
+func (g *tsGenerator) shapeOf(s *schema.Schema, indent string) string {
+	var parts []string
+	if own := g.ownExpr(s, indent); own != "" {
+		parts = append(parts, own)
+	}
+	for _, v := range s.AllOf {
+		parts = append(parts, g.typeExpr(v, indent))
+	}
+	for _, l := range []schema.SchemaList{s.OneOf, s.AnyOf} {
+		if len(l) == 0 {
+			continue
+		}
+		alts := make([]string, len(l))
+		for i, v := range l {
+			alts[i] = g.typeExpr(v, indent)
+		}
+		parts = append(parts, union(alts))
+	}
+
+	switch len(parts) {
+	case 0:
+		return "unknown"
+	case 1:
+		return parts[0]
+	}
+	for i, p := range parts {
+		parts[i] = wrapType(p)
+	}
+	return strings.Join(parts, " & ")
+}
+
+// ownExpr returns the type described by the keywords of `s` other than
+// allOf, anyOf and oneOf, or the empty string if they do not restrict
+// the type
+func (g *tsGenerator) ownExpr(s *schema.Schema, indent string) string {
+	if lits, ok := enumLiterals(s.Enum); ok {
+		return union(lits)
+	}
+
+	types := []schema.PrimitiveType(s.Type)
+	if len(types) == 0 {
+		switch {
+		case len(s.Properties) > 0 || len(s.PatternProperties) > 0:
+			types = append(types, schema.ObjectType)
+		case s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil:
+			types = append(types, schema.ObjectType)
+		case s.Items != nil:
+			types = append(types, schema.ArrayType)
+		default:
+			return ""
+		}
+	}
+
+	var exprs []string
+	seen := make(map[string]struct{})
+	for _, t := range types {
+		var expr string
+		switch t {
+		case schema.NullType:
+			expr = "null"
+		case schema.BooleanType:
+			expr = "boolean"
+		case schema.IntegerType, schema.NumberType:
+			expr = "number"
+		case schema.StringType:
+			expr = "string"
+		case schema.ArrayType:
+			expr = g.arrayExpr(s, indent)
+		case schema.ObjectType:
+			expr = g.objectExpr(s, indent)
+		default:
+			continue
+		}
+		if _, ok := seen[expr]; !ok {
+			seen[expr] = struct{}{}
+			exprs = append(exprs, expr)
+		}
+	}
+	if len(exprs) == 0 {
+		return ""
+	}
+	return union(exprs)
+}
+
+func (g *tsGenerator) arrayExpr(s *schema.Schema, indent string) string {
+	if s.Items == nil || len(s.Items.Schemas) == 0 {
+		return "unknown[]"
+	}
+	if !s.Items.TupleMode {
+		return wrapType(g.typeExpr(s.Items.Schemas[0], indent)) + "[]"
+	}
+
+	// Elements beyond minItems are optional
+	elems := make([]string, 0, len(s.Items.Schemas)+1)
+	for i, v := range s.Items.Schemas {
+		expr := g.typeExpr(v, indent)
+		if !s.MinItems.Initialized || i >= s.MinItems.Val {
+			expr = wrapType(expr) + "?"
+		}
+		elems = append(elems, expr)
+	}
+	if ai := s.AdditionalItems; ai != nil {
+		elems = append(elems, "..."+wrapType(g.typeExpr(ai.Schema, indent))+"[]")
+	}
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+func (g *tsGenerator) objectExpr(s *schema.Schema, indent string) string {
+	required := make(map[string]bool)
+	for _, name := range s.Required {
+		required[name] = true
+	}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// The index signature must accept the types of the properties,
+	// so they are included in its type
+	var index []string
+	if ap := s.AdditionalProperties; ap != nil && ap.Schema != nil {
+		index = append(index, g.typeExpr(ap.Schema, indent+"  "))
+	}
+	rxs := make([]string, 0, len(s.PatternProperties))
+	patterns := make(map[string]*schema.Schema)
+	for rx, v := range s.PatternProperties {
+		rxs = append(rxs, rx.String())
+		patterns[rx.String()] = v
+	}
+	sort.Strings(rxs)
+	for _, rx := range rxs {
+		index = append(index, g.typeExpr(patterns[rx], indent+"  "))
+	}
+
+	if len(names) == 0 && len(index) == 0 {
+		if s.AdditionalProperties == nil {
+			return "Record<string, never>"
+		}
+		return "{ [key: string]: unknown }"
+	}
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	optional := false
+	for _, name := range names {
+		v := s.Properties[name]
+		expr := g.typeExpr(v, indent+"  ")
+		if len(index) > 0 {
+			index = append(index, expr)
+		}
+
+		mark := ""
+		if !required[name] {
+			mark = "?"
+			optional = true
+		}
+
+		b.WriteString(docComment(indent+"  ", v))
+		fmt.Fprintf(&b, "%s  %s%s: %s;\n", indent, propertyName(name), mark, expr)
+	}
+	if len(index) > 0 {
+		if optional {
+			index = append(index, "undefined")
+		}
+		fmt.Fprintf(&b, "%s  [key: string]: %s;\n", indent, union(indexMembers(index)))
+	}
+	b.WriteString(indent + "}")
+	return b.String()
+}
+
+// enumLiterals returns the literal types of the values of an enum. All
+// values must be scalars
+func enumLiterals(enum []interface{}) ([]string, bool) {
+	if len(enum) == 0 {
+		return nil, false
+	}
+	lits := make([]string, 0, len(enum))
+	for _, v := range enum {
+		switch v.(type) {
+		case nil, bool, string, float64, json.Number:
+		default:
+			return nil, false
+		}
+		buf, err := json.Marshal(v)
+		if err != nil {
+			return nil, false
+		}
+		lits = append(lits, string(buf))
+	}
+	return uniqueStrings(lits), true
+}
+
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// propertyName returns `name` quoted if it is not a valid identifier
+func propertyName(name string) string {
+	if tsIdentifier.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+// union returns the union of the types. Operands need no parentheses,
+// as intersections take precedence over unions
+func union(l []string) string {
+	return strings.Join(l, " | ")
+}
+
+// wrapType wraps `expr` in parentheses if it is a union or an
+// intersection, so that it can be used as an operand
+func wrapType(expr string) string {
+	depth := 0
+	quoted := false
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quoted:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				quoted = false
+			}
+		case c == '"':
+			quoted = true
+		case c == '{' || c == '[' || c == '(' || c == '<':
+			depth++
+		case c == '}' || c == ']' || c == ')' || c == '>':
+			depth--
+		case depth == 0 && (c == '|' || c == '&'):
+			return "(" + expr + ")"
+		}
+	}
+	return expr
+}
+
+// indexMembers returns the members of the union of the types `l`, once
+// each. Literal types are left out when their primitive type is there
+func indexMembers(l []string) []string {
+	var members []string
+	for _, expr := range l {
+		members = append(members, unionMembers(expr)...)
+	}
+	members = uniqueStrings(members)
+
+	present := make(map[string]bool, len(members))
+	for _, m := range members {
+		present[m] = true
+	}
+	out := members[:0]
+	for _, m := range members {
+		switch {
+		case strings.HasPrefix(m, `"`) && present["string"]:
+		case (m == "true" || m == "false") && present["boolean"]:
+		case isNumberLiteral(m) && present["number"]:
+		default:
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func isNumberLiteral(expr string) bool {
+	_, err := strconv.ParseFloat(expr, 64)
+	return err == nil
+}
+
+// unionMembers returns the operands of `expr` if it is a union, or
+// `expr` itself otherwise
+func unionMembers(expr string) []string {
+	var l []string
+	depth := 0
+	quoted := false
+	start := 0
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quoted:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				quoted = false
+			}
+		case c == '"':
+			quoted = true
+		case c == '{' || c == '[' || c == '(' || c == '<':
+			depth++
+		case c == '}' || c == ']' || c == ')' || c == '>':
+			depth--
+		case depth == 0 && c == '|':
+			l = append(l, strings.TrimSpace(expr[start:i]))
+			start = i + 1
+		}
+	}
+	return append(l, strings.TrimSpace(expr[start:]))
+}
+
+func uniqueStrings(l []string) []string {
+	seen := make(map[string]struct{}, len(l))
+	out := make([]string, 0, len(l))
+	for _, s := range l {
+		if _, ok := seen[s]; !ok {
+			seen[s] = struct{}{}
+			out = append(out, s)
+		}
+	}
+	return out
+}
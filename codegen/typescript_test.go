@@ -0,0 +1,141 @@
+package codegen_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/lestrrat-go/jsschema/codegen"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateTypeScript(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(petStore))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, codegen.GenerateTypeScript(&buf, s), "GenerateTypeScript should succeed") {
+		return
+	}
+
+	expected := `// Code generated by jsschema. DO NOT EDIT.
+
+/** A pet store */
+export interface PetStore {
+  address?: {
+    city?: string | null;
+    zip?: string;
+  };
+  counts?: {
+    [key: string]: number;
+  };
+  /** Anything goes */
+  extra?: unknown;
+  /** Name of the store */
+  name: string;
+  owner?: Person;
+  pets?: Pet[];
+  status?: "open" | "closed";
+  tags?: {
+    [key: string]: string;
+  };
+  user_id?: number;
+}
+
+export interface Animal {
+  age?: number;
+}
+
+export type Cat = Animal & {
+  lives?: number;
+};
+
+export interface Dog {
+  breed: string;
+}
+
+export interface Person {
+  friend?: Person;
+}
+
+export type Pet = Cat | Dog | string;
+`
+	if !assert.Equal(t, expected, buf.String(), "generated code should match") {
+		return
+	}
+}
+
+func TestGenerateTypeScriptShapes(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(`{
+  "description": "Line one\nLine two */",
+  "type": "object",
+  "required": ["point"],
+  "properties": {
+    "point": {"type": "array", "items": [{"type": "number"}, {"type": "number"}], "minItems": 1, "additionalItems": false},
+    "content-type": {"type": "string"},
+    "levels": {"type": "array", "items": {"type": ["string", "integer"]}},
+    "code": {"enum": [1, 2, null]}
+  },
+  "patternProperties": {"^x-": {"type": "boolean"}}
+}`))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, codegen.GenerateTypeScript(&buf, s, codegen.WithTypeName("Shapes")), "GenerateTypeScript should succeed") {
+		return
+	}
+
+	expected := `// Code generated by jsschema. DO NOT EDIT.
+
+/**
+ * Line one
+ * Line two *\/
+ */
+export interface Shapes {
+  code?: 1 | 2 | null;
+  "content-type"?: string;
+  levels?: (string | number)[];
+  point: [number, number?];
+  [key: string]: boolean | 1 | 2 | null | string | (string | number)[] | [number, number?] | undefined;
+}
+`
+	if !assert.Equal(t, expected, buf.String(), "generated code should match") {
+		return
+	}
+}
+
+func TestGenerateTypeScriptIndexSignature(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(`{
+  "type": "object",
+  "additionalProperties": {"type": ["number", "string"]},
+  "properties": {
+    "a": {"enum": ["a\"b", 1, null]},
+    "b": {"type": ["string", "null"]}
+  }
+}`))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, codegen.GenerateTypeScript(&buf, s, codegen.WithTypeName("Index")), "GenerateTypeScript should succeed") {
+		return
+	}
+
+	expected := `// Code generated by jsschema. DO NOT EDIT.
+
+export interface Index {
+  a?: "a\"b" | 1 | null;
+  b?: string | null;
+  [key: string]: number | string | null | undefined;
+}
+`
+	if !assert.Equal(t, expected, buf.String(), "generated code should match") {
+		return
+	}
+}
@@ -0,0 +1,805 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/pkg/errors"
+)
+
+// GenerateGoValidator writes Go source code that validates values
+// against the schema `s` to `w`. The generated code declares a single
+// exported function, named "Validate" followed by the name of the root
+// type (see WithTypeName):
+//
+//	func ValidatePetStore(v interface{}) error
+//
+// The function accepts values as decoded by encoding/json into an
+// interface{} (numbers may be float64 or json.Number), and returns the
+// first violation that it finds, like validator.Validator does. Numbers
+// are compared with the values of the schema exactly, float64 values
+// being taken as the shortest decimal that they represent. Errors
+// for properties and items wrap the errors of the values that they
+// contain. The generated code does not depend on this package, does
+// not parse the schema at runtime, and does not use reflection.
+func GenerateGoValidator(w io.Writer, s *schema.Schema, options ...Option) error {
+	g := validatorGenerator{
+		pkg:      "schema",
+		funcs:    make(map[*schema.Schema]string),
+		used:     make(map[string]struct{}),
+		patterns: make(map[string]string),
+		enums:    make(map[string]string),
+		numbers:  make(map[string]string),
+	}
+
+	var rootName string
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyPackageName:
+			g.pkg = o.Value().(string)
+		case optkeyTypeName:
+			rootName = o.Value().(string)
+		}
+	}
+	if rootName == "" {
+		rootName = exportedName(s.Title)
+	}
+	if rootName == "" {
+		rootName = "Root"
+	}
+
+	// Functions of the definitions are named after them
+	root := g.function(s, rootName)
+	keys := make([]string, 0, len(s.Definitions))
+	for k := range s.Definitions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		g.function(s.Definitions[k], rootName+nameOr(exportedName(k), "Definition"))
+	}
+
+	for len(g.queue) > 0 {
+		v := g.queue[0]
+		g.queue = g.queue[1:]
+		g.declare(g.funcs[v], v)
+	}
+	if g.err != nil {
+		return g.err
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by jsschema. DO NOT EDIT.\n\n")
+	out.WriteString("package " + g.pkg + "\n\n")
+	imports := validatorImports
+	if g.errors {
+		imports = append(imports, "errors")
+	}
+	sort.Strings(imports)
+	out.WriteString("import (\n")
+	for _, pkg := range imports {
+		out.WriteString(strconv.Quote(pkg) + "\n")
+	}
+	out.WriteString(")\n\n")
+	fmt.Fprintf(&out, "// Validate%s validates a value, as decoded by encoding/json into an\n", rootName)
+	fmt.Fprintf(&out, "// interface{}, against the schema. It returns the first violation found.\n")
+	fmt.Fprintf(&out, "func Validate%s(v interface{}) error {\n\treturn %s(v)\n}\n\n", rootName, root)
+
+	if len(g.patterns) > 0 || len(g.enums) > 0 || len(g.numbers) > 0 {
+		out.WriteString("var (\n")
+		for _, name := range sortedValues(g.patterns) {
+			out.WriteString(name + "\n")
+		}
+		for _, name := range sortedValues(g.enums) {
+			out.WriteString(name + "\n")
+		}
+		for _, name := range sortedValues(g.numbers) {
+			out.WriteString(name + "\n")
+		}
+		out.WriteString(")\n\n")
+	}
+	out.Write(g.buf.Bytes())
+	out.WriteString(validatorHelpers)
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return errors.Wrap(err, "failed to format generated code")
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+type validatorGenerator struct {
+	pkg      string
+	funcs    map[*schema.Schema]string // validation functions
+	used     map[string]struct{}       // names that are taken
+	queue    []*schema.Schema          // functions yet to be declared
+	patterns map[string]string         // pattern => variable declaration
+	enums    map[string]string         // enum literal => variable declaration
+	numbers  map[string]string         // exact number => variable declaration
+	errors   bool                      // true if the errors package is used
+	buf      bytes.Buffer
+	err      error
+}
+
+// function returns the name of the function that validates `s`,
+// assigning it a name based on `hint` if it does not have one yet
+func (g *validatorGenerator) function(s *schema.Schema, hint string) string {
+	if name, ok := g.funcs[s]; ok {
+		return name
+	}
+	name := unique(g.used, "validate"+hint)
+	g.funcs[s] = name
+	g.queue = append(g.queue, s)
+	return name
+}
+
+// ref returns the name of the function that validates `s`, following
+// references
+func (g *validatorGenerator) ref(s *schema.Schema, hint string) string {
+	if s.Reference != "" {
+		t, err := s.Resolve(nil)
+		if err != nil {
+			if g.err == nil {
+				g.err = errors.Wrapf(err, "failed to resolve reference at %s", s.Pointer())
+			}
+			return "validateAny"
+		}
+		if _, ok := g.funcs[t]; !ok {
+			if tokens, err := schema.Pointer(t.Pointer()).Tokens(); err == nil && len(tokens) > 0 {
+				hint = nameOr(exportedName(tokens[len(tokens)-1]), hint)
+			}
+		}
+		s = t
+	}
+	return g.function(s, hint)
+}
+
+// variable returns the name of a package level variable, declared as
+// `prefix` followed by a number, and initialized with `expr`
+func (g *validatorGenerator) variable(decls map[string]string, prefix, expr string) string {
+	if decl, ok := decls[expr]; ok {
+		return strings.Fields(decl)[0]
+	}
+	name := unique(g.used, prefix+strconv.Itoa(len(decls)+1))
+	decls[expr] = name + " = " + expr
+	return name
+}
+
+func (g *validatorGenerator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// fail returns an error with a constant message, and closes the block
+// of the condition that led to it
+func (g *validatorGenerator) fail(msg string) {
+	g.errors = true
+	g.printf("return errors.New(%q)\n}\n", msg)
+}
+
+func (g *validatorGenerator) declare(name string, s *schema.Schema) {
+	g.printf("// %s validates values against the schema at %s\n", name, s.Pointer())
+	g.printf("func %s(v interface{}) error {\n", name)
+
+	// References replace the other keywords of a schema
+	if s.Reference != "" {
+		g.printf("return %s(v)\n}\n\n", g.ref(s, strings.TrimPrefix(name, "validate")+"Target"))
+		return
+	}
+	defer g.printf("return nil\n}\n\n")
+
+	hint := strings.TrimPrefix(name, "validate")
+	if len(s.Enum) > 0 {
+		l := make([]string, len(s.Enum))
+		for i, e := range s.Enum {
+			l[i] = goLiteral(e)
+		}
+		enum := g.variable(g.enums, "enum", "[]interface{}{"+strings.Join(l, ", ")+"}")
+		g.printf("if !containsValue(%s, v) {\n", enum)
+		g.fail("value is not one of the enumerated values")
+	}
+
+	if len(s.Type) > 0 {
+		l := make([]string, len(s.Type))
+		for i, t := range s.Type {
+			l[i] = t.String()
+		}
+		g.printf("if t := jsonType(v); ")
+		for i, t := range s.Type {
+			if i > 0 {
+				g.printf(" && ")
+			}
+			if t == schema.NumberType {
+				g.printf("t != \"number\" && t != \"integer\"")
+			} else {
+				g.printf("t != %q", t.String())
+			}
+		}
+		g.printf(" {\n")
+		g.printf("return fmt.Errorf(\"invalid type: expected %s, got %%s\", t)\n}\n", strings.Join(l, " or "))
+	}
+
+	g.declareString(s)
+	g.declareNumber(s)
+	g.declareArray(s, hint)
+	g.declareObject(s, hint)
+	g.declareCombinators(s, hint)
+}
+
+func (g *validatorGenerator) declareString(s *schema.Schema) {
+	hasFormat := formatCheck(s.Format) != ""
+	if !s.MinLength.Initialized && !s.MaxLength.Initialized && s.Pattern == nil && !hasFormat {
+		return
+	}
+
+	g.printf("if str, ok := v.(string); ok {\n")
+	if s.MinLength.Initialized || s.MaxLength.Initialized {
+		g.printf("n := stringLength(str)\n")
+	}
+	if s.MinLength.Initialized {
+		g.printf("if n < %d {\n", s.MinLength.Val)
+		g.printf("return fmt.Errorf(\"string length %%d is shorter than minLength %d\", n)\n}\n", s.MinLength.Val)
+	}
+	if s.MaxLength.Initialized {
+		g.printf("if n > %d {\n", s.MaxLength.Val)
+		g.printf("return fmt.Errorf(\"string length %%d is longer than maxLength %d\", n)\n}\n", s.MaxLength.Val)
+	}
+	if s.Pattern != nil {
+		rx := g.variable(g.patterns, "pattern", "regexp.MustCompile("+strconv.Quote(s.Pattern.String())+")")
+		g.printf("if !%s.MatchString(str) {\n", rx)
+		g.printf("return fmt.Errorf(\"string %%q does not match pattern %%s\", str, %s)\n}\n", rx)
+	}
+	if hasFormat {
+		g.printf("if !%s(str) {\n", formatCheck(s.Format))
+		g.printf("return fmt.Errorf(\"string %%q is not a valid %s\", str)\n}\n", s.Format)
+	}
+	g.printf("}\n")
+}
+
+// formatCheck returns the name of the helper function that checks
+// strings of the given format, or the empty string if the format is
+// not checked
+func formatCheck(f schema.Format) string {
+	switch f {
+	case schema.FormatDateTime:
+		return "isDateTime"
+	case schema.FormatEmail:
+		return "isEmail"
+	case schema.FormatHostname:
+		return "isHostname"
+	case schema.FormatIPv4:
+		return "isIPv4"
+	case schema.FormatIPv6:
+		return "isIPv6"
+	case schema.FormatURI:
+		return "isURI"
+	}
+	return ""
+}
+
+// declareNumber checks numbers by their exact values. Bounds that are
+// not finite are compared as float64
+func (g *validatorGenerator) declareNumber(s *schema.Schema) {
+	if !s.Minimum.Initialized && !s.Maximum.Initialized && !s.MultipleOf.Initialized {
+		return
+	}
+
+	g.printf("if r, ok := toRat(v); ok {\n")
+	if s.Minimum.Initialized {
+		op, desc := "<", "less than"
+		if s.ExclusiveMinimum.Bool() {
+			op, desc = "<=", "less than or equal to"
+		}
+		g.compareNumber(s.Minimum, op)
+		g.printf("return fmt.Errorf(\"number %%v is %s minimum %s\", v)\n}\n", desc, numberString(s.Minimum))
+	}
+	if s.Maximum.Initialized {
+		op, desc := ">", "greater than"
+		if s.ExclusiveMaximum.Bool() {
+			op, desc = ">=", "greater than or equal to"
+		}
+		g.compareNumber(s.Maximum, op)
+		g.printf("return fmt.Errorf(\"number %%v is %s maximum %s\", v)\n}\n", desc, numberString(s.Maximum))
+	}
+	if s.MultipleOf.Initialized {
+		if r, ok := exactNumber(s.MultipleOf); ok && r.Sign() > 0 {
+			g.printf("if !isMultiple(r, %s) {\n", g.number(r))
+		} else {
+			m := goFloat(s.MultipleOf.Val)
+			g.printf("if f, _ := r.Float64(); f/%s != math.Trunc(f/%s) {\n", m, m)
+		}
+		g.printf("return fmt.Errorf(\"number %%v is not a multiple of %s\", v)\n}\n", numberString(s.MultipleOf))
+	}
+	g.printf("}\n")
+}
+
+// compareNumber opens the block of the condition that the number `r`
+// compares to `n` with `op`
+func (g *validatorGenerator) compareNumber(n schema.Number, op string) {
+	if r, ok := exactNumber(n); ok {
+		g.printf("if r.Cmp(%s) %s 0 {\n", g.number(r), op)
+		return
+	}
+	g.printf("if f, _ := r.Float64(); f %s %s {\n", op, goFloat(n.Val))
+}
+
+// number returns the name of a package level variable holding `r`
+func (g *validatorGenerator) number(r *big.Rat) string {
+	return g.variable(g.numbers, "number", "mustRat("+strconv.Quote(r.RatString())+")")
+}
+
+// numberString returns the number as written in the schema, if known
+func numberString(n schema.Number) string {
+	if n.Exact != "" {
+		if f, err := n.Exact.Float64(); err == nil && f == n.Val {
+			return n.Exact.String()
+		}
+	}
+	return strconv.FormatFloat(n.Val, 'g', -1, 64)
+}
+
+// exactNumber returns the exact value of the number, which is only
+// known if it is finite
+func exactNumber(n schema.Number) (*big.Rat, bool) {
+	return new(big.Rat).SetString(numberString(n))
+}
+
+func (g *validatorGenerator) declareArray(s *schema.Schema, hint string) {
+	items := s.Items
+	hasItems := items != nil && len(items.Schemas) > 0
+	restricted := items != nil && items.TupleMode && (s.AdditionalItems == nil || s.AdditionalItems.Schema != nil)
+	if !s.MinItems.Initialized && !s.MaxItems.Initialized && !s.UniqueItems.Bool() && !hasItems && !restricted {
+		return
+	}
+
+	g.printf("if l, ok := v.([]interface{}); ok {\n")
+	if s.MinItems.Initialized {
+		g.printf("if len(l) < %d {\n", s.MinItems.Val)
+		g.printf("return fmt.Errorf(\"array has %%d items, fewer than minItems %d\", len(l))\n}\n", s.MinItems.Val)
+	}
+	if s.MaxItems.Initialized {
+		g.printf("if len(l) > %d {\n", s.MaxItems.Val)
+		g.printf("return fmt.Errorf(\"array has %%d items, more than maxItems %d\", len(l))\n}\n", s.MaxItems.Val)
+	}
+	if s.UniqueItems.Bool() {
+		g.printf("for i := range l {\nfor j := i + 1; j < len(l); j++ {\n")
+		g.printf("if equalValues(l[i], l[j]) {\n")
+		g.printf("return fmt.Errorf(\"items %%d and %%d are equal\", i, j)\n}\n}\n}\n")
+	}
+
+	switch {
+	case items == nil || len(items.Schemas) == 0 && !items.TupleMode:
+	case !items.TupleMode:
+		fn := g.ref(items.Schemas[0], hint+"Item")
+		g.printf("for i, e := range l {\nif err := %s(e); err != nil {\n", fn)
+		g.printf("return fmt.Errorf(\"item %%d: %%w\", i, err)\n}\n}\n")
+	default:
+		for i, item := range items.Schemas {
+			fn := g.ref(item, hint+"Item"+strconv.Itoa(i))
+			g.printf("if len(l) > %d {\nif err := %s(l[%d]); err != nil {\n", i, fn, i)
+			g.printf("return fmt.Errorf(\"item %d: %%w\", err)\n}\n}\n", i)
+		}
+		n := len(items.Schemas)
+		switch ai := s.AdditionalItems; {
+		case ai == nil:
+			g.printf("if len(l) > %d {\n", n)
+			g.printf("return fmt.Errorf(\"array has %%d items, but additional items are not allowed after %d\", len(l))\n}\n", n)
+		case ai.Schema != nil:
+			fn := g.ref(ai.Schema, hint+"AdditionalItem")
+			g.printf("for i := %d; i < len(l); i++ {\nif err := %s(l[i]); err != nil {\n", n, fn)
+			g.printf("return fmt.Errorf(\"item %%d: %%w\", i, err)\n}\n}\n")
+		}
+	}
+	g.printf("}\n")
+}
+
+func (g *validatorGenerator) declareObject(s *schema.Schema, hint string) {
+	hasDeps := len(s.Dependencies.Names) > 0 || len(s.Dependencies.Schemas) > 0
+	ap := s.AdditionalProperties
+	checkKeys := len(s.PatternProperties) > 0 || ap == nil || ap.Schema != nil
+	if !s.MinProperties.Initialized && !s.MaxProperties.Initialized && len(s.Required) == 0 && len(s.Properties) == 0 && !checkKeys && !hasDeps {
+		return
+	}
+
+	g.printf("if m, ok := v.(map[string]interface{}); ok {\n")
+	if s.MinProperties.Initialized {
+		g.printf("if len(m) < %d {\n", s.MinProperties.Val)
+		g.printf("return fmt.Errorf(\"object has %%d properties, fewer than minProperties %d\", len(m))\n}\n", s.MinProperties.Val)
+	}
+	if s.MaxProperties.Initialized {
+		g.printf("if len(m) > %d {\n", s.MaxProperties.Val)
+		g.printf("return fmt.Errorf(\"object has %%d properties, more than maxProperties %d\", len(m))\n}\n", s.MaxProperties.Val)
+	}
+	for _, name := range s.Required {
+		g.printf("if _, ok := m[%q]; !ok {\n", name)
+		g.fail(fmt.Sprintf("required property %q is missing", name))
+	}
+
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fn := g.ref(s.Properties[name], hint+nameOr(exportedName(name), "Property"))
+		g.printf("if pv, ok := m[%q]; ok {\nif err := %s(pv); err != nil {\n", name, fn)
+		g.printf("return fmt.Errorf(%q, err)\n}\n}\n", fmt.Sprintf("property %q: %%w", name))
+	}
+
+	if checkKeys {
+		g.printf("for _, k := range sortedKeys(m) {\n")
+		if len(names) > 0 {
+			g.printf("matched := false\nswitch k {\ncase ")
+			for i, name := range names {
+				if i > 0 {
+					g.printf(", ")
+				}
+				g.printf("%q", name)
+			}
+			g.printf(":\nmatched = true\n}\n")
+		} else {
+			g.printf("matched := false\n")
+		}
+
+		rxs := make([]string, 0, len(s.PatternProperties))
+		patterns := make(map[string]*schema.Schema)
+		for rx, v := range s.PatternProperties {
+			rxs = append(rxs, rx.String())
+			patterns[rx.String()] = v
+		}
+		sort.Strings(rxs)
+		for _, src := range rxs {
+			rx := g.variable(g.patterns, "pattern", "regexp.MustCompile("+strconv.Quote(src)+")")
+			fn := g.ref(patterns[src], hint+"PatternProperty")
+			g.printf("if %s.MatchString(k) {\nmatched = true\n", rx)
+			g.printf("if err := %s(m[k]); err != nil {\n", fn)
+			g.printf("return fmt.Errorf(\"property %%q: %%w\", k, err)\n}\n}\n")
+		}
+
+		switch {
+		case ap == nil:
+			g.printf("if !matched {\n")
+			g.printf("return fmt.Errorf(\"additional property %%q is not allowed\", k)\n}\n")
+		case ap.Schema != nil:
+			fn := g.ref(ap.Schema, hint+"AdditionalProperty")
+			g.printf("if !matched {\nif err := %s(m[k]); err != nil {\n", fn)
+			g.printf("return fmt.Errorf(\"property %%q: %%w\", k, err)\n}\n}\n")
+		default:
+			g.printf("_ = matched\n")
+		}
+		g.printf("}\n")
+	}
+
+	if hasDeps {
+		keys := make([]string, 0, len(s.Dependencies.Names)+len(s.Dependencies.Schemas))
+		for k := range s.Dependencies.Names {
+			keys = append(keys, k)
+		}
+		for k := range s.Dependencies.Schemas {
+			if _, ok := s.Dependencies.Names[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			g.printf("if _, ok := m[%q]; ok {\n", k)
+			for _, dep := range s.Dependencies.Names[k] {
+				g.printf("if _, ok := m[%q]; !ok {\n", dep)
+				g.fail(fmt.Sprintf("property %q is required by property %q", dep, k))
+			}
+			if dep, ok := s.Dependencies.Schemas[k]; ok {
+				fn := g.ref(dep, hint+nameOr(exportedName(k), "Property")+"Dependency")
+				g.printf("if err := %s(v); err != nil {\n", fn)
+				g.printf("return fmt.Errorf(%q, err)\n}\n", fmt.Sprintf("dependency of property %q: %%w", k))
+			}
+			g.printf("}\n")
+		}
+	}
+	g.printf("}\n")
+}
+
+func (g *validatorGenerator) declareCombinators(s *schema.Schema, hint string) {
+	for i, v := range s.AllOf {
+		fn := g.ref(v, hint+"AllOf"+strconv.Itoa(i))
+		g.printf("if err := %s(v); err != nil {\n", fn)
+		g.printf("return fmt.Errorf(\"allOf %d: %%w\", err)\n}\n", i)
+	}
+
+	if len(s.AnyOf) > 0 {
+		fns := make([]string, len(s.AnyOf))
+		for i, v := range s.AnyOf {
+			fns[i] = g.ref(v, hint+"AnyOf"+strconv.Itoa(i))
+		}
+		g.printf("if countValid(v, %s) == 0 {\n", strings.Join(fns, ", "))
+		g.fail("value does not match any of anyOf")
+	}
+
+	if len(s.OneOf) > 0 {
+		fns := make([]string, len(s.OneOf))
+		for i, v := range s.OneOf {
+			fns[i] = g.ref(v, hint+"OneOf"+strconv.Itoa(i))
+		}
+		g.printf("if n := countValid(v, %s); n != 1 {\n", strings.Join(fns, ", "))
+		g.printf("return fmt.Errorf(\"value matches %%d of oneOf, instead of exactly one\", n)\n}\n")
+	}
+
+	if s.Not != nil {
+		fn := g.ref(s.Not, hint+"Not")
+		g.printf("if %s(v) == nil {\n", fn)
+		g.fail("value matches the schema of not")
+	}
+}
+
+// goLiteral returns a Go expression for a JSON value, which evaluates
+// to the value as decoded by encoding/json into an interface{}
+func goLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return strconv.Quote(v)
+	case float64:
+		return "float64(" + goFloat(v) + ")"
+	case json.Number:
+		// The exact value is kept, and compared exactly
+		if _, ok := new(big.Rat).SetString(v.String()); ok {
+			return "json.Number(" + strconv.Quote(v.String()) + ")"
+		}
+		return strconv.Quote(v.String())
+	case []interface{}:
+		l := make([]string, len(v))
+		for i, e := range v {
+			l[i] = goLiteral(e)
+		}
+		return "[]interface{}{" + strings.Join(l, ", ") + "}"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		l := make([]string, len(keys))
+		for i, k := range keys {
+			l[i] = strconv.Quote(k) + ": " + goLiteral(v[k])
+		}
+		return "map[string]interface{}{" + strings.Join(l, ", ") + "}"
+	}
+
+	// Other values are converted through their JSON representation
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return "nil"
+	}
+	var decoded interface{}
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	if err := dec.Decode(&decoded); err != nil {
+		return "nil"
+	}
+	return goLiteral(decoded)
+}
+
+// goFloat formats a float64 as a Go expression
+func goFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "math.Inf(1)"
+	case math.IsInf(f, -1):
+		return "math.Inf(-1)"
+	case math.IsNaN(f):
+		return "math.NaN()"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// sortedValues returns the values of the map, sorted
+func sortedValues(m map[string]string) []string {
+	l := make([]string, 0, len(m))
+	for _, v := range m {
+		l = append(l, v)
+	}
+	sort.Strings(l)
+	return l
+}
+
+// validatorImports lists the packages used by validatorHelpers
+var validatorImports = []string{
+	"encoding/json",
+	"fmt",
+	"math",
+	"math/big",
+	"net",
+	"net/mail",
+	"net/url",
+	"regexp",
+	"sort",
+	"strconv",
+	"strings",
+	"time",
+	"unicode/utf8",
+}
+
+const validatorHelpers = `
+// validateAny accepts any value
+func validateAny(v interface{}) error {
+	return nil
+}
+
+// jsonType returns the JSON type of a value. Numbers without a
+// fractional part are integers
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	if r, ok := toRat(v); ok {
+		if r.IsInt() {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// toRat converts numbers to their exact values. Floats are taken as
+// the shortest decimal that they represent, which is the number that
+// they were decoded from
+func toRat(v interface{}) (*big.Rat, bool) {
+	var s string
+	switch v := v.(type) {
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, false
+		}
+		s = strconv.FormatFloat(v, 'g', -1, 64)
+	case float32:
+		if math.IsInf(float64(v), 0) || math.IsNaN(float64(v)) {
+			return nil, false
+		}
+		s = strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case int:
+		return new(big.Rat).SetInt64(int64(v)), true
+	case int64:
+		return new(big.Rat).SetInt64(v), true
+	case json.Number:
+		s = v.String()
+	default:
+		return nil, false
+	}
+	return new(big.Rat).SetString(s)
+}
+
+// mustRat returns the exact value of a number
+func mustRat(s string) *big.Rat {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		panic("invalid number " + s)
+	}
+	return r
+}
+
+// isMultiple returns true if r is a multiple of m
+func isMultiple(r, m *big.Rat) bool {
+	return new(big.Rat).Quo(r, m).IsInt()
+}
+
+// equalValues returns true if two JSON values are equal. Numbers are
+// compared by their exact values
+func equalValues(a, b interface{}) bool {
+	if ra, ok := toRat(a); ok {
+		rb, ok := toRat(b)
+		return ok && ra.Cmp(rb) == 0
+	}
+	switch a := a.(type) {
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equalValues(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			w, ok := b[k]
+			if !ok || !equalValues(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// containsValue returns true if l contains a value equal to v
+func containsValue(l []interface{}, v interface{}) bool {
+	for _, e := range l {
+		if equalValues(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// countValid returns the number of functions that accept v
+func countValid(v interface{}, fns ...func(interface{}) error) int {
+	n := 0
+	for _, fn := range fns {
+		if fn(v) == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// stringLength returns the length of a string in code points
+func stringLength(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isDateTime(s string) bool {
+	_, err := time.Parse(time.RFC3339, s)
+	return err == nil
+}
+
+func isEmail(s string) bool {
+	_, err := mail.ParseAddress(s)
+	return err == nil
+}
+
+var hostnameRx = regexp.MustCompile(` + "`" + `^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$` + "`" + `)
+
+func isHostname(s string) bool {
+	return len(s) <= 255 && hostnameRx.MatchString(s)
+}
+
+func isIPv4(s string) bool {
+	ip := net.ParseIP(s)
+	return ip != nil && ip.To4() != nil && !strings.Contains(s, ":")
+}
+
+func isIPv6(s string) bool {
+	ip := net.ParseIP(s)
+	return ip != nil && strings.Contains(s, ":")
+}
+
+func isURI(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.IsAbs()
+}
+`
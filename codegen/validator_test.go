@@ -0,0 +1,276 @@
+package codegen_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/lestrrat-go/jsschema/codegen"
+	"github.com/lestrrat-go/jsschema/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateGoValidator(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(petStore))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, codegen.GenerateGoValidator(&buf, s, codegen.WithPackageName("petstore")), "GenerateGoValidator should succeed") {
+		return
+	}
+	src := buf.String()
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "petstore.go", src, parser.ParseComments); !assert.NoError(t, err, "generated code should parse") {
+		return
+	}
+
+	expected := []string{
+		"package petstore",
+		"func ValidatePetStore(v interface{}) error {\n\treturn validatePetStore(v)\n}",
+		"func validatePetStore(v interface{}) error {",
+		`if _, ok := m["name"]; !ok { return errors.New("required property \"name\" is missing") }`,
+		`return fmt.Errorf("property \"pets\": %w", err)`,
+		`return fmt.Errorf("item %d: %w", i, err)`,
+		"func validatePetStorePet(v interface{}) error {",
+		"func validatePetStorePerson(v interface{}) error {",
+		`[]interface{}{"open", "closed"}`,
+		"func jsonType(v interface{}) string {",
+	}
+	for _, e := range expected {
+		if !assert.Contains(t, strings.Join(strings.Fields(src), " "), strings.Join(strings.Fields(e), " "), "generated code should contain %s", e) {
+			return
+		}
+	}
+}
+
+// validateNumbers validates `data`, decoded with json.Number values as
+// the generated validators do, against `s`
+func validateNumbers(t *testing.T, s *schema.Schema, data []byte) bool {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("failed to decode %s: %s", data, err)
+	}
+	return validator.New(s).Validate(v) == nil
+}
+
+func TestGenerateGoValidatorInfinity(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(`{"type": "number", "minimum": 0, "maximum": 10}`))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+	s.Minimum.Val = math.Inf(-1)
+	s.Minimum.Exact = ""
+	s.Enum = []interface{}{math.Inf(1), json.Number("0.1")}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, codegen.GenerateGoValidator(&buf, s), "GenerateGoValidator should succeed") {
+		return
+	}
+	src := buf.String()
+	if !assert.NoError(t, typeCheck("validator.go", src), "generated code should type-check") {
+		return
+	}
+	for _, e := range []string{"f < math.Inf(-1)", "float64(math.Inf(1))", `json.Number("0.1")`, `mustRat("10")`} {
+		if !assert.Contains(t, src, e, "generated code should contain %s", e) {
+			return
+		}
+	}
+	if !assert.NotContains(t, src, "math.NaN()", "infinity should not be generated as NaN") {
+		return
+	}
+}
+
+func TestGenerateGoValidatorUnresolvable(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(`{"properties": {"a": {"$ref": "#/definitions/missing"}}}`))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.Error(t, codegen.GenerateGoValidator(&buf, s), "GenerateGoValidator should fail") {
+		return
+	}
+}
+
+// fixtureName extracts the name of the schema from the name of a fixture
+var fixtureName = regexp.MustCompile(`^(.+)_(?:pass|fail)`)
+
+// validatorCase is a value, along with the index of the schema that it
+// is validated against and the expected result
+type validatorCase struct {
+	Schema int             `json:"schema"`
+	Data   json.RawMessage `json:"data"`
+	name   string
+	valid  bool
+}
+
+// TestGenerateGoValidatorRun builds the validators generated for the
+// schemas under test/, and checks that they accept the same fixtures
+// as jsval
+func TestGenerateGoValidatorRun(t *testing.T) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command is not available")
+	}
+
+	var schemas []*schema.Schema
+	var cases []validatorCase
+
+	files, err := filepath.Glob(filepath.Join("..", "test", "*_pass*.json"))
+	if !assert.NoError(t, err, "filepath.Glob should succeed") {
+		return
+	}
+	fails, err := filepath.Glob(filepath.Join("..", "test", "*_fail*.json"))
+	if !assert.NoError(t, err, "filepath.Glob should succeed") {
+		return
+	}
+	indices := make(map[string]int)
+	for _, file := range append(files, fails...) {
+		name := fixtureName.FindStringSubmatch(filepath.Base(file))[1]
+		idx, ok := indices[name]
+		if !ok {
+			s, err := schema.ReadFile(filepath.Join("..", "test", name+".json"))
+			if !assert.NoError(t, err, "schema.ReadFile(%s) should succeed", name) {
+				return
+			}
+			idx = len(schemas)
+			indices[name] = idx
+			schemas = append(schemas, s)
+		}
+
+		data, err := ioutil.ReadFile(file)
+		if !assert.NoError(t, err, "ioutil.ReadFile(%s) should succeed", file) {
+			return
+		}
+		cases = append(cases, validatorCase{
+			Schema: idx,
+			Data:   data,
+			name:   file,
+			valid:  validateNumbers(t, schemas[idx], data),
+		})
+	}
+
+	// Numbers are compared exactly by both validators
+	s, err := schema.Read(strings.NewReader(`{"type": "integer", "enum": [9007199254740993, 1]}`))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+	schemas = append(schemas, s)
+	for _, data := range []string{`9007199254740993`, `9007199254740992`, `1`, `1.0`} {
+		cases = append(cases, validatorCase{Schema: len(schemas) - 1, Data: json.RawMessage(data), name: data, valid: validateNumbers(t, s, []byte(data))})
+	}
+
+	// Lengths are counted in code points, and bounds and multipleOf are
+	// checked exactly, which jsval does not do
+	for src, values := range map[string]map[string]bool{
+		`{"type": "string", "minLength": 2, "maxLength": 3}`:                  {`"日本"`: true, `"日本語"`: true, `"éé"`: true, `"日"`: false, `"日本語です"`: false, `"ab🙂🙂"`: false},
+		`{"type": "number", "multipleOf": 0.01, "maximum": 9007199254740992}`: {`0.07`: true, `1.1`: true, `0.075`: false, `9007199254740992`: true, `9007199254740993`: false},
+		`{"type": "number", "minimum": 0.1, "exclusiveMinimum": true}`:        {`0.1`: false, `0.10000000000000001`: true, `0.2`: true},
+	} {
+		s, err := schema.Read(strings.NewReader(src))
+		if !assert.NoError(t, err, "schema.Read should succeed") {
+			return
+		}
+		schemas = append(schemas, s)
+		for data, valid := range values {
+			cases = append(cases, validatorCase{Schema: len(schemas) - 1, Data: json.RawMessage(data), name: src + " " + data, valid: valid})
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "jsschema-validator")
+	if !assert.NoError(t, err, "ioutil.TempDir should succeed") {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	var main bytes.Buffer
+	main.WriteString("package main\n\nimport (\n\t\"encoding/json\"\n\t\"fmt\"\n\t\"io\"\n\t\"os\"\n\n")
+	for i := range schemas {
+		fmt.Fprintf(&main, "\t\"generated/s%d\"\n", i)
+	}
+	main.WriteString(")\n\nvar validators = []func(interface{}) error{\n")
+	for i, s := range schemas {
+		pkg := fmt.Sprintf("s%d", i)
+		var buf bytes.Buffer
+		if !assert.NoError(t, codegen.GenerateGoValidator(&buf, s, codegen.WithPackageName(pkg), codegen.WithTypeName("Root")), "GenerateGoValidator should succeed") {
+			return
+		}
+		if !assert.NoError(t, os.Mkdir(filepath.Join(dir, pkg), 0755), "os.Mkdir should succeed") {
+			return
+		}
+		if !assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, pkg, "validator.go"), buf.Bytes(), 0644), "ioutil.WriteFile should succeed") {
+			return
+		}
+		fmt.Fprintf(&main, "\t%s.ValidateRoot,\n", pkg)
+	}
+	main.WriteString(`}
+
+// main validates the values read from stdin, printing the results
+func main() {
+	dec := json.NewDecoder(os.Stdin)
+	dec.UseNumber()
+	for {
+		var c struct {
+			Schema int
+			Data   interface{}
+		}
+		if err := dec.Decode(&c); err == io.EOF {
+			return
+		} else if err != nil {
+			panic(err)
+		}
+		fmt.Println(validators[c.Schema](c.Data) == nil)
+	}
+}
+`)
+	if !assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "main.go"), main.Bytes(), 0644), "ioutil.WriteFile should succeed") {
+		return
+	}
+	if !assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module generated\n\ngo 1.13\n"), 0644), "ioutil.WriteFile should succeed") {
+		return
+	}
+
+	var input bytes.Buffer
+	enc := json.NewEncoder(&input)
+	for _, c := range cases {
+		if !assert.NoError(t, enc.Encode(c), "Encode should succeed") {
+			return
+		}
+	}
+
+	cmd := exec.Command(gobin, "run", ".")
+	cmd.Dir = dir
+	cmd.Stdin = &input
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if !assert.NoError(t, err, "generated validators should run: %s", stderr.String()) {
+		return
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for _, c := range cases {
+		if !assert.True(t, sc.Scan(), "result for %s should be printed", c.name) {
+			return
+		}
+		if !assert.Equal(t, fmt.Sprint(c.valid), sc.Text(), "generated validator should agree on %s", c.name) {
+			return
+		}
+	}
+}
@@ -0,0 +1,528 @@
+// Package docgen generates human readable documentation from JSON
+// schemas, in Markdown or HTML.
+package docgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/lestrrat-go/jsschema/internal/option"
+	"github.com/pkg/errors"
+)
+
+// Option is an option that can be passed to the generators
+type Option interface {
+	option.Interface
+}
+
+const (
+	optkeyTitle = "title"
+)
+
+// WithTitle specifies the title of the document. By default the title
+// of the schema is used, or "Schema" if it has none.
+func WithTitle(s string) Option {
+	return option.New(optkeyTitle, s)
+}
+
+// span is a piece of text, which may be rendered as code or as a link
+type span struct {
+	text string
+	href string
+	code bool
+}
+
+// text is a sequence of spans, which the renderers escape as needed
+type text []span
+
+func plain(s string) span {
+	return span{text: s}
+}
+
+func code(s string) span {
+	return span{text: s, code: true}
+}
+
+// section documents the root schema or one of its definitions
+type section struct {
+	anchor      string
+	title       string
+	description string
+	typ         text
+	constraints []text
+	def         text
+	examples    []text
+	rows        []row
+}
+
+// row documents a property in the table of properties of a section
+type row struct {
+	name        text
+	typ         text
+	required    bool
+	constraints []text
+	def         text
+	examples    []text
+	description string
+}
+
+type document struct {
+	title       string
+	root        *section
+	definitions []*section
+}
+
+type builder struct {
+	anchors  map[*schema.Schema]string
+	titles   map[*schema.Schema]string
+	used     map[string]struct{}
+	visiting map[*schema.Schema]struct{}
+	err      error
+}
+
+// build collects the contents of the documentation for `s`
+func build(s *schema.Schema, options []Option) (*document, error) {
+	b := builder{
+		anchors:  make(map[*schema.Schema]string),
+		titles:   make(map[*schema.Schema]string),
+		used:     make(map[string]struct{}),
+		visiting: make(map[*schema.Schema]struct{}),
+	}
+
+	doc := document{title: s.Title}
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyTitle:
+			doc.title = o.Value().(string)
+		}
+	}
+	if doc.title == "" {
+		doc.title = "Schema"
+	}
+
+	// All anchors need to be known before any section is built, as
+	// sections link to each other
+	b.anchor(s, doc.title)
+	keys := make([]string, 0, len(s.Definitions))
+	for k := range s.Definitions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.anchor(s.Definitions[k], k)
+	}
+
+	doc.root = b.section(s, doc.title)
+	for _, k := range keys {
+		doc.definitions = append(doc.definitions, b.section(s.Definitions[k], k))
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	return &doc, nil
+}
+
+// anchor registers `s` as the target of links titled `title`
+func (b *builder) anchor(s *schema.Schema, title string) {
+	if _, ok := b.anchors[s]; ok {
+		return
+	}
+
+	base := slug(title)
+	name := base
+	for i := 2; ; i++ {
+		if _, ok := b.used[name]; !ok {
+			break
+		}
+		name = base + "-" + strconv.Itoa(i)
+	}
+	b.used[name] = struct{}{}
+	b.anchors[s] = name
+	b.titles[s] = title
+}
+
+// slug converts `s` into a string that can be used as an anchor
+func slug(s string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' {
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			dash = false
+			sb.WriteRune(r)
+			continue
+		}
+		dash = true
+	}
+	if sb.Len() == 0 {
+		return "schema"
+	}
+	return sb.String()
+}
+
+func (b *builder) section(s *schema.Schema, title string) *section {
+	sec := section{
+		anchor:      b.anchors[s],
+		title:       title,
+		description: s.Description,
+		typ:         b.typeText(s),
+		constraints: b.constraints(s),
+		def:         defaultText(s),
+		examples:    examples(s),
+	}
+	if s.Title != "" && s.Title != title {
+		sec.description = strings.TrimSpace(s.Title + "\n\n" + s.Description)
+	}
+	b.rows(&sec.rows, s, "")
+	return &sec
+}
+
+// rows appends a row for each property of `s`, followed by the
+// properties of inline objects, which are named after their path
+func (b *builder) rows(rows *[]row, s *schema.Schema, prefix string) {
+	// Inline members of allOf contribute properties to the object
+	members := []*schema.Schema{s}
+	for _, v := range s.AllOf {
+		if v.Reference == "" {
+			members = append(members, v)
+		}
+	}
+
+	for _, m := range members {
+		names := make([]string, 0, len(m.Properties))
+		for name := range m.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			prop := m.Properties[name]
+			*rows = append(*rows, b.row(text{code(prefix + name)}, prop, m.IsPropRequired(name)))
+			if prop.Reference != "" {
+				continue
+			}
+			b.rows(rows, prop, prefix+name+".")
+			if items := prop.Items; items != nil && !items.TupleMode && len(items.Schemas) == 1 && items.Schemas[0].Reference == "" {
+				b.rows(rows, items.Schemas[0], prefix+name+"[].")
+			}
+		}
+
+		patterns := make([]string, 0, len(m.PatternProperties))
+		byPattern := make(map[string]*schema.Schema)
+		for rx, prop := range m.PatternProperties {
+			patterns = append(patterns, rx.String())
+			byPattern[rx.String()] = prop
+		}
+		sort.Strings(patterns)
+		for _, pattern := range patterns {
+			*rows = append(*rows, b.row(text{code(prefix + "/" + pattern + "/")}, byPattern[pattern], false))
+		}
+
+		if ap := m.AdditionalProperties; ap != nil && ap.Schema != nil {
+			*rows = append(*rows, b.row(text{code(prefix + "*")}, ap.Schema, false))
+		}
+	}
+}
+
+func (b *builder) row(name text, s *schema.Schema, required bool) row {
+	r := row{
+		name:        name,
+		typ:         b.typeText(s),
+		required:    required,
+		constraints: b.constraints(s),
+		def:         defaultText(s),
+		examples:    examples(s),
+		description: s.Description,
+	}
+	if r.description == "" && s.Title != "" {
+		r.description = s.Title
+	}
+	return r
+}
+
+// deref follows the references of `s`, if any
+func (b *builder) deref(s *schema.Schema) *schema.Schema {
+	if s == nil || s.Reference == "" {
+		return s
+	}
+	t, err := s.Resolve(nil)
+	if err != nil {
+		if b.err == nil {
+			b.err = errors.Wrapf(err, "failed to resolve reference at %s", s.Pointer())
+		}
+		return nil
+	}
+	return t
+}
+
+// typeText describes the type of values that `s` accepts. Schemas that
+// have their own section are linked to instead.
+func (b *builder) typeText(s *schema.Schema) text {
+	if s == nil {
+		return text{plain("any")}
+	}
+
+	if s.Reference != "" {
+		t := b.deref(s)
+		if t == nil {
+			return text{code(s.Reference)}
+		}
+		if anchor, ok := b.anchors[t]; ok {
+			return text{span{text: b.titles[t], href: "#" + anchor}}
+		}
+		if _, ok := b.visiting[t]; ok {
+			return text{code(s.Reference)}
+		}
+		b.visiting[t] = struct{}{}
+		defer delete(b.visiting, t)
+		return b.typeText(t)
+	}
+
+	var parts []text
+	if own := b.ownType(s); len(own) > 0 {
+		parts = append(parts, own)
+	}
+	for _, c := range []struct {
+		label string
+		list  schema.SchemaList
+	}{{"all of", s.AllOf}, {"any of", s.AnyOf}, {"one of", s.OneOf}} {
+		if len(c.list) > 0 {
+			parts = append(parts, b.listText(c.label, c.list))
+		}
+	}
+	if s.Not != nil {
+		parts = append(parts, b.listText("not", schema.SchemaList{s.Not}))
+	}
+
+	if len(parts) == 0 {
+		return text{plain("any")}
+	}
+	return join(parts, ", ")
+}
+
+func (b *builder) listText(label string, list schema.SchemaList) text {
+	l := make([]text, len(list))
+	for i, v := range list {
+		l[i] = b.typeText(v)
+	}
+	t := text{plain(label + " (")}
+	t = append(t, join(l, ", ")...)
+	return append(t, plain(")"))
+}
+
+// ownType describes the types listed in `s`, or guessed from the
+// keywords of `s` if it lists none
+func (b *builder) ownType(s *schema.Schema) text {
+	types := make([]string, 0, len(s.Type))
+	for _, t := range s.Type {
+		types = append(types, t.String())
+	}
+	if len(types) == 0 {
+		switch {
+		case len(s.Enum) > 0:
+			for _, v := range s.Enum {
+				types = append(types, jsonType(v))
+			}
+		case len(s.Properties) > 0 || len(s.PatternProperties) > 0 || len(s.Required) > 0:
+			types = append(types, "object")
+		case s.Items != nil:
+			types = append(types, "array")
+		}
+	}
+	types = uniqueStrings(types)
+
+	l := make([]text, len(types))
+	for i, t := range types {
+		l[i] = text{plain(t)}
+		if t == "array" && s.Items != nil && len(s.Items.Schemas) > 0 {
+			if s.Items.TupleMode {
+				l[i] = b.listText("tuple", s.Items.Schemas)
+			} else {
+				l[i] = append(text{plain("array of ")}, b.typeText(s.Items.Schemas[0])...)
+			}
+		}
+	}
+	return join(l, " or ")
+}
+
+func join(l []text, sep string) text {
+	var t text
+	for i, v := range l {
+		if i > 0 {
+			t = append(t, plain(sep))
+		}
+		t = append(t, v...)
+	}
+	return t
+}
+
+// constraints lists the validation keywords of `s`
+func (b *builder) constraints(s *schema.Schema) []text {
+	var l []text
+	add := func(spans ...span) {
+		l = append(l, text(spans))
+	}
+	if s.Format != "" {
+		add(plain("format: "), code(string(s.Format)))
+	}
+	if len(s.Enum) > 0 {
+		t := text{plain("enum: ")}
+		for i, v := range s.Enum {
+			if i > 0 {
+				t = append(t, plain(", "))
+			}
+			t = append(t, code(jsonText(v)))
+		}
+		add(t...)
+	}
+	if s.Minimum.Initialized {
+		add(plain("minimum: " + numberText(s.Minimum) + exclusive(s.ExclusiveMinimum)))
+	}
+	if s.Maximum.Initialized {
+		add(plain("maximum: " + numberText(s.Maximum) + exclusive(s.ExclusiveMaximum)))
+	}
+	if s.MultipleOf.Initialized {
+		add(plain("multipleOf: " + numberText(s.MultipleOf)))
+	}
+	if s.MinLength.Initialized {
+		add(plain("minLength: " + strconv.Itoa(s.MinLength.Val)))
+	}
+	if s.MaxLength.Initialized {
+		add(plain("maxLength: " + strconv.Itoa(s.MaxLength.Val)))
+	}
+	if s.Pattern != nil {
+		add(plain("pattern: "), code(s.Pattern.String()))
+	}
+	if s.MinItems.Initialized {
+		add(plain("minItems: " + strconv.Itoa(s.MinItems.Val)))
+	}
+	if s.MaxItems.Initialized {
+		add(plain("maxItems: " + strconv.Itoa(s.MaxItems.Val)))
+	}
+	if s.UniqueItems.Bool() {
+		add(plain("uniqueItems"))
+	}
+	if s.Items != nil && s.Items.TupleMode {
+		switch ai := s.AdditionalItems; {
+		case ai == nil:
+			add(plain("no additional items"))
+		case ai.Schema != nil:
+			add(append(text{plain("additional items: ")}, b.typeText(ai.Schema)...)...)
+		}
+	}
+	if s.MinProperties.Initialized {
+		add(plain("minProperties: " + strconv.Itoa(s.MinProperties.Val)))
+	}
+	if s.MaxProperties.Initialized {
+		add(plain("maxProperties: " + strconv.Itoa(s.MaxProperties.Val)))
+	}
+	if s.AdditionalProperties == nil {
+		add(plain("no additional properties"))
+	}
+
+	names := make([]string, 0, len(s.Dependencies.Names)+len(s.Dependencies.Schemas))
+	for name := range s.Dependencies.Names {
+		names = append(names, name)
+	}
+	for name := range s.Dependencies.Schemas {
+		names = append(names, name)
+	}
+	for _, name := range uniqueStrings(names) {
+		if deps := s.Dependencies.Names[name]; len(deps) > 0 {
+			t := text{code(name), plain(" requires ")}
+			for i, dep := range deps {
+				if i > 0 {
+					t = append(t, plain(", "))
+				}
+				t = append(t, code(dep))
+			}
+			add(t...)
+		}
+		if dep := s.Dependencies.Schemas[name]; dep != nil {
+			add(append(text{code(name), plain(" requires ")}, b.typeText(dep)...)...)
+		}
+	}
+	return l
+}
+
+func exclusive(b schema.Bool) string {
+	if b.Bool() {
+		return " (exclusive)"
+	}
+	return ""
+}
+
+func numberText(n schema.Number) string {
+	if n.Exact != "" {
+		return n.Exact.String()
+	}
+	return strconv.FormatFloat(n.Val, 'g', -1, 64)
+}
+
+func defaultText(s *schema.Schema) text {
+	if s.Default == nil {
+		return nil
+	}
+	return text{code(jsonText(s.Default))}
+}
+
+// examples returns the values of the "examples" keyword of `s`, as
+// well as the "example" keyword that OpenAPI uses
+func examples(s *schema.Schema) []text {
+	var l []text
+	if list, ok := s.Extras["examples"].([]interface{}); ok {
+		for _, v := range list {
+			l = append(l, text{code(jsonText(v))})
+		}
+	}
+	if v, ok := s.Extras["example"]; ok {
+		l = append(l, text{code(jsonText(v))})
+	}
+	return l
+}
+
+func jsonText(v interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "?"
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// jsonType returns the name of the JSON type of `v`
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "number"
+}
+
+// uniqueStrings sorts `l` and removes duplicates, in place
+func uniqueStrings(l []string) []string {
+	sort.Strings(l)
+	out := l[:0]
+	for i, v := range l {
+		if i > 0 && v == l[i-1] {
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package docgen_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/lestrrat-go/jsschema/docgen"
+	"github.com/stretchr/testify/assert"
+)
+
+const petStore = `{
+  "title": "Pet store",
+  "description": "A pet store",
+  "type": "object",
+  "required": ["name"],
+  "additionalProperties": false,
+  "properties": {
+    "name": {"type": "string", "minLength": 1, "description": "Name of the store", "examples": ["Pets & Co"]},
+    "status": {"enum": ["open", "closed"], "default": "open"},
+    "code": {"type": "string", "pattern": "^[A-Z]{2}|[0-9]{3}$"},
+    "pets": {"type": "array", "items": {"$ref": "#/definitions/pet"}, "uniqueItems": true},
+    "address": {
+      "type": "object",
+      "properties": {"zip": {"type": "string"}, "city": {"type": ["string", "null"]}}
+    }
+  },
+  "definitions": {
+    "pet": {
+      "type": "object",
+      "required": ["name"],
+      "properties": {
+        "name": {"type": "string"},
+        "age": {"type": "integer", "minimum": 0, "maximum": 30, "exclusiveMaximum": true},
+        "parent": {"$ref": "#/definitions/pet"}
+      }
+    }
+  }
+}`
+
+func TestGenerateMarkdown(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(petStore))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, docgen.GenerateMarkdown(&buf, s), "GenerateMarkdown should succeed") {
+		return
+	}
+
+	expected := "<a id=\"pet-store\"></a>\n" +
+		"\n" +
+		"# Pet store\n" +
+		"\n" +
+		"A pet store\n" +
+		"\n" +
+		"- **Type:** object\n" +
+		"- no additional properties\n" +
+		"\n" +
+		"| Property | Type | Required | Constraints | Default | Examples | Description |\n" +
+		"| --- | --- | --- | --- | --- | --- | --- |\n" +
+		"| `address` | object | no |  |  |  |  |\n" +
+		"| `address.city` | null or string | no |  |  |  |  |\n" +
+		"| `address.zip` | string | no |  |  |  |  |\n" +
+		"| `code` | string | no | pattern: `^[A-Z]{2}\\|[0-9]{3}$` |  |  |  |\n" +
+		"| `name` | string | yes | minLength: 1 |  | `\"Pets & Co\"` | Name of the store |\n" +
+		"| `pets` | array of [pet](#pet) | no | uniqueItems |  |  |  |\n" +
+		"| `status` | string | no | enum: `\"open\"`, `\"closed\"` | `\"open\"` |  |  |\n" +
+		"\n" +
+		"## Definitions\n" +
+		"\n" +
+		"<a id=\"pet\"></a>\n" +
+		"\n" +
+		"### pet\n" +
+		"\n" +
+		"- **Type:** object\n" +
+		"\n" +
+		"| Property | Type | Required | Constraints | Description |\n" +
+		"| --- | --- | --- | --- | --- |\n" +
+		"| `age` | integer | no | minimum: 0<br>maximum: 30 (exclusive) |  |\n" +
+		"| `name` | string | yes |  |  |\n" +
+		"| `parent` | [pet](#pet) | no |  |  |\n"
+	if !assert.Equal(t, expected, buf.String(), "generated Markdown should match") {
+		return
+	}
+}
+
+func TestGenerateHTML(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(petStore))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, docgen.GenerateHTML(&buf, s, docgen.WithTitle("Pets <API>")), "GenerateHTML should succeed") {
+		return
+	}
+
+	expected := []string{
+		"<title>Pets &lt;API&gt;</title>",
+		`<section id="pets-api">`,
+		"<h1>Pets &lt;API&gt;</h1>",
+		"<p>Pet store</p>\n<p>A pet store</p>",
+		`<tr><td><code>name</code></td><td>string</td><td>yes</td><td>minLength: 1</td><td></td><td><code>&#34;Pets &amp; Co&#34;</code></td><td>Name of the store</td></tr>`,
+		`<td>array of <a href="#pet">pet</a></td>`,
+		`<section id="pet">`,
+		"<td>enum: <code>&#34;open&#34;</code>, <code>&#34;closed&#34;</code></td>",
+	}
+	for _, e := range expected {
+		if !assert.Contains(t, buf.String(), e, "generated HTML should contain %s", e) {
+			return
+		}
+	}
+}
+
+func TestGenerateMarkdownUnresolvable(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(`{"properties": {"a": {"$ref": "#/definitions/missing"}}}`))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.Error(t, docgen.GenerateMarkdown(&buf, s), "GenerateMarkdown should fail") {
+		return
+	}
+}
@@ -0,0 +1,108 @@
+package docgen
+
+import (
+	"bytes"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/lestrrat-go/jsschema"
+)
+
+const htmlStyle = `body { font-family: sans-serif; line-height: 1.5; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.5em; text-align: left; vertical-align: top; }
+code { background: #f4f4f4; padding: 0 0.2em; }`
+
+// GenerateHTML writes the documentation of the schema `s` to `w` as a
+// standalone HTML page. The contents are the same as those written by
+// GenerateMarkdown, except that descriptions are rendered as plain
+// text.
+func GenerateHTML(w io.Writer, s *schema.Schema, options ...Option) error {
+	doc, err := build(s, options)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	buf.WriteString("<title>" + html.EscapeString(doc.title) + "</title>\n")
+	buf.WriteString("<style>\n" + htmlStyle + "\n</style>\n</head>\n<body>\n")
+	writeHTMLSection(&buf, doc.root, "h1")
+	if len(doc.definitions) > 0 {
+		buf.WriteString("<h2>Definitions</h2>\n")
+		for _, sec := range doc.definitions {
+			writeHTMLSection(&buf, sec, "h3")
+		}
+	}
+	buf.WriteString("</body>\n</html>\n")
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func writeHTMLSection(buf *bytes.Buffer, sec *section, heading string) {
+	buf.WriteString(`<section id="` + html.EscapeString(sec.anchor) + `">` + "\n")
+	buf.WriteString("<" + heading + ">" + html.EscapeString(sec.title) + "</" + heading + ">\n")
+	if sec.description != "" {
+		for _, p := range strings.Split(strings.TrimSpace(sec.description), "\n\n") {
+			buf.WriteString("<p>" + htmlCell(p) + "</p>\n")
+		}
+	}
+
+	buf.WriteString("<ul>\n<li><strong>Type:</strong> " + htmlText(sec.typ) + "</li>\n")
+	for _, c := range sec.constraints {
+		buf.WriteString("<li>" + htmlText(c) + "</li>\n")
+	}
+	if len(sec.def) > 0 {
+		buf.WriteString("<li><strong>Default:</strong> " + htmlText(sec.def) + "</li>\n")
+	}
+	if len(sec.examples) > 0 {
+		buf.WriteString("<li><strong>Examples:</strong> " + htmlList(sec.examples, ", ") + "</li>\n")
+	}
+	buf.WriteString("</ul>\n")
+
+	if len(sec.rows) > 0 {
+		cols := columns(sec.rows)
+		buf.WriteString("<table>\n<tr><th>" + strings.Join(cols.headers(), "</th><th>") + "</th></tr>\n")
+		for _, r := range sec.rows {
+			cells := []string{htmlText(r.name), htmlText(r.typ), yesNo(r.required), htmlList(r.constraints, "<br>")}
+			if cols.def {
+				cells = append(cells, htmlText(r.def))
+			}
+			if cols.examples {
+				cells = append(cells, htmlList(r.examples, "<br>"))
+			}
+			cells = append(cells, htmlCell(r.description))
+			buf.WriteString("<tr><td>" + strings.Join(cells, "</td><td>") + "</td></tr>\n")
+		}
+		buf.WriteString("</table>\n")
+	}
+	buf.WriteString("</section>\n")
+}
+
+func htmlText(t text) string {
+	var b strings.Builder
+	for _, s := range t {
+		v := html.EscapeString(s.text)
+		if s.code {
+			v = "<code>" + v + "</code>"
+		}
+		if s.href != "" {
+			v = `<a href="` + html.EscapeString(s.href) + `">` + v + "</a>"
+		}
+		b.WriteString(v)
+	}
+	return b.String()
+}
+
+func htmlList(l []text, sep string) string {
+	s := make([]string, len(l))
+	for i, t := range l {
+		s[i] = htmlText(t)
+	}
+	return strings.Join(s, sep)
+}
+
+func htmlCell(s string) string {
+	return strings.Replace(html.EscapeString(strings.TrimSpace(s)), "\n", "<br>", -1)
+}
@@ -0,0 +1,155 @@
+package docgen
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/lestrrat-go/jsschema"
+)
+
+// GenerateMarkdown writes the documentation of the schema `s` to `w`
+// in Markdown. The document has a section for the root schema, and one
+// for each of its definitions. Each section describes the type and the
+// constraints of the schema, followed by a table of its properties.
+// Properties of inline objects are listed in the same table, named
+// after their path (e.g. "address.zip" or "pets[].name"), while
+// references link to the section of their target.
+//
+// Descriptions are copied as they are, so that they may use Markdown.
+func GenerateMarkdown(w io.Writer, s *schema.Schema, options ...Option) error {
+	doc, err := build(s, options)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	writeMarkdownSection(&buf, doc.root, "#")
+	if len(doc.definitions) > 0 {
+		buf.WriteString("\n## Definitions\n")
+		for _, sec := range doc.definitions {
+			buf.WriteString("\n")
+			writeMarkdownSection(&buf, sec, "###")
+		}
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func writeMarkdownSection(buf *bytes.Buffer, sec *section, heading string) {
+	buf.WriteString(`<a id="` + sec.anchor + `"></a>` + "\n\n")
+	buf.WriteString(heading + " " + escapeMarkdown(sec.title) + "\n\n")
+	if sec.description != "" {
+		buf.WriteString(strings.TrimSpace(sec.description) + "\n\n")
+	}
+
+	buf.WriteString("- **Type:** " + markdownText(sec.typ) + "\n")
+	for _, c := range sec.constraints {
+		buf.WriteString("- " + markdownText(c) + "\n")
+	}
+	if len(sec.def) > 0 {
+		buf.WriteString("- **Default:** " + markdownText(sec.def) + "\n")
+	}
+	if len(sec.examples) > 0 {
+		buf.WriteString("- **Examples:** " + markdownList(sec.examples, ", ") + "\n")
+	}
+
+	if len(sec.rows) == 0 {
+		return
+	}
+
+	cols := columns(sec.rows)
+	buf.WriteString("\n| " + strings.Join(cols.headers(), " | ") + " |\n")
+	buf.WriteString("|" + strings.Repeat(" --- |", len(cols.headers())) + "\n")
+	for _, r := range sec.rows {
+		cells := []string{markdownText(r.name), markdownText(r.typ), yesNo(r.required), markdownList(r.constraints, "<br>")}
+		if cols.def {
+			cells = append(cells, markdownText(r.def))
+		}
+		if cols.examples {
+			cells = append(cells, markdownList(r.examples, "<br>"))
+		}
+		cells = append(cells, markdownCell(r.description))
+		buf.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+}
+
+// markdownText renders `t`, escaping it to be used in a table cell
+func markdownText(t text) string {
+	var b strings.Builder
+	for _, s := range t {
+		v := s.text
+		if s.code {
+			fence := "`"
+			for strings.Contains(v, fence) {
+				fence += "`"
+			}
+			if strings.HasPrefix(v, "`") || strings.HasSuffix(v, "`") {
+				v = " " + v + " "
+			}
+			v = fence + v + fence
+		} else {
+			v = escapeMarkdown(v)
+		}
+		if s.href != "" {
+			v = "[" + v + "](" + s.href + ")"
+		}
+		b.WriteString(strings.Replace(v, "|", `\|`, -1))
+	}
+	return b.String()
+}
+
+func markdownList(l []text, sep string) string {
+	s := make([]string, len(l))
+	for i, t := range l {
+		s[i] = markdownText(t)
+	}
+	return strings.Join(s, sep)
+}
+
+// markdownCell fits Markdown text, such as a description, in a table cell
+func markdownCell(s string) string {
+	s = strings.Replace(strings.TrimSpace(s), "|", `\|`, -1)
+	return strings.Replace(s, "\n", "<br>", -1)
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, "`", "\\`",
+)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// tableColumns records which optional columns a table of properties has
+type tableColumns struct {
+	def      bool
+	examples bool
+}
+
+func columns(rows []row) tableColumns {
+	var cols tableColumns
+	for _, r := range rows {
+		cols.def = cols.def || len(r.def) > 0
+		cols.examples = cols.examples || len(r.examples) > 0
+	}
+	return cols
+}
+
+func (cols tableColumns) headers() []string {
+	l := []string{"Property", "Type", "Required", "Constraints"}
+	if cols.def {
+		l = append(l, "Default")
+	}
+	if cols.examples {
+		l = append(l, "Examples")
+	}
+	return append(l, "Description")
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
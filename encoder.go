@@ -0,0 +1,176 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// canonicalKeywords lists the keywords in the order that they are
+// emitted by Encoder. Keywords that are not listed here (i.e. extra
+// fields) are emitted after them in sorted order, followed by
+// "definitions", which always comes last.
+var canonicalKeywords = []string{
+	"$schema",
+	"id",
+	"$ref",
+	"title",
+	"description",
+	"type",
+	"format",
+	"default",
+	"enum",
+	"multipleOf",
+	"minimum",
+	"exclusiveMinimum",
+	"maximum",
+	"exclusiveMaximum",
+	"minLength",
+	"maxLength",
+	"pattern",
+	"items",
+	"additionalItems",
+	"minItems",
+	"maxItems",
+	"uniqueItems",
+	"required",
+	"properties",
+	"patternProperties",
+	"additionalProperties",
+	"dependencies",
+	"minProperties",
+	"maxProperties",
+	"allOf",
+	"anyOf",
+	"oneOf",
+	"not",
+}
+
+// Encoder writes schemas in a canonical form, where keywords are
+// always emitted in the same, conventional order. This makes the
+// output stable, and easy to compare across revisions.
+//
+// Names within "definitions", "properties" and the like are emitted in
+// the order they were read when the schema was read with
+// WithPreserveKeyOrder, and in sorted order otherwise.
+type Encoder struct {
+	dst    io.Writer
+	prefix string
+	indent string
+}
+
+// NewEncoder creates a new Encoder that writes to `dst`
+func NewEncoder(dst io.Writer) *Encoder {
+	return &Encoder{dst: dst}
+}
+
+// SetIndent instructs the encoder to format each subsequent encoded
+// schema as if indented by json.Indent. Calling SetIndent("", "")
+// disables indentation.
+func (e *Encoder) SetIndent(prefix, indent string) {
+	e.prefix = prefix
+	e.indent = indent
+}
+
+// Encode writes the canonical JSON representation of `s`, followed
+// by a newline character
+func (e *Encoder) Encode(s *Schema) error {
+	buf, err := json.Marshal(canonicalValue(s))
+	if err != nil {
+		return errors.Wrap(err, "failed to serialize schema")
+	}
+
+	if e.prefix != "" || e.indent != "" {
+		var out bytes.Buffer
+		if err := json.Indent(&out, buf, e.prefix, e.indent); err != nil {
+			return errors.Wrap(err, "failed to indent schema")
+		}
+		buf = out.Bytes()
+	}
+	buf = append(buf, '\n')
+
+	if _, err := e.dst.Write(buf); err != nil {
+		return errors.Wrap(err, "failed to write schema")
+	}
+	return nil
+}
+
+// canonicalOrder returns the order in which the keywords in `m`
+// should be emitted
+func canonicalOrder(m map[string]interface{}) []string {
+	order := make([]string, 0, len(m))
+	for _, k := range canonicalKeywords {
+		if _, ok := m[k]; ok {
+			order = append(order, k)
+		}
+	}
+
+	var extras []string
+	for k := range m {
+		if !isKeyword(k) && k != "definitions" {
+			extras = append(extras, k)
+		}
+	}
+	sort.Strings(extras)
+	order = append(order, extras...)
+
+	if _, ok := m["definitions"]; ok {
+		order = append(order, "definitions")
+	}
+	return order
+}
+
+func isKeyword(name string) bool {
+	for _, k := range canonicalKeywords {
+		if k == name {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalValue converts `v` so that every schema contained within
+// it is serialized in canonical form
+func canonicalValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *Schema:
+		m := v.marshalMap()
+		for k, x := range m {
+			if isKeyword(k) || k == "definitions" {
+				m[k] = canonicalValue(x)
+			}
+		}
+		return orderedObject{values: m, order: canonicalOrder(m)}
+	case orderedObject:
+		values := make(map[string]interface{}, len(v.values))
+		for k, x := range v.values {
+			values[k] = canonicalValue(x)
+		}
+		return orderedObject{values: values, order: v.order}
+	case map[string]*Schema:
+		values := make(map[string]interface{}, len(v))
+		for k, x := range v {
+			values[k] = canonicalValue(x)
+		}
+		return values
+	case map[string]interface{}:
+		values := make(map[string]interface{}, len(v))
+		for k, x := range v {
+			values[k] = canonicalValue(x)
+		}
+		return values
+	case SchemaList:
+		return canonicalValue([]*Schema(v))
+	case []*Schema:
+		l := make([]interface{}, len(v))
+		for i, x := range v {
+			l[i] = canonicalValue(x)
+		}
+		return l
+	default:
+		return v
+	}
+}
@@ -0,0 +1,68 @@
+package schema_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncoder(t *testing.T) {
+	const src = `{
+  "definitions": {
+    "name": { "type": "string", "minLength": 1, "title": "Name" }
+  },
+  "x-extra": true,
+  "type": "object",
+  "properties": {
+    "name": { "$ref": "#/definitions/name" }
+  },
+  "id": "http://example.com/person.json",
+  "$schema": "http://json-schema.org/draft-04/schema#"
+}`
+
+	s, err := schema.Read(strings.NewReader(src), schema.WithRoundTrip(true))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	enc := schema.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if !assert.NoError(t, enc.Encode(s), "Encode should succeed") {
+		return
+	}
+
+	const expected = `{
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "id": "http://example.com/person.json",
+  "type": "object",
+  "properties": {
+    "name": {
+      "$ref": "#/definitions/name"
+    }
+  },
+  "x-extra": true,
+  "definitions": {
+    "name": {
+      "title": "Name",
+      "type": "string",
+      "minLength": 1
+    }
+  }
+}
+`
+	if !assert.Equal(t, expected, buf.String(), "output should be in canonical order") {
+		return
+	}
+
+	buf.Reset()
+	if !assert.NoError(t, schema.NewEncoder(&buf).Encode(s), "Encode should succeed") {
+		return
+	}
+	if !assert.NotContains(t, buf.String(), "\n  ", "output should not be indented by default") {
+		return
+	}
+}
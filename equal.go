@@ -0,0 +1,332 @@
+package schema
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"sort"
+	"strconv"
+)
+
+// Equal returns true if the two schemas are semantically equal.
+//
+// The comparison ignores differences that do not change the meaning
+// of the schemas: the order of the values in "required", "type" and
+// "enum", how numbers are written (1 and 1.0 are the same number),
+// and keywords that are set to their default values (e.g.
+// "exclusiveMinimum": false, or "minLength": 0). Regular expressions
+// are compared by their source. References are compared as they are
+// written, without resolving them.
+func Equal(a, b *Schema) bool {
+	if a == b {
+		return true
+	}
+	if a == nil || b == nil {
+		return false
+	}
+	return bytes.Equal(a.canonicalForm(), b.canonicalForm())
+}
+
+// Hash returns a hex encoded SHA-256 digest of the semantic content
+// of the schema. Schemas that are Equal have the same hash, which
+// makes it suitable for deduplicating schemas, or for detecting
+// changes to them.
+func (s *Schema) Hash() string {
+	sum := sha256.Sum256(s.canonicalForm())
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalForm returns the normalized JSON representation of the
+// schema that Equal and Hash work on
+func (s *Schema) canonicalForm() []byte {
+	n := normalizer{active: make(map[*Schema]int)}
+	// Only values that encoding/json handles are produced, and maps
+	// are encoded with sorted keys, so this can not fail
+	buf, _ := json.Marshal(n.schema(s))
+	return buf
+}
+
+// normalizer converts schemas into a normalized structure. In order
+// to distinguish them from strings, numbers are converted to strings
+// prefixed by "n:", and strings are prefixed by "s:"
+type normalizer struct {
+	active map[*Schema]int // schemas being normalized, and their depth
+}
+
+func (n *normalizer) schema(s *Schema) interface{} {
+	if s == nil {
+		return nil
+	}
+
+	// Schemas that contain themselves are replaced by a reference
+	// to the depth at which they first appear
+	if depth, ok := n.active[s]; ok {
+		return map[string]interface{}{"$cycle": depth}
+	}
+	n.active[s] = len(n.active)
+	defer delete(n.active, s)
+
+	m := make(map[string]interface{})
+	for k, v := range s.Extras {
+		m[k] = n.value(v)
+	}
+
+	n.placeString(m, "id", s.ID)
+	n.placeString(m, "title", s.Title)
+	n.placeString(m, "description", s.Description)
+	n.placeString(m, "$schema", s.SchemaRef)
+	n.placeString(m, "$ref", s.Reference)
+	n.placeString(m, "format", string(s.Format))
+	if s.Default != nil {
+		m["default"] = n.value(s.Default)
+	}
+	if len(s.Type) > 0 {
+		l := make([]string, len(s.Type))
+		for i, t := range s.Type {
+			l[i] = t.String()
+		}
+		m["type"] = sortedSet(l)
+	}
+	if len(s.Enum) > 0 {
+		m["enum"] = n.valueSet(s.Enum)
+	}
+
+	n.placeNumber(m, "multipleOf", s.MultipleOf)
+	n.placeNumber(m, "minimum", s.Minimum)
+	n.placeNumber(m, "maximum", s.Maximum)
+	n.placeBool(m, "exclusiveMinimum", s.ExclusiveMinimum)
+	n.placeBool(m, "exclusiveMaximum", s.ExclusiveMaximum)
+
+	n.placeInteger(m, "maxLength", s.MaxLength, -1)
+	n.placeInteger(m, "minLength", s.MinLength, 0)
+	if s.Pattern != nil {
+		m["pattern"] = s.Pattern.String()
+	}
+
+	if s.Items != nil {
+		if s.Items.TupleMode {
+			m["items"] = n.schemaList(s.Items.Schemas)
+		} else if len(s.Items.Schemas) > 0 {
+			n.placeSchema(m, "items", s.Items.Schemas[0])
+		}
+	}
+	if s.AdditionalItems != nil {
+		n.placeSchema(m, "additionalItems", s.AdditionalItems.Schema)
+	} else if canBeType(s, ArrayType) {
+		m["additionalItems"] = false
+	}
+	n.placeInteger(m, "maxItems", s.MaxItems, -1)
+	n.placeInteger(m, "minItems", s.MinItems, 0)
+	n.placeBool(m, "uniqueItems", s.UniqueItems)
+
+	n.placeInteger(m, "maxProperties", s.MaxProperties, -1)
+	n.placeInteger(m, "minProperties", s.MinProperties, 0)
+	if len(s.Required) > 0 {
+		m["required"] = sortedSet(s.Required)
+	}
+	n.placeSchemaMap(m, "definitions", s.Definitions)
+	n.placeSchemaMap(m, "properties", s.Properties)
+	if len(s.PatternProperties) > 0 {
+		pm := make(map[string]interface{}, len(s.PatternProperties))
+		for rx, v := range s.PatternProperties {
+			pm[rx.String()] = n.schema(v)
+		}
+		m["patternProperties"] = pm
+	}
+	if s.AdditionalProperties != nil {
+		n.placeSchema(m, "additionalProperties", s.AdditionalProperties.Schema)
+	} else if canBeType(s, ObjectType) {
+		m["additionalProperties"] = false
+	}
+	if len(s.Dependencies.Names) > 0 || len(s.Dependencies.Schemas) > 0 {
+		dm := make(map[string]interface{})
+		for k, v := range s.Dependencies.Names {
+			dm[k] = sortedSet(v)
+		}
+		for k, v := range s.Dependencies.Schemas {
+			dm[k] = n.schema(v)
+		}
+		m["dependencies"] = dm
+	}
+
+	if len(s.AllOf) > 0 {
+		m["allOf"] = n.schemaList(s.AllOf)
+	}
+	if len(s.AnyOf) > 0 {
+		m["anyOf"] = n.schemaList(s.AnyOf)
+	}
+	if len(s.OneOf) > 0 {
+		m["oneOf"] = n.schemaList(s.OneOf)
+	}
+	n.placeSchema(m, "not", s.Not)
+
+	return m
+}
+
+func (n *normalizer) placeString(m map[string]interface{}, name, s string) {
+	if s != "" {
+		m[name] = s
+	}
+}
+
+func (n *normalizer) placeNumber(m map[string]interface{}, name string, v Number) {
+	if !v.Initialized {
+		return
+	}
+	if v.Exact != "" {
+		if f, err := v.Exact.Float64(); err == nil && f == v.Val {
+			m[name] = n.value(v.Exact)
+			return
+		}
+	}
+	m[name] = n.value(v.Val)
+}
+
+// placeInteger places the integer unless it is equal to `def`, which
+// is the value that has the same meaning as the keyword being absent
+func (n *normalizer) placeInteger(m map[string]interface{}, name string, v Integer, def int) {
+	if v.Initialized && v.Val != def {
+		m[name] = v.Val
+	}
+}
+
+func (n *normalizer) placeBool(m map[string]interface{}, name string, v Bool) {
+	if v.Initialized && v.Val != v.Default {
+		m[name] = v.Val
+	}
+}
+
+func (n *normalizer) placeSchema(m map[string]interface{}, name string, s *Schema) {
+	if s == nil {
+		return
+	}
+	// A nil or empty schema accepts anything, which is the same as
+	// not specifying the keyword at all. For additionalItems and
+	// additionalProperties, false is represented by a nil pointer
+	// to AdditionalItems or AdditionalProperties
+	if v := n.schema(s); !isEmptyObject(v) {
+		m[name] = v
+	}
+}
+
+func (n *normalizer) placeSchemaMap(m map[string]interface{}, name string, schemas map[string]*Schema) {
+	if len(schemas) == 0 {
+		return
+	}
+	sm := make(map[string]interface{}, len(schemas))
+	for k, v := range schemas {
+		sm[k] = n.schema(v)
+	}
+	m[name] = sm
+}
+
+func (n *normalizer) schemaList(l SchemaList) []interface{} {
+	out := make([]interface{}, len(l))
+	for i, s := range l {
+		out[i] = n.schema(s)
+	}
+	return out
+}
+
+// value normalizes an arbitrary JSON value
+func (n *normalizer) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, bool:
+		return v
+	case string:
+		return "s:" + v
+	case float64:
+		return normalizeNumber(strconv.FormatFloat(v, 'g', -1, 64))
+	case float32:
+		return normalizeNumber(strconv.FormatFloat(float64(v), 'g', -1, 32))
+	case json.Number:
+		return normalizeNumber(v.String())
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = n.value(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = n.value(e)
+		}
+		return l
+	}
+
+	if r, ok := toRat(v); ok {
+		return "n:" + r.RatString()
+	}
+
+	// Other Go values are normalized through their JSON representation
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return "?:" + err.Error()
+	}
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	var decoded interface{}
+	if err := dec.Decode(&decoded); err != nil {
+		return "?:" + err.Error()
+	}
+	return n.value(decoded)
+}
+
+// valueSet normalizes a list of values whose order does not matter
+func (n *normalizer) valueSet(l []interface{}) []interface{} {
+	type entry struct {
+		key   string
+		value interface{}
+	}
+
+	entries := make([]entry, 0, len(l))
+	seen := make(map[string]struct{}, len(l))
+	for _, v := range l {
+		nv := n.value(v)
+		buf, _ := json.Marshal(nv)
+		key := string(buf)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		entries = append(entries, entry{key: key, value: nv})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+
+	out := make([]interface{}, len(entries))
+	for i, e := range entries {
+		out[i] = e.value
+	}
+	return out
+}
+
+func normalizeNumber(s string) string {
+	if r, ok := new(big.Rat).SetString(s); ok {
+		return "n:" + r.RatString()
+	}
+	return "n:" + s
+}
+
+func sortedSet(l []string) []string {
+	seen := make(map[string]struct{}, len(l))
+	out := make([]string, 0, len(l))
+	for _, s := range l {
+		if _, ok := seen[s]; ok {
+			continue
+		}
+		seen[s] = struct{}{}
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func isEmptyObject(v interface{}) bool {
+	m, ok := v.(map[string]interface{})
+	return ok && len(m) == 0
+}
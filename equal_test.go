@@ -0,0 +1,137 @@
+package schema_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEqual(t *testing.T) {
+	read := func(src string) *schema.Schema {
+		s, err := schema.Read(strings.NewReader(src))
+		if err != nil {
+			t.Fatalf("schema.Read should succeed: %s", err)
+		}
+		return s
+	}
+
+	base := read(`{
+  "type": ["string", "null"],
+  "enum": ["a", "b", 1],
+  "required": ["x", "y"],
+  "minimum": 1,
+  "pattern": "^[a-z]+$",
+  "properties": {"x": {"type": "integer"}}
+}`)
+
+	equal := map[string]string{
+		"reordered": `{
+  "properties": {"x": {"type": "integer"}},
+  "pattern": "^[a-z]+$",
+  "minimum": 1.0,
+  "required": ["y", "x"],
+  "enum": [1, "b", "a"],
+  "type": ["null", "string"]
+}`,
+		"defaults": `{
+  "type": ["string", "null"],
+  "enum": ["a", "b", 1],
+  "required": ["x", "y"],
+  "minimum": 1,
+  "exclusiveMinimum": false,
+  "minLength": 0,
+  "pattern": "^[a-z]+$",
+  "properties": {"x": {"type": "integer", "uniqueItems": false}},
+  "additionalProperties": true,
+  "additionalItems": {}
+}`,
+	}
+	for name, src := range equal {
+		s := read(src)
+		if !assert.True(t, schema.Equal(base, s), "%s: schemas should be equal", name) {
+			return
+		}
+		if !assert.Equal(t, base.Hash(), s.Hash(), "%s: hashes should be equal", name) {
+			return
+		}
+	}
+
+	different := map[string]string{
+		"enum": `{
+  "type": ["string", "null"],
+  "enum": ["a", "b", "1"],
+  "required": ["x", "y"],
+  "minimum": 1,
+  "pattern": "^[a-z]+$",
+  "properties": {"x": {"type": "integer"}}
+}`,
+		"exclusiveMinimum": `{
+  "type": ["string", "null"],
+  "enum": ["a", "b", 1],
+  "required": ["x", "y"],
+  "minimum": 1,
+  "exclusiveMinimum": true,
+  "pattern": "^[a-z]+$",
+  "properties": {"x": {"type": "integer"}}
+}`,
+		"subschema": `{
+  "type": ["string", "null"],
+  "enum": ["a", "b", 1],
+  "required": ["x", "y"],
+  "minimum": 1,
+  "pattern": "^[a-z]+$",
+  "properties": {"x": {"type": "number"}}
+}`,
+	}
+	for name, src := range different {
+		s := read(src)
+		if !assert.False(t, schema.Equal(base, s), "%s: schemas should differ", name) {
+			return
+		}
+		if !assert.NotEqual(t, base.Hash(), s.Hash(), "%s: hashes should differ", name) {
+			return
+		}
+	}
+
+	object := read(`{"type": "object"}`)
+	if !assert.False(t, schema.Equal(object, read(`{"type": "object", "additionalProperties": false}`)), "additionalProperties: false should differ from its absence") {
+		return
+	}
+	if !assert.True(t, schema.Equal(object, read(`{"type": "object", "additionalProperties": {}}`)), "additionalProperties: {} should be the same as its absence") {
+		return
+	}
+	// Schemas that are built by hand use nil for false
+	manual := schema.New()
+	manual.Type = schema.PrimitiveTypes{schema.ObjectType}
+	if !assert.False(t, schema.Equal(object, manual), "nil additionalProperties should mean false") {
+		return
+	}
+	manual.AdditionalProperties = &schema.AdditionalProperties{}
+	if !assert.True(t, schema.Equal(object, manual), "empty additionalProperties should mean true") {
+		return
+	}
+
+	if !assert.True(t, schema.Equal(base, base.Clone()), "clone should be equal") {
+		return
+	}
+	if !assert.False(t, schema.Equal(base, nil), "nil should not be equal") {
+		return
+	}
+}
+
+func TestEqualRecursive(t *testing.T) {
+	a := schema.New()
+	a.Properties = map[string]*schema.Schema{"self": a}
+
+	b := schema.New()
+	b.Properties = map[string]*schema.Schema{"self": b}
+
+	if !assert.True(t, schema.Equal(a, b), "recursive schemas should be equal") {
+		return
+	}
+	if !assert.False(t, schema.Equal(a, schema.New()), "recursive schema should differ from empty schema") {
+		return
+	}
+}
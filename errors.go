@@ -1,7 +1,57 @@
 package schema
 
-import "github.com/pkg/errors"
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
 
 func errInvalidType(s string, v interface{}) error {
 	return errors.Errorf("invalid type: expected %s, got %T", s, v)
 }
+
+// Error returns the string representation of the error
+func (e *CircularReferenceError) Error() string {
+	return "circular reference detected: " + strings.Join(e.Path, " -> ")
+}
+
+// Error returns the string representation of the error
+func (e *ReferenceError) Error() string {
+	msg := "invalid reference " + strconv.Quote(e.Reference) + " at " + e.Pointer
+	if e.Position.IsValid() {
+		msg += " (" + e.Position.String() + ")"
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+// Error returns the string representation of the error
+func (l ReferenceErrors) Error() string {
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return strconv.Itoa(len(l)) + " invalid reference(s) found: " + strings.Join(msgs, ", ")
+}
+
+// Error returns the string representation of the error
+func (e *ParseError) Error() string {
+	if e.Position.IsValid() {
+		return e.Position.String() + " (" + e.Pointer + "): " + e.Err.Error()
+	}
+	return e.Pointer + ": " + e.Err.Error()
+}
+
+// Cause returns the underlying error
+func (e *ParseError) Cause() error {
+	return e.Err
+}
+
+// Error returns the string representation of the error
+func (l ParseErrors) Error() string {
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return strconv.Itoa(len(l)) + " error(s) found while parsing schema: " + strings.Join(msgs, ", ")
+}
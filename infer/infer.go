@@ -0,0 +1,299 @@
+// Package infer builds JSON schemas from sample JSON documents.
+package infer
+
+import (
+	"encoding/json"
+	"math"
+	"net/mail"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/lestrrat-go/jsschema/internal/option"
+	"github.com/pkg/errors"
+)
+
+// Option is an option that can be passed to New and Infer
+type Option interface {
+	option.Interface
+}
+
+const (
+	optkeyEnumThreshold = "enum-threshold"
+	optkeyFormats       = "formats"
+)
+
+// WithEnumThreshold specifies the maximum number of distinct values that
+// a string may take for it to be described using "enum". Only strings
+// that have been observed more times than they have distinct values are
+// considered, so that values that are seen only once do not turn into
+// enums. The default is 0, which disables enums.
+func WithEnumThreshold(n int) Option {
+	return option.New(optkeyEnumThreshold, n)
+}
+
+// WithFormats specifies if the formats of strings ("date-time",
+// "email" and "uuid") should be detected. A format is only assigned if
+// every string observed at that location matches it. Enabled by default.
+func WithFormats(b bool) Option {
+	return option.New(optkeyFormats, b)
+}
+
+// Inferrer accumulates sample documents, and builds a schema that
+// describes all of them
+type Inferrer struct {
+	enumThreshold int
+	formats       bool
+	root          *node
+}
+
+// New creates a new Inferrer
+func New(options ...Option) *Inferrer {
+	i := &Inferrer{
+		formats: true,
+		root:    newNode(),
+	}
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyEnumThreshold:
+			i.enumThreshold = o.Value().(int)
+		case optkeyFormats:
+			i.formats = o.Value().(bool)
+		}
+	}
+	return i
+}
+
+// Infer builds a schema that describes all of the given samples, which
+// must be values as decoded by encoding/json into an interface{}
+func Infer(samples []interface{}, options ...Option) (*schema.Schema, error) {
+	i := New(options...)
+	for _, v := range samples {
+		if err := i.Add(v); err != nil {
+			return nil, err
+		}
+	}
+	return i.Schema()
+}
+
+// Add adds a sample document, as decoded by encoding/json into an
+// interface{}. Numbers may be either float64 or json.Number.
+func (i *Inferrer) Add(v interface{}) error {
+	return i.root.add(v, i.enumThreshold)
+}
+
+// Schema returns a schema describing all of the samples that have been
+// added so far:
+//
+//   - "type" lists every type that was observed (integers are merged
+//     into numbers if both were observed)
+//   - objects list every property that was observed, and require those
+//     that were present in every sample
+//   - arrays describe all of their elements with a single "items" schema
+//   - strings get a "format" or an "enum", depending on the options
+func (i *Inferrer) Schema() (*schema.Schema, error) {
+	m := i.root.schema(i)
+	m["$schema"] = schema.SchemaURL + "#"
+
+	s := schema.New()
+	if err := s.Extract(m); err != nil {
+		return nil, errors.Wrap(err, "failed to build schema")
+	}
+	return s, nil
+}
+
+// node holds what has been observed at one location within the samples
+type node struct {
+	types   map[schema.PrimitiveType]struct{}
+	objects int              // number of objects observed
+	seen    int              // number of objects that had this property
+	props   map[string]*node // properties of the objects
+	items   *node            // elements of the arrays
+
+	strs      int            // number of strings observed
+	values    map[string]int // distinct strings, while they are few enough
+	formatHit map[schema.Format]int
+}
+
+func newNode() *node {
+	return &node{
+		types:     make(map[schema.PrimitiveType]struct{}),
+		props:     make(map[string]*node),
+		values:    make(map[string]int),
+		formatHit: make(map[schema.Format]int),
+	}
+}
+
+func (n *node) add(v interface{}, enumThreshold int) error {
+	switch v := v.(type) {
+	case nil:
+		n.types[schema.NullType] = struct{}{}
+	case bool:
+		n.types[schema.BooleanType] = struct{}{}
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			n.types[schema.IntegerType] = struct{}{}
+		} else {
+			n.types[schema.NumberType] = struct{}{}
+		}
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			n.types[schema.IntegerType] = struct{}{}
+			break
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return errors.Wrapf(err, "invalid number %s", v)
+		}
+		if f == math.Trunc(f) {
+			n.types[schema.IntegerType] = struct{}{}
+		} else {
+			n.types[schema.NumberType] = struct{}{}
+		}
+	case string:
+		n.types[schema.StringType] = struct{}{}
+		n.strs++
+		for _, f := range detectFormats(v) {
+			n.formatHit[f]++
+		}
+		// Only keep track of the distinct values while they may still
+		// become an enum
+		if n.values != nil {
+			n.values[v]++
+			if len(n.values) > enumThreshold {
+				n.values = nil
+			}
+		}
+	case []interface{}:
+		n.types[schema.ArrayType] = struct{}{}
+		for _, e := range v {
+			if n.items == nil {
+				n.items = newNode()
+			}
+			if err := n.items.add(e, enumThreshold); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		n.types[schema.ObjectType] = struct{}{}
+		n.objects++
+		for k, e := range v {
+			p, ok := n.props[k]
+			if !ok {
+				p = newNode()
+				n.props[k] = p
+			}
+			if err := p.add(e, enumThreshold); err != nil {
+				return err
+			}
+			p.seen++
+		}
+	default:
+		return errors.Errorf("unsupported value of type %T", v)
+	}
+	return nil
+}
+
+var (
+	uuidRx  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	emailRx = regexp.MustCompile(`^[^@\s]+@[^@\s]+$`)
+)
+
+// detectFormats returns the formats that `v` conforms to
+func detectFormats(v string) []schema.Format {
+	var l []schema.Format
+	if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		l = append(l, schema.FormatDateTime)
+	}
+	if emailRx.MatchString(v) {
+		if addr, err := mail.ParseAddress(v); err == nil && addr.Address == v {
+			l = append(l, schema.FormatEmail)
+		}
+	}
+	if uuidRx.MatchString(v) {
+		l = append(l, schema.Format("uuid"))
+	}
+	return l
+}
+
+// schema returns the JSON representation of the schema for this node
+func (n *node) schema(i *Inferrer) map[string]interface{} {
+	m := make(map[string]interface{})
+
+	var types []string
+	for t := range n.types {
+		if t == schema.IntegerType {
+			if _, ok := n.types[schema.NumberType]; ok {
+				continue
+			}
+		}
+		types = append(types, t.String())
+	}
+	sort.Strings(types)
+	switch len(types) {
+	case 0:
+	case 1:
+		m["type"] = types[0]
+	default:
+		l := make([]interface{}, len(types))
+		for i, t := range types {
+			l[i] = t
+		}
+		m["type"] = l
+	}
+
+	if n.strs > 0 {
+		if i.formats {
+			for _, f := range []schema.Format{schema.FormatDateTime, schema.Format("uuid"), schema.FormatEmail} {
+				if n.formatHit[f] == n.strs {
+					m["format"] = string(f)
+					break
+				}
+			}
+		}
+
+		// Values that are seen only once are not considered to be
+		// part of an enumeration
+		if i.enumThreshold > 0 && n.values != nil && n.strs > len(n.values) && len(types) == 1 {
+			values := make([]string, 0, len(n.values))
+			for v := range n.values {
+				values = append(values, v)
+			}
+			sort.Strings(values)
+			l := make([]interface{}, len(values))
+			for i, v := range values {
+				l[i] = v
+			}
+			m["enum"] = l
+		}
+	}
+
+	if n.items != nil {
+		m["items"] = n.items.schema(i)
+	}
+
+	if n.objects > 0 {
+		props := make(map[string]interface{}, len(n.props))
+		var required []interface{}
+		names := make([]string, 0, len(n.props))
+		for k := range n.props {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			p := n.props[k]
+			props[k] = p.schema(i)
+			if p.seen == n.objects {
+				required = append(required, k)
+			}
+		}
+		if len(props) > 0 {
+			m["properties"] = props
+		}
+		if len(required) > 0 {
+			m["required"] = required
+		}
+	}
+	return m
+}
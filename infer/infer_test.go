@@ -0,0 +1,94 @@
+package infer_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lestrrat-go/jsschema/infer"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeSamples(t *testing.T, src ...string) []interface{} {
+	samples := make([]interface{}, len(src))
+	for i, s := range src {
+		if err := json.Unmarshal([]byte(s), &samples[i]); err != nil {
+			t.Fatalf("failed to decode sample: %s", err)
+		}
+	}
+	return samples
+}
+
+func TestInfer(t *testing.T) {
+	samples := decodeSamples(t,
+		`{"id": "0b9a3f0e-7a0e-4c3b-9d5e-2f1c0a7b8e61", "name": "alice", "age": 30, "email": "alice@example.com", "created": "2016-09-03T10:00:00Z", "status": "active", "tags": ["a", "b"]}`,
+		`{"id": "5c3d1a2b-0f4e-4d6a-8b7c-9e0f1a2b3c4d", "name": "bob", "age": 41.5, "created": "2017-01-01T00:00:00+09:00", "status": "inactive", "tags": [], "manager": null}`,
+		`{"id": "9f8e7d6c-5b4a-4321-8fed-cba987654321", "name": "carol", "age": null, "email": "carol@example.com", "created": "2018-05-05T12:30:00Z", "status": "active", "manager": {"name": "alice"}}`,
+	)
+
+	s, err := infer.Infer(samples, infer.WithEnumThreshold(3))
+	if !assert.NoError(t, err, "infer.Infer should succeed") {
+		return
+	}
+
+	buf, err := json.Marshal(s)
+	if !assert.NoError(t, err, "json.Marshal should succeed") {
+		return
+	}
+
+	expected := `{
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "type": "object",
+  "required": ["age", "created", "id", "name", "status"],
+  "properties": {
+    "id": {"type": "string", "format": "uuid"},
+    "name": {"type": "string"},
+    "age": {"type": ["null", "number"]},
+    "email": {"type": "string", "format": "email"},
+    "created": {"type": "string", "format": "date-time"},
+    "status": {"type": "string", "enum": ["active", "inactive"]},
+    "tags": {"type": "array", "items": {"type": "string"}},
+    "manager": {
+      "type": ["null", "object"],
+      "required": ["name"],
+      "properties": {"name": {"type": "string"}}
+    }
+  }
+}`
+	if !assert.JSONEq(t, expected, string(buf), "schema should match") {
+		return
+	}
+
+	if !assert.NoError(t, s.Validate(), "tree should be consistent") {
+		return
+	}
+}
+
+func TestInferOptions(t *testing.T) {
+	samples := decodeSamples(t, `{"when": "2016-09-03T10:00:00Z", "n": 1}`, `{"when": "2016-09-03T10:00:00Z", "n": 2}`)
+
+	i := infer.New(infer.WithFormats(false))
+	for _, v := range samples {
+		if !assert.NoError(t, i.Add(v), "Add should succeed") {
+			return
+		}
+	}
+	s, err := i.Schema()
+	if !assert.NoError(t, err, "Schema should succeed") {
+		return
+	}
+
+	when := s.Properties["when"]
+	if !assert.Empty(t, when.Format, "formats should not be detected") {
+		return
+	}
+	if !assert.Empty(t, when.Enum, "enums should be disabled by default") {
+		return
+	}
+	if !assert.Equal(t, "integer", s.Properties["n"].Type[0].String(), "integers should be detected") {
+		return
+	}
+
+	if !assert.Error(t, i.Add(struct{}{}), "unsupported values should be rejected") {
+		return
+	}
+}
@@ -0,0 +1,631 @@
+package instance
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/lestrrat-go/jsschema/validator"
+	"github.com/pkg/errors"
+)
+
+// Fuzzer generates random instances of a schema. Instances are derived
+// from a seed, so that the same seed always produces the same
+// instances, which makes Fuzzer suitable for fuzz targets:
+//
+//	func FuzzHandler(f *testing.F) {
+//	  f.Add(int64(0))
+//	  f.Fuzz(func(t *testing.T, seed int64) {
+//	    fz := instance.NewFuzzer(s, seed)
+//	    v, err := fz.Valid()
+//	    if err != nil {
+//	      t.Skip(err)
+//	    }
+//	    // Exercise the code under test with `v`...
+//	  })
+//	}
+type Fuzzer struct {
+	schema    *schema.Schema
+	g         *generator
+	validator *validator.Validator
+}
+
+// Invalid is an instance that fails validation because of a single
+// keyword of the schema
+type Invalid struct {
+	// Value is the instance
+	Value interface{}
+	// Keyword is the keyword that the instance violates, e.g. "minimum"
+	Keyword string
+	// Pointer locates the value that violates Keyword within the instance
+	Pointer schema.Pointer
+}
+
+// NewFuzzer creates a new Fuzzer for the schema `s`
+func NewFuzzer(s *schema.Schema, seed int64, options ...Option) *Fuzzer {
+	g := newGenerator(options)
+	g.rnd = rand.New(rand.NewSource(seed))
+	return &Fuzzer{
+		schema:    s,
+		g:         g,
+		validator: validator.New(s),
+	}
+}
+
+// Valid returns a random instance that validates against the schema.
+// Instances are checked with the validator package, and those that
+// fail validation are replaced by new ones. An error is returned if
+// no valid instance can be found.
+func (f *Fuzzer) Valid() (interface{}, error) {
+	return f.valid(nil)
+}
+
+// valid generates a valid instance, recording the locations that may
+// be mutated into `sites` if it is non-nil
+func (f *Fuzzer) valid(sites *[]site) (interface{}, error) {
+	var err error
+	for attempt := 0; attempt <= maxAttempts; attempt++ {
+		if sites != nil {
+			*sites = (*sites)[:0]
+		}
+		f.g.sites = sites
+		var v interface{}
+		v, err = f.g.generate([]*schema.Schema{f.schema}, schema.Pointer("#"), 0, 0)
+		f.g.sites = nil
+		if err == nil {
+			if err = f.validator.Validate(v); err == nil {
+				return v, nil
+			}
+		}
+	}
+	return nil, errors.Wrap(err, "failed to generate valid instance")
+}
+
+// Invalid returns a random instance that is almost valid: it violates
+// exactly one keyword of the schema, which is reported along with the
+// instance. The keywords that may be violated are "type", "enum",
+// "minimum", "maximum", "multipleOf", "minLength", "maxLength",
+// "pattern", "format", "minItems", "maxItems", "uniqueItems",
+// "additionalItems", "required", "minProperties", "maxProperties",
+// "additionalProperties" and "dependencies". Values picked for
+// "oneOf" and "anyOf" are never altered, as they could end up
+// matching another alternative. Instances are checked with the
+// validator package, so keywords that it does not enforce (e.g. some
+// values of "format") are not reported as violated. Instances must
+// also validate once the reported keyword is removed from the schema,
+// so that they do not violate another keyword (e.g. of "not") as well.
+func (f *Fuzzer) Invalid() (*Invalid, error) {
+	var sites []site
+	v, err := f.valid(&sites)
+	if err != nil {
+		return nil, err
+	}
+
+	rnd := f.g.rnd
+	rnd.Shuffle(len(sites), func(i, j int) {
+		sites[i], sites[j] = sites[j], sites[i]
+	})
+	for _, st := range sites {
+		mutations := st.mutations()
+		rnd.Shuffle(len(mutations), func(i, j int) {
+			mutations[i], mutations[j] = mutations[j], mutations[i]
+		})
+		for _, m := range mutations {
+			mv, ok := m.apply(f.g)
+			if !ok {
+				continue
+			}
+			root, err := replace(v, st.path, mv)
+			if err != nil {
+				return nil, err
+			}
+			if f.validator.Validate(root) == nil || !f.violatesOnly(st, m.keyword, root) {
+				// The validator accepts the value, or rejects it for
+				// another reason, so restore the original and try
+				// something else
+				if _, err := replace(v, st.path, st.value); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return &Invalid{
+				Value:   root,
+				Keyword: m.keyword,
+				Pointer: st.path,
+			}, nil
+		}
+	}
+	return nil, errors.New("no keyword of the schema can be violated")
+}
+
+// violatesOnly returns true if the instance `v` validates once `keyword`
+// is removed from the schemas of the site `st`
+func (f *Fuzzer) violatesOnly(st site, keyword string, v interface{}) bool {
+	root := f.schema.Root()
+	ptrs, ok := st.pointers(root)
+	if !ok {
+		return false
+	}
+
+	relaxed := root.Clone()
+	for _, ptr := range ptrs {
+		s, err := relaxed.Lookup(ptr)
+		if err != nil {
+			return false
+		}
+		removeKeyword(s, keyword)
+	}
+	s, err := relaxed.Lookup(f.schema.Pointer())
+	if err != nil {
+		return false
+	}
+	return validator.New(s).Validate(v) == nil
+}
+
+// pointers returns the pointers of the schemas that the constraints of
+// the site come from, including the schemas of "allOf", references and
+// schema dependencies. It returns false if some of them are not part
+// of `root`
+func (st site) pointers(root *schema.Schema) ([]string, bool) {
+	var ptrs []string
+	seen := make(map[*schema.Schema]bool)
+	var walk func(*schema.Schema) bool
+	walk = func(s *schema.Schema) bool {
+		s, err := s.Resolve(nil)
+		if err != nil || s.Root() != root {
+			return false
+		}
+		if seen[s] {
+			return true
+		}
+		seen[s] = true
+		ptrs = append(ptrs, s.Pointer())
+
+		for _, sub := range s.AllOf {
+			if !walk(sub) {
+				return false
+			}
+		}
+		for _, dep := range s.Dependencies.Schemas {
+			if !walk(dep) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, s := range st.c.schemas {
+		if !walk(s) {
+			return nil, false
+		}
+	}
+	return ptrs, true
+}
+
+// removeKeyword removes `keyword`, as reported by Invalid, from `s`
+func removeKeyword(s *schema.Schema, keyword string) {
+	switch keyword {
+	case "type":
+		s.Type = nil
+	case "enum":
+		s.Enum = nil
+	case "minimum":
+		s.Minimum, s.ExclusiveMinimum = schema.Number{}, schema.Bool{}
+	case "maximum":
+		s.Maximum, s.ExclusiveMaximum = schema.Number{}, schema.Bool{}
+	case "multipleOf":
+		s.MultipleOf = schema.Number{}
+	case "minLength":
+		s.MinLength = schema.Integer{}
+	case "maxLength":
+		s.MaxLength = schema.Integer{}
+	case "pattern":
+		s.Pattern = nil
+	case "format":
+		s.Format = ""
+	case "minItems":
+		s.MinItems = schema.Integer{}
+	case "maxItems":
+		s.MaxItems = schema.Integer{}
+	case "uniqueItems":
+		s.UniqueItems = schema.Bool{}
+	case "additionalItems":
+		s.AdditionalItems = &schema.AdditionalItems{}
+	case "required":
+		s.Required = nil
+	case "minProperties":
+		s.MinProperties = schema.Integer{}
+	case "maxProperties":
+		s.MaxProperties = schema.Integer{}
+	case "additionalProperties":
+		s.AdditionalProperties = &schema.AdditionalProperties{}
+	case "dependencies":
+		s.Dependencies = schema.DependencyMap{}
+	}
+}
+
+// site is a location within a generated instance, along with the
+// constraints that the value at that location satisfies
+type site struct {
+	path  schema.Pointer
+	c     *constraints
+	depth int
+	value interface{}
+}
+
+// mutation replaces the value of a site by one that violates `keyword`
+type mutation struct {
+	keyword string
+	apply   func(*generator) (interface{}, bool)
+}
+
+// invalidFormats holds values that are invalid for the formats that
+// validators are required to check
+var invalidFormats = map[schema.Format]string{
+	schema.FormatDateTime: "not a date-time",
+	schema.FormatEmail:    "not an email",
+	schema.FormatHostname: "not a hostname",
+	schema.FormatIPv4:     "not an ipv4",
+	schema.FormatIPv6:     "not an ipv6",
+}
+
+func (st site) mutations() []mutation {
+	c := st.c
+	var l []mutation
+
+	if c.types != nil && c.enum == nil {
+		l = append(l, mutation{keyword: "type", apply: st.wrongType})
+	}
+	if c.enum != nil {
+		l = append(l, st.relax("enum", func(rc *constraints) {
+			rc.enum = nil
+		}, func(v interface{}) bool {
+			for _, e := range c.enum {
+				if encodeKey(e) == encodeKey(v) {
+					return false
+				}
+			}
+			return true
+		}))
+		// Any other change would also violate "enum"
+		return l
+	}
+
+	switch v := st.value.(type) {
+	case float64:
+		if c.minimum != nil {
+			min, excl := *c.minimum, c.exclusiveMinimum
+			l = append(l, st.relax("minimum", func(rc *constraints) {
+				rc.minimum = nil
+				rc.maximum, rc.exclusiveMaximum = &min, !excl
+			}, func(v interface{}) bool {
+				f := v.(float64)
+				return f < min || f == min && excl
+			}))
+		}
+		if c.maximum != nil {
+			max, excl := *c.maximum, c.exclusiveMaximum
+			l = append(l, st.relax("maximum", func(rc *constraints) {
+				rc.maximum = nil
+				rc.minimum, rc.exclusiveMinimum = &max, !excl
+			}, func(v interface{}) bool {
+				f := v.(float64)
+				return f > max || f == max && excl
+			}))
+		}
+		if len(c.multipleOf) == 1 {
+			m := c.multipleOf[0]
+			l = append(l, st.relax("multipleOf", func(rc *constraints) {
+				rc.multipleOf = nil
+			}, func(v interface{}) bool {
+				q := v.(float64) / m
+				return q != math.Trunc(q)
+			}))
+		}
+	case string:
+		if c.minLength > 0 {
+			min := c.minLength
+			l = append(l, st.relax("minLength", func(rc *constraints) {
+				rc.minLength, rc.maxLength = 0, min-1
+			}, func(v interface{}) bool {
+				return utf8.RuneCountInString(v.(string)) < min
+			}))
+		}
+		if c.maxLength >= 0 {
+			max := c.maxLength
+			l = append(l, st.relax("maxLength", func(rc *constraints) {
+				rc.minLength, rc.maxLength = max+1, -1
+			}, func(v interface{}) bool {
+				return utf8.RuneCountInString(v.(string)) > max
+			}))
+		}
+		if len(c.patterns) == 1 {
+			rx := c.patterns[0]
+			l = append(l, st.relax("pattern", func(rc *constraints) {
+				rc.patterns = nil
+			}, func(v interface{}) bool {
+				return !rx.MatchString(v.(string))
+			}))
+		}
+		if bad, ok := invalidFormats[c.format]; ok && c.acceptsString(bad) {
+			l = append(l, mutation{keyword: "format", apply: func(*generator) (interface{}, bool) {
+				return bad, true
+			}})
+		}
+	case []interface{}:
+		if c.minItems > 0 {
+			min := c.minItems
+			l = append(l, st.relax("minItems", func(rc *constraints) {
+				rc.minItems, rc.maxItems = 0, min-1
+			}, func(v interface{}) bool {
+				return len(v.([]interface{})) < min
+			}))
+		}
+		if c.maxItems >= 0 {
+			max := c.maxItems
+			l = append(l, st.relax("maxItems", func(rc *constraints) {
+				rc.minItems, rc.maxItems = max+1, -1
+			}, func(v interface{}) bool {
+				return len(v.([]interface{})) > max
+			}))
+		}
+		room := c.maxItems < 0 || len(v) < c.maxItems
+		if c.uniqueItems && !c.tupleMode && len(v) > 0 && room {
+			l = append(l, mutation{keyword: "uniqueItems", apply: func(*generator) (interface{}, bool) {
+				return append(append([]interface{}(nil), v...), copyValue(v[0])), true
+			}})
+		}
+		if c.tupleMode && !c.additionalItems && len(v) == len(c.tuple) && room && !(c.uniqueItems && containsNull(v)) {
+			l = append(l, mutation{keyword: "additionalItems", apply: func(*generator) (interface{}, bool) {
+				return append(append([]interface{}(nil), v...), nil), true
+			}})
+		}
+	case map[string]interface{}:
+		l = append(l, st.objectMutations(v)...)
+	}
+	return l
+}
+
+func (st site) objectMutations(m map[string]interface{}) []mutation {
+	c := st.c
+	var l []mutation
+
+	required := make(map[string]bool)
+	for _, name := range c.required {
+		required[name] = true
+	}
+	// dependents returns the number of properties that depend on `name`
+	dependents := func(name string) int {
+		n := 0
+		for k, deps := range c.depNames {
+			if _, ok := m[k]; !ok || k == name {
+				continue
+			}
+			for _, dep := range deps {
+				if dep == name {
+					n++
+				}
+			}
+		}
+		return n
+	}
+	// Removing a property that a dependency schema may require could
+	// violate "dependencies" as well
+	removable := len(m) > c.minProperties && len(c.depSchemas) == 0
+
+	if removable {
+		for _, name := range sortedSet(c.required) {
+			if _, ok := m[name]; !ok || dependents(name) > 0 {
+				continue
+			}
+			name := name
+			l = append(l, mutation{keyword: "required", apply: func(*generator) (interface{}, bool) {
+				return without(m, name), true
+			}})
+		}
+
+		for _, k := range sortedNames(c.depNames) {
+			if _, ok := m[k]; !ok {
+				continue
+			}
+			for _, dep := range c.depNames[k] {
+				if _, ok := m[dep]; !ok || required[dep] || dependents(dep) != 1 {
+					continue
+				}
+				dep := dep
+				l = append(l, mutation{keyword: "dependencies", apply: func(*generator) (interface{}, bool) {
+					return without(m, dep), true
+				}})
+			}
+		}
+	}
+
+	if !c.additionalProperties && (c.maxProperties < 0 || len(m) < c.maxProperties) {
+		l = append(l, mutation{keyword: "additionalProperties", apply: func(*generator) (interface{}, bool) {
+			for i := 0; i < 100; i++ {
+				name := "unexpected"
+				if i > 0 {
+					name += strconv.Itoa(i)
+				}
+				// The name must not be covered by "properties" or
+				// "patternProperties"
+				if _, ok := m[name]; ok || len(c.propertySchemas(name)) > 0 {
+					continue
+				}
+				mv := without(m, "")
+				mv[name] = nil
+				return mv, true
+			}
+			return nil, false
+		}})
+	}
+
+	if c.minProperties > 0 {
+		min := c.minProperties
+		l = append(l, st.relax("minProperties", func(rc *constraints) {
+			rc.minProperties, rc.maxProperties = 0, min-1
+		}, func(v interface{}) bool {
+			return len(v.(map[string]interface{})) < min
+		}))
+	}
+	if c.maxProperties >= 0 {
+		max := c.maxProperties
+		l = append(l, st.relax("maxProperties", func(rc *constraints) {
+			rc.minProperties, rc.maxProperties = max+1, -1
+		}, func(v interface{}) bool {
+			return len(v.(map[string]interface{})) > max
+		}))
+	}
+	return l
+}
+
+// relax returns a mutation that generates values from the constraints
+// of the site, as changed by `change`, until one of them violates the
+// keyword
+func (st site) relax(keyword string, change func(*constraints), violates func(interface{}) bool) mutation {
+	return mutation{keyword: keyword, apply: func(g *generator) (interface{}, bool) {
+		rc := *st.c
+		rc.hasDefault = false
+		// Values are meant to be invalid
+		rc.schemas = nil
+		if keyword != "enum" {
+			// The value must remain of the same type for the keyword
+			// to apply to it
+			t := typeOf(st.value)
+			if t == schema.IntegerType && rc.allows(schema.NumberType) {
+				t = schema.NumberType
+			}
+			rc.types = []schema.PrimitiveType{t}
+		}
+		change(&rc)
+
+		for i := 0; i < 16; i++ {
+			v, err := g.value(&rc, st.path, st.depth, 0, 0)
+			if err == nil && violates(v) {
+				return v, true
+			}
+		}
+		return nil, false
+	}}
+}
+
+// wrongType returns a value of a type that the site does not allow
+func (st site) wrongType(g *generator) (interface{}, bool) {
+	samples := map[schema.PrimitiveType]interface{}{
+		schema.NullType:    nil,
+		schema.BooleanType: true,
+		schema.IntegerType: 1.0,
+		schema.NumberType:  0.5,
+		schema.StringType:  "string",
+		schema.ArrayType:   []interface{}{},
+		schema.ObjectType:  map[string]interface{}{},
+	}
+
+	var candidates []schema.PrimitiveType
+	for _, t := range allTypes {
+		if !st.c.allows(t) {
+			candidates = append(candidates, t)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, false
+	}
+	return samples[candidates[g.rnd.Intn(len(candidates))]], true
+}
+
+// acceptsString returns true if `s` satisfies the string constraints,
+// other than "format"
+func (c *constraints) acceptsString(s string) bool {
+	n := utf8.RuneCountInString(s)
+	if n < c.minLength || c.maxLength >= 0 && n > c.maxLength {
+		return false
+	}
+	for _, rx := range c.patterns {
+		if !rx.MatchString(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// replace replaces the value located at `path` within `root`
+func replace(root interface{}, path schema.Pointer, v interface{}) (interface{}, error) {
+	tokens, err := path.Tokens()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid path")
+	}
+	if len(tokens) == 0 {
+		return v, nil
+	}
+
+	cur := root
+	for i, tok := range tokens {
+		last := i == len(tokens)-1
+		switch container := cur.(type) {
+		case map[string]interface{}:
+			if last {
+				container[tok] = v
+			} else {
+				cur = container[tok]
+			}
+		case []interface{}:
+			idx, err := strconv.Atoi(tok)
+			if err != nil || idx < 0 || idx >= len(container) {
+				return nil, errors.Errorf("invalid array index in path %s", path)
+			}
+			if last {
+				container[idx] = v
+			} else {
+				cur = container[idx]
+			}
+		default:
+			return nil, errors.Errorf("path %s does not exist", path)
+		}
+	}
+	return root, nil
+}
+
+// without returns a copy of `m` without the key `name`
+func without(m map[string]interface{}, name string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if k != name {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func containsNull(l []interface{}) bool {
+	for _, v := range l {
+		if v == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedSet(l []string) []string {
+	seen := make(map[string]struct{}, len(l))
+	out := make([]string, 0, len(l))
+	for _, s := range l {
+		if _, ok := seen[s]; !ok {
+			seen[s] = struct{}{}
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sortedNames(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,355 @@
+package instance_test
+
+import (
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/lestrrat-go/jsschema/instance"
+	"github.com/lestrrat-go/jsschema/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+const fuzzSchema = `{
+  "type": "object",
+  "required": ["id", "name", "tags"],
+  "additionalProperties": false,
+  "properties": {
+    "id": {"type": "integer", "minimum": 1, "maximum": 1000},
+    "name": {"type": "string", "minLength": 1, "maxLength": 8},
+    "code": {"type": "string", "pattern": "^[A-Z]{3}-[0-9]+$"},
+    "email": {"type": "string", "format": "email"},
+    "price": {"type": "number", "minimum": 0, "exclusiveMinimum": true, "multipleOf": 0.25},
+    "status": {"enum": ["active", "inactive"]},
+    "tags": {"type": "array", "items": {"type": "string"}, "minItems": 1, "maxItems": 3, "uniqueItems": true},
+    "point": {"type": "array", "items": [{"type": "number"}, {"type": "number"}], "additionalItems": false},
+    "card": {"type": "string"},
+    "billing": {"type": "string"}
+  },
+  "dependencies": {"card": ["billing"]}
+}`
+
+func readFuzzSchema(t testing.TB) *schema.Schema {
+	s, err := schema.Read(strings.NewReader(fuzzSchema))
+	if err != nil {
+		t.Fatalf("failed to read schema: %s", err)
+	}
+	return s
+}
+
+// checkFuzzInstance returns the keywords of fuzzSchema that `v` violates
+func checkFuzzInstance(v interface{}) []string {
+	var violations []string
+	violate := func(keyword string) {
+		violations = append(violations, keyword)
+	}
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return []string{"type"}
+	}
+	for _, name := range []string{"id", "name", "tags"} {
+		if _, ok := m[name]; !ok {
+			violate("required")
+		}
+	}
+	if _, ok := m["card"]; ok {
+		if _, ok := m["billing"]; !ok {
+			violate("dependencies")
+		}
+	}
+
+	for k, pv := range m {
+		switch k {
+		case "id":
+			f, ok := pv.(float64)
+			switch {
+			case !ok || f != math.Trunc(f):
+				violate("type")
+			case f < 1:
+				violate("minimum")
+			case f > 1000:
+				violate("maximum")
+			}
+		case "name", "code", "email", "card", "billing":
+			s, ok := pv.(string)
+			if !ok {
+				violate("type")
+				continue
+			}
+			switch n := utf8.RuneCountInString(s); {
+			case k == "name" && n < 1:
+				violate("minLength")
+			case k == "name" && n > 8:
+				violate("maxLength")
+			case k == "code" && !regexp.MustCompile(`^[A-Z]{3}-[0-9]+$`).MatchString(s):
+				violate("pattern")
+			case k == "email" && !strings.Contains(s, "@"):
+				violate("format")
+			}
+		case "price":
+			f, ok := pv.(float64)
+			switch {
+			case !ok:
+				violate("type")
+			case f <= 0:
+				violate("minimum")
+			case f/0.25 != math.Trunc(f/0.25):
+				violate("multipleOf")
+			}
+		case "status":
+			if pv != "active" && pv != "inactive" {
+				violate("enum")
+			}
+		case "tags":
+			l, ok := pv.([]interface{})
+			if !ok {
+				violate("type")
+				continue
+			}
+			switch {
+			case len(l) < 1:
+				violate("minItems")
+			case len(l) > 3:
+				violate("maxItems")
+			}
+			seen := make(map[interface{}]bool)
+			for _, e := range l {
+				if _, ok := e.(string); !ok {
+					violate("type")
+				}
+				if seen[e] {
+					violate("uniqueItems")
+				}
+				seen[e] = true
+			}
+		case "point":
+			l, ok := pv.([]interface{})
+			if !ok {
+				violate("type")
+				continue
+			}
+			if len(l) > 2 {
+				violate("additionalItems")
+			}
+			for _, e := range l[:min(len(l), 2)] {
+				if _, ok := e.(float64); !ok {
+					violate("type")
+				}
+			}
+		default:
+			violate("additionalProperties")
+		}
+	}
+	return violations
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func TestFuzzerValid(t *testing.T) {
+	s := readFuzzSchema(t)
+
+	for seed := int64(0); seed < 200; seed++ {
+		v, err := instance.NewFuzzer(s, seed).Valid()
+		if !assert.NoError(t, err, "Valid should succeed (seed = %d)", seed) {
+			return
+		}
+		if !assert.Empty(t, checkFuzzInstance(v), "instance should be valid (seed = %d): %#v", seed, v) {
+			return
+		}
+	}
+}
+
+func TestFuzzerInvalid(t *testing.T) {
+	s := readFuzzSchema(t)
+
+	keywords := make(map[string]bool)
+	for seed := int64(0); seed < 500; seed++ {
+		inv, err := instance.NewFuzzer(s, seed).Invalid()
+		if !assert.NoError(t, err, "Invalid should succeed (seed = %d)", seed) {
+			return
+		}
+		if !assert.Equal(t, []string{inv.Keyword}, checkFuzzInstance(inv.Value), "instance should violate only %s at %s (seed = %d): %#v", inv.Keyword, inv.Pointer, seed, inv.Value) {
+			return
+		}
+		keywords[inv.Keyword] = true
+	}
+
+	for _, keyword := range []string{"type", "enum", "minimum", "maximum", "multipleOf", "minLength", "maxLength", "pattern", "format", "minItems", "maxItems", "uniqueItems", "additionalItems", "required", "additionalProperties", "dependencies"} {
+		if !assert.True(t, keywords[keyword], "%s should have been violated", keyword) {
+			return
+		}
+	}
+}
+
+// TestFuzzerValidator checks the instances generated for the schemas
+// under test/ with the validator
+func TestFuzzerValidator(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "test", "*.json"))
+	if !assert.NoError(t, err, "filepath.Glob should succeed") {
+		return
+	}
+
+	for _, file := range files {
+		if strings.Contains(file, "_pass") || strings.Contains(file, "_fail") {
+			continue
+		}
+
+		s, err := schema.ReadFile(file)
+		if !assert.NoError(t, err, "schema.ReadFile(%s) should succeed", file) {
+			return
+		}
+
+		v := validator.New(s)
+		for seed := int64(0); seed < 50; seed++ {
+			if valid, err := instance.NewFuzzer(s, seed).Valid(); err == nil {
+				if !assert.NoError(t, v.Validate(valid), "instance for %s should be valid (seed = %d)", file, seed) {
+					return
+				}
+			}
+			if inv, err := instance.NewFuzzer(s, seed).Invalid(); err == nil {
+				if !assert.Error(t, v.Validate(inv.Value), "instance for %s should violate %s (seed = %d)", file, inv.Keyword, seed) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// TestFuzzerNot checks that invalid instances do not violate "not" in
+// addition to the reported keyword
+func TestFuzzerNot(t *testing.T) {
+	tests := []struct {
+		schema string
+		check  func(interface{}) []string // returns the violated keywords
+	}{
+		{`{
+  "type": "object",
+  "properties": {"a": {"type": "integer"}},
+  "required": ["a"],
+  "not": {"properties": {"a": {"type": "string"}}, "required": ["a"]}
+}`, func(v interface{}) []string {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return []string{"type"}
+			}
+			a, ok := m["a"]
+			switch {
+			case !ok:
+				return []string{"required"}
+			case typeName(a) == "string":
+				return []string{"type", "not"}
+			case typeName(a) != "integer":
+				return []string{"type"}
+			}
+			return nil
+		}},
+		{`{"type": "integer", "minimum": 0, "maximum": 5, "not": {"minimum": 10}}`, func(v interface{}) []string {
+			if typeName(v) != "integer" {
+				return []string{"type"}
+			}
+			switch f := v.(float64); {
+			case f < 0:
+				return []string{"minimum"}
+			case f >= 10:
+				return []string{"maximum", "not"}
+			case f > 5:
+				return []string{"maximum"}
+			}
+			return nil
+		}},
+	}
+
+	for _, test := range tests {
+		s, err := schema.Read(strings.NewReader(test.schema))
+		if !assert.NoError(t, err, "schema.Read should succeed") {
+			return
+		}
+		for seed := int64(0); seed < 100; seed++ {
+			inv, err := instance.NewFuzzer(s, seed).Invalid()
+			if !assert.NoError(t, err, "Invalid should succeed (seed = %d)", seed) {
+				return
+			}
+			if !assert.Equal(t, []string{inv.Keyword}, test.check(inv.Value), "instance should violate only %s (seed = %d): %#v", inv.Keyword, seed, inv.Value) {
+				return
+			}
+		}
+	}
+}
+
+// typeName returns the JSON type of a decoded value, telling integers
+// apart from other numbers
+func typeName(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+func TestFuzzerNoViolation(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(`{}`))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+	_, err = instance.NewFuzzer(s, 0).Invalid()
+	if !assert.Error(t, err, "Invalid should fail when anything is valid") {
+		return
+	}
+}
+
+func FuzzFuzzer(f *testing.F) {
+	s := readFuzzSchema(f)
+	for seed := int64(0); seed < 8; seed++ {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, seed int64) {
+		v, err := instance.NewFuzzer(s, seed).Valid()
+		if err != nil {
+			t.Fatalf("Valid failed: %s", err)
+		}
+		if violations := checkFuzzInstance(v); len(violations) > 0 {
+			t.Fatalf("valid instance violates %v: %#v", violations, v)
+		}
+
+		// The same seed must produce the same instance
+		again, _ := instance.NewFuzzer(s, seed).Valid()
+		buf1, _ := json.Marshal(v)
+		buf2, _ := json.Marshal(again)
+		if string(buf1) != string(buf2) {
+			t.Fatalf("instances differ for the same seed: %s and %s", buf1, buf2)
+		}
+
+		inv, err := instance.NewFuzzer(s, seed).Invalid()
+		if err != nil {
+			t.Fatalf("Invalid failed: %s", err)
+		}
+		if violations := checkFuzzInstance(inv.Value); len(violations) != 1 || violations[0] != inv.Keyword {
+			t.Fatalf("invalid instance violates %v instead of %s: %#v", violations, inv.Keyword, inv.Value)
+		}
+	})
+}
@@ -0,0 +1,1439 @@
+// Package instance generates JSON values that conform to JSON schemas.
+package instance
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/lestrrat-go/jsschema/internal/option"
+	"github.com/lestrrat-go/jsschema/validator"
+	"github.com/pkg/errors"
+)
+
+// Option is an option that can be passed to Generate
+type Option interface {
+	option.Interface
+}
+
+const (
+	optkeyOptionalProperties = "optional-properties"
+)
+
+// WithOptionalProperties specifies if properties that are not required
+// should be included in objects, and if arrays should contain at least
+// one element. This makes for more useful examples, and is enabled by
+// default. Optional properties of deeply nested (e.g. recursive) schemas
+// are omitted regardless of this option.
+func WithOptionalProperties(b bool) Option {
+	return option.New(optkeyOptionalProperties, b)
+}
+
+const (
+	// maxOptionalDepth is the depth after which optional properties and
+	// array elements are no longer generated
+	maxOptionalDepth = 5
+	// maxDepth is the depth after which generation fails, which happens
+	// when a schema requires itself
+	maxDepth = 64
+	// maxAttempts is the number of values that are tried in addition to
+	// the first one, when values must not match some schemas
+	maxAttempts = 16
+)
+
+// Generate returns a value that validates against the schema `s`. The
+// value is made of the same types that encoding/json produces when
+// decoding into an interface{}, so it can be marshaled into JSON, or
+// passed to a validator.
+//
+// Generation is deterministic. Values are taken from "default" or
+// "enum" when available and valid. Otherwise they are built from the constraints
+// of the schema: "type", "minimum", "maximum", "multipleOf",
+// "minLength", "maxLength", "pattern", "format", "required",
+// "minItems", "maxItems", "uniqueItems", tuple "items",
+// "minProperties", "maxProperties" and "dependencies". Subschemas of
+// "allOf" are merged into their parent, and the first alternative of
+// "oneOf" and "anyOf" that can be generated is used. Values that match
+// the schema of "not", or another alternative of "oneOf", are replaced
+// by other values, and an error is returned if none can be found.
+func Generate(s *schema.Schema, options ...Option) (interface{}, error) {
+	g := newGenerator(options)
+	return g.generate([]*schema.Schema{s}, schema.Pointer("#"), 0, 0)
+}
+
+// generator generates values. If rnd is nil, values are deterministic
+type generator struct {
+	optional bool
+	rnd      *rand.Rand
+	sites    *[]site // if non-nil, locations that may be mutated are recorded
+	fixed    int     // greater than zero while generating values that must not be mutated
+
+	validators map[*schema.Schema]*validator.Validator // for the schemas that values are checked against
+	selfRefs   map[*schema.Schema]bool                 // for the roots that refer to themselves as a whole
+}
+
+func newGenerator(options []Option) *generator {
+	g := &generator{optional: true}
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyOptionalProperties:
+			g.optional = o.Value().(bool)
+		}
+	}
+	return g
+}
+
+// chance returns true once every `n` times on average
+func (g *generator) chance(n int) bool {
+	return g.rnd.Intn(n) == 0
+}
+
+// generate returns a value that validates against all of `schemas`.
+// `path` is the location of the value within the instance, and
+// `variant` is used to generate distinct values, for unique items
+func (g *generator) generate(schemas []*schema.Schema, path schema.Pointer, depth, variant int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, &depthError{path: path}
+	}
+
+	c, err := merge(schemas)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pick an alternative for oneOf and anyOf, trying them in order.
+	// Mutating the result could make it match another alternative, so
+	// it is never mutated
+	if len(c.choices) > 0 {
+		choice := c.choices[0]
+		alternatives := choice.alternatives
+		if g.rnd != nil {
+			alternatives = append([]*schema.Schema(nil), alternatives...)
+			g.rnd.Shuffle(len(alternatives), func(i, j int) {
+				alternatives[i], alternatives[j] = alternatives[j], alternatives[i]
+			})
+		}
+
+		g.fixed++
+		defer func() { g.fixed-- }()
+		var lastErr error
+		for _, alt := range alternatives {
+			next := append(append([]*schema.Schema(nil), schemas...), alt)
+			var excluded []*schema.Schema
+			if choice.exclusive {
+				// The value of a oneOf must not match the other alternatives
+				for _, other := range choice.alternatives {
+					if other != alt {
+						excluded = append(excluded, other)
+					}
+				}
+			}
+			next = markChosen(next, choice.owner, excluded)
+			v, err := g.generate(next, path, depth, variant)
+			if err == nil {
+				return v, nil
+			}
+			lastErr = err
+		}
+		return nil, wrapf(lastErr, "no alternative could be generated")
+	}
+
+	// Values that match a schema of "not" are discarded, and other
+	// values are tried instead
+	for attempt := 0; ; attempt++ {
+		var mark int
+		if g.sites != nil {
+			mark = len(*g.sites)
+		}
+		v, err := g.value(c, path, depth, variant, attempt)
+		if err == nil {
+			err = g.exclude(c.nots, v)
+		}
+		if g.sites != nil {
+			if err != nil {
+				// The value is discarded, and so are the locations within it
+				*g.sites = (*g.sites)[:mark]
+			} else if g.fixed == 0 {
+				*g.sites = append(*g.sites, site{path: path, c: c, depth: depth, value: v})
+			}
+		}
+		if err == nil || len(c.nots) == 0 || attempt >= maxAttempts {
+			return v, err
+		}
+	}
+}
+
+// depthError is returned when values are nested too deeply. It is
+// returned as is by the enclosing values, which would only repeat the
+// same context many times
+type depthError struct {
+	path schema.Pointer
+}
+
+func (e *depthError) Error() string {
+	return fmt.Sprintf("schema is nested too deeply at %s (does it require itself?)", e.path)
+}
+
+// wrapf wraps `err` unless it is a *depthError
+func wrapf(err error, format string, args ...interface{}) error {
+	if _, ok := err.(*depthError); ok {
+		return err
+	}
+	return errors.Wrapf(err, format, args...)
+}
+
+// validator returns the validator of `s`, which is created once
+func (g *generator) validator(s *schema.Schema) *validator.Validator {
+	val, ok := g.validators[s]
+	if !ok {
+		val = validator.New(s)
+		if g.validators == nil {
+			g.validators = make(map[*schema.Schema]*validator.Validator)
+		}
+		g.validators[s] = val
+	}
+	return val
+}
+
+// exclude returns an error if `v` validates against any of `schemas`
+func (g *generator) exclude(schemas []*schema.Schema, v interface{}) error {
+	for _, s := range schemas {
+		if g.validator(s).Validate(v) == nil {
+			return errors.Errorf("value matches the schema at %s, which it must not match", s.Pointer())
+		}
+	}
+	return nil
+}
+
+// accepts returns true if `v` validates against all of `schemas`.
+// Subschemas of roots that refer to themselves as a whole are skipped,
+// as the validator would resolve "#" to the subschema
+func (g *generator) accepts(schemas []*schema.Schema, v interface{}) bool {
+	for _, s := range schemas {
+		if root := s.Root(); root != s && g.refersToSelf(root) {
+			continue
+		}
+		if g.validator(s).Validate(v) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// refersToSelf returns true if `root` contains a reference to "#"
+func (g *generator) refersToSelf(root *schema.Schema) bool {
+	found, ok := g.selfRefs[root]
+	if !ok {
+		schema.Walk(root, func(_ schema.Pointer, node *schema.Schema) error {
+			if node.Reference == "#" {
+				found = true
+			}
+			return nil
+		})
+		if g.selfRefs == nil {
+			g.selfRefs = make(map[*schema.Schema]bool)
+		}
+		g.selfRefs[root] = found
+	}
+	return found
+}
+
+// value returns a value that satisfies `c`. `attempt` is greater than
+// zero when previous values had to be discarded
+func (g *generator) value(c *constraints, path schema.Pointer, depth, variant, attempt int) (interface{}, error) {
+	// Values of "default" and "enum" are only checked for their type by
+	// the constraints, so they are validated against the whole schemas
+	if c.hasDefault && variant+attempt == 0 && c.allows(typeOf(c.def)) && g.accepts(c.schemas, c.def) && (g.rnd == nil || g.chance(4)) {
+		return copyValue(c.def), nil
+	}
+
+	if c.enum != nil {
+		var candidates []interface{}
+		for _, v := range c.enum {
+			if c.allows(typeOf(v)) && g.accepts(c.schemas, v) {
+				candidates = append(candidates, v)
+			}
+		}
+		if g.rnd != nil && len(candidates) > 0 {
+			return copyValue(candidates[g.rnd.Intn(len(candidates))]), nil
+		}
+		if variant+attempt >= len(candidates) {
+			return nil, errors.New("not enough values in enum")
+		}
+		return copyValue(candidates[variant+attempt]), nil
+	}
+
+	t := g.pickType(c)
+	if t == schema.IntegerType || t == schema.NumberType {
+		return c.number(t == schema.IntegerType, variant, attempt, g.rnd)
+	}
+	variant += attempt
+	switch t {
+	case schema.NullType:
+		if variant > 0 {
+			return nil, errors.New("null can not be made unique")
+		}
+		return nil, nil
+	case schema.BooleanType:
+		if g.rnd != nil {
+			return g.chance(2), nil
+		}
+		if variant > 1 {
+			return nil, errors.New("not enough boolean values")
+		}
+		return variant == 1, nil
+	case schema.StringType:
+		return c.string(variant, g.rnd)
+	case schema.ArrayType:
+		return g.array(c, path, depth, variant)
+	case schema.ObjectType:
+		return g.object(c, path, depth, variant)
+	}
+	return nil, errors.New("no type satisfies the schema")
+}
+
+var allTypes = []schema.PrimitiveType{
+	schema.NullType,
+	schema.BooleanType,
+	schema.IntegerType,
+	schema.NumberType,
+	schema.StringType,
+	schema.ArrayType,
+	schema.ObjectType,
+}
+
+// pickType picks the type of the value to generate
+func (g *generator) pickType(c *constraints) schema.PrimitiveType {
+	if g.rnd == nil {
+		return c.pickType()
+	}
+
+	types := c.types
+	if types == nil {
+		// Schemas without any keywords accept anything
+		if t := c.pickType(); t != schema.NullType {
+			return t
+		}
+		types = allTypes
+	}
+	if len(types) == 0 {
+		return schema.UnspecifiedType
+	}
+	return types[g.rnd.Intn(len(types))]
+}
+
+// count returns a random number of elements between `min` and `max`,
+// a negative `max` meaning that there is no upper bound
+func (g *generator) count(min, max int) int {
+	hi := min + 3
+	if max >= 0 && hi > max {
+		hi = max
+	}
+	if hi <= min {
+		return min
+	}
+	return min + g.rnd.Intn(hi-min+1)
+}
+
+func (g *generator) array(c *constraints, path schema.Pointer, depth, variant int) (interface{}, error) {
+	count := c.minItems
+	if g.optional && depth < maxOptionalDepth {
+		if len(c.tuple) > count {
+			count = len(c.tuple)
+		}
+		if count == 0 {
+			count = 1
+		}
+		if g.rnd != nil {
+			count = g.count(c.minItems, c.maxItems)
+		}
+	}
+	// Arrays can be made unique by adding elements
+	count += variant
+	if c.maxItems >= 0 && count > c.maxItems {
+		count = c.maxItems
+	}
+	if !c.additionalItems && count > len(c.tuple) && c.tupleMode {
+		count = len(c.tuple)
+	}
+	if count < c.minItems {
+		return nil, errors.New("can not satisfy minItems")
+	}
+
+	// Elements of unique arrays could become duplicates if mutated
+	if c.uniqueItems {
+		g.fixed++
+		defer func() { g.fixed-- }()
+	}
+
+	l := make([]interface{}, 0, count)
+	seen := make(map[string]struct{})
+	for i := 0; i < count; i++ {
+		var schemas []*schema.Schema
+		switch {
+		case !c.tupleMode:
+			schemas = c.items
+		case i < len(c.tuple):
+			schemas = []*schema.Schema{c.tuple[i]}
+		default:
+			schemas = c.additionalItemSchemas
+		}
+
+		itemPath := path.Append(strconv.Itoa(i))
+		itemVariant := 0
+		if c.uniqueItems && g.rnd == nil {
+			itemVariant = len(l)
+		}
+
+		var v interface{}
+		var err error
+		for attempt := 0; ; attempt++ {
+			v, err = g.generate(schemas, itemPath, depth+1, itemVariant)
+			if err != nil || !c.uniqueItems {
+				break
+			}
+			key := encodeKey(v)
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				break
+			}
+			// Random values may be made unique by trying again
+			if g.rnd == nil || attempt >= 16 {
+				err = errors.New("can not generate unique items")
+				break
+			}
+		}
+		if err != nil {
+			if len(l) >= c.minItems && i >= len(c.tuple) {
+				break
+			}
+			return nil, wrapf(err, "failed to generate item %d", i)
+		}
+		l = append(l, v)
+	}
+	return l, nil
+}
+
+func (g *generator) object(c *constraints, path schema.Pointer, depth, variant int) (interface{}, error) {
+	required := make(map[string]bool)
+	var names []string
+	add := func(name string, req bool) {
+		if _, ok := required[name]; !ok {
+			names = append(names, name)
+			required[name] = req
+		} else if req {
+			required[name] = true
+		}
+	}
+
+	for _, name := range c.required {
+		add(name, true)
+	}
+	if g.optional && depth < maxOptionalDepth {
+		for _, name := range sortedKeys(c.properties) {
+			if g.rnd == nil || g.chance(2) {
+				add(name, false)
+			}
+		}
+	}
+
+	// Dependencies may add requirements, which may have dependencies
+	// of their own
+	applied := make(map[string]bool)
+	dropped := make(map[string]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, name := range append([]string(nil), names...) {
+			if applied[name] {
+				continue
+			}
+			applied[name] = true
+			changed = true
+			if !required[name] && !c.allowsDependencies(name) {
+				// The property is left out rather than the object
+				// made invalid
+				dropped[name] = true
+				continue
+			}
+			for _, dep := range c.depNames[name] {
+				add(dep, true)
+			}
+			for _, dep := range c.depSchemas[name] {
+				dc, err := merge([]*schema.Schema{dep})
+				if err != nil {
+					return nil, err
+				}
+				c.absorbObject(dc)
+				for _, r := range dc.required {
+					add(r, true)
+				}
+			}
+		}
+	}
+
+	m := make(map[string]interface{})
+	for _, name := range names {
+		if dropped[name] || c.maxProperties >= 0 && len(m) >= c.maxProperties && !required[name] {
+			continue
+		}
+		if !c.allowsProperty(name) {
+			if required[name] {
+				return nil, errors.Errorf("property %s is required but not allowed", strconv.Quote(name))
+			}
+			continue
+		}
+		v, err := g.generate(c.propertySchemas(name), path.Append(name), depth+1, 0)
+		if err != nil {
+			if required[name] {
+				return nil, wrapf(err, "failed to generate property %s", strconv.Quote(name))
+			}
+			continue
+		}
+		m[name] = v
+	}
+
+	// Objects are made unique by adding properties. Properties that were
+	// left out are used first, then additional properties
+	extra := c.minProperties
+	if variant > 0 && len(m)+variant > extra {
+		extra = len(m) + variant
+	}
+	if g.rnd != nil && c.additionalProperties && g.optional && depth < maxOptionalDepth {
+		if n := g.count(len(m), c.maxProperties); n > extra {
+			extra = n
+		}
+	}
+	if g.rnd == nil {
+		for _, name := range sortedKeys(c.properties) {
+			if len(m) >= extra {
+				break
+			}
+			// Properties with dependencies would require others
+			if _, ok := m[name]; ok || c.hasDependencies(name) {
+				continue
+			}
+			if v, err := g.generate(c.propertySchemas(name), path.Append(name), depth+1, 0); err == nil {
+				m[name] = v
+			}
+		}
+	}
+	for i := 1; len(m) < extra; i++ {
+		if i > extra+8 {
+			return nil, errors.New("can not satisfy minProperties")
+		}
+		name := "property" + strconv.Itoa(i)
+		if g.rnd != nil {
+			name = randomName(g.rnd)
+		}
+		if !c.additionalProperties {
+			// Only names that match "patternProperties" are allowed
+			var ok bool
+			if name, ok = c.patternName(i-1, g.rnd); !ok {
+				return nil, errors.New("can not satisfy minProperties without additional properties")
+			}
+		}
+		if _, ok := m[name]; ok || c.hasDependencies(name) {
+			continue
+		}
+		v, err := g.generate(c.propertySchemas(name), path.Append(name), depth+1, 0)
+		if err != nil {
+			return nil, wrapf(err, "failed to generate additional property")
+		}
+		m[name] = v
+	}
+
+	if c.maxProperties >= 0 && len(m) > c.maxProperties {
+		return nil, errors.New("can not satisfy maxProperties")
+	}
+	return m, nil
+}
+
+// randomName returns a random property name
+func randomName(rnd *rand.Rand) string {
+	b := make([]byte, 3+rnd.Intn(6))
+	for i := range b {
+		b[i] = byte('a' + rnd.Intn(26))
+	}
+	return string(b)
+}
+
+// choice is a oneOf or anyOf, of which one alternative must be picked.
+// The value of an exclusive choice (oneOf) must match only one of them
+type choice struct {
+	owner        *schema.Schema
+	alternatives []*schema.Schema
+	exclusive    bool
+}
+
+// constraints holds the combined constraints of several schemas
+type constraints struct {
+	types      []schema.PrimitiveType // nil if any type is allowed
+	enum       []interface{}          // nil if there is no enum
+	def        interface{}
+	hasDefault bool
+	choices    []choice
+	nots       []*schema.Schema // schemas that the value must not match
+	schemas    []*schema.Schema // merged schemas, that "default" and "enum" values are validated against
+
+	minimum, maximum     *float64
+	exclusiveMinimum     bool
+	exclusiveMaximum     bool
+	multipleOf           []float64
+	minLength, maxLength int
+	patterns             []*regexp.Regexp
+	format               schema.Format
+
+	tupleMode             bool
+	items                 []*schema.Schema // applied to every element
+	tuple                 []*schema.Schema
+	additionalItems       bool
+	additionalItemSchemas []*schema.Schema
+	minItems, maxItems    int
+	uniqueItems           bool
+
+	required             []string
+	properties           map[string][]*schema.Schema
+	patternProperties    map[*regexp.Regexp][]*schema.Schema
+	additionalProperties bool
+	additionalSchemas    []*schema.Schema
+	depNames             map[string][]string
+	depSchemas           map[string][]*schema.Schema
+	minProperties        int
+	maxProperties        int
+}
+
+// markChosen records that the choices of `owner` have been made, and
+// that the value must not match the schemas `excluded`. The returned
+// list starts with a sentinel schema carrying that information
+func markChosen(schemas []*schema.Schema, owner *schema.Schema, excluded []*schema.Schema) []*schema.Schema {
+	marker := schema.New()
+	marker.Extras = map[string]interface{}{chosenKey: owner, excludedKey: excluded}
+	return append([]*schema.Schema{marker}, schemas...)
+}
+
+// chosenKey and excludedKey are the keys within Extras of the sentinel
+// schemas that markChosen creates. They can not collide with keys read
+// from JSON, as those never hold *schema.Schema values
+const (
+	chosenKey   = "\x00chosen"
+	excludedKey = "\x00excluded"
+)
+
+func merge(schemas []*schema.Schema) (*constraints, error) {
+	c := &constraints{
+		minLength:            0,
+		maxLength:            -1,
+		maxItems:             -1,
+		maxProperties:        -1,
+		additionalItems:      true,
+		additionalProperties: true,
+		properties:           make(map[string][]*schema.Schema),
+		patternProperties:    make(map[*regexp.Regexp][]*schema.Schema),
+		depNames:             make(map[string][]string),
+		depSchemas:           make(map[string][]*schema.Schema),
+	}
+
+	decided := make(map[*schema.Schema]bool)
+	for _, s := range schemas {
+		if owner, ok := s.Extras[chosenKey].(*schema.Schema); ok {
+			decided[owner] = true
+			c.nots = append(c.nots, s.Extras[excludedKey].([]*schema.Schema)...)
+		}
+	}
+
+	visiting := make(map[*schema.Schema]bool)
+	for _, s := range schemas {
+		if _, ok := s.Extras[chosenKey]; ok {
+			continue
+		}
+		c.schemas = append(c.schemas, s)
+		if err := c.add(s, decided, visiting); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *constraints) add(s *schema.Schema, decided, visiting map[*schema.Schema]bool) error {
+	if s == nil {
+		return nil
+	}
+	s, err := s.Resolve(nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to resolve reference")
+	}
+	// allOf that includes itself adds nothing new
+	if visiting[s] {
+		return nil
+	}
+	visiting[s] = true
+	defer delete(visiting, s)
+
+	if len(s.Type) > 0 {
+		c.restrictTypes(s.Type)
+	}
+	if s.Enum != nil {
+		c.restrictEnum(s.Enum)
+	}
+	if s.Default != nil && !c.hasDefault {
+		c.def = s.Default
+		c.hasDefault = true
+	}
+	if s.Not != nil {
+		c.nots = append(c.nots, s.Not)
+	}
+	if !decided[s] {
+		if len(s.OneOf) > 0 {
+			c.choices = append(c.choices, choice{owner: s, alternatives: s.OneOf, exclusive: true})
+		} else if len(s.AnyOf) > 0 {
+			c.choices = append(c.choices, choice{owner: s, alternatives: s.AnyOf})
+		}
+	}
+
+	if s.Minimum.Initialized {
+		v := s.Minimum.Val
+		if c.minimum == nil || v > *c.minimum || v == *c.minimum && s.ExclusiveMinimum.Bool() {
+			c.minimum = &v
+			c.exclusiveMinimum = s.ExclusiveMinimum.Bool()
+		}
+	}
+	if s.Maximum.Initialized {
+		v := s.Maximum.Val
+		if c.maximum == nil || v < *c.maximum || v == *c.maximum && s.ExclusiveMaximum.Bool() {
+			c.maximum = &v
+			c.exclusiveMaximum = s.ExclusiveMaximum.Bool()
+		}
+	}
+	if s.MultipleOf.Initialized && s.MultipleOf.Val > 0 {
+		c.multipleOf = append(c.multipleOf, s.MultipleOf.Val)
+	}
+
+	if s.MinLength.Initialized && s.MinLength.Val > c.minLength {
+		c.minLength = s.MinLength.Val
+	}
+	if s.MaxLength.Initialized && (c.maxLength < 0 || s.MaxLength.Val < c.maxLength) {
+		c.maxLength = s.MaxLength.Val
+	}
+	if s.Pattern != nil {
+		c.patterns = append(c.patterns, s.Pattern)
+	}
+	if s.Format != "" && c.format == "" {
+		c.format = s.Format
+	}
+
+	if s.Items != nil {
+		if s.Items.TupleMode {
+			if !c.tupleMode {
+				c.tupleMode = true
+				c.tuple = s.Items.Schemas
+			}
+		} else {
+			c.items = append(c.items, s.Items.Schemas...)
+		}
+	}
+	if s.AdditionalItems == nil {
+		c.additionalItems = false
+	} else if s.AdditionalItems.Schema != nil {
+		c.additionalItemSchemas = append(c.additionalItemSchemas, s.AdditionalItems.Schema)
+	}
+	if s.MinItems.Initialized && s.MinItems.Val > c.minItems {
+		c.minItems = s.MinItems.Val
+	}
+	if s.MaxItems.Initialized && (c.maxItems < 0 || s.MaxItems.Val < c.maxItems) {
+		c.maxItems = s.MaxItems.Val
+	}
+	if s.UniqueItems.Bool() {
+		c.uniqueItems = true
+	}
+
+	c.required = append(c.required, s.Required...)
+	for k, v := range s.Properties {
+		c.properties[k] = append(c.properties[k], v)
+	}
+	for rx, v := range s.PatternProperties {
+		c.patternProperties[rx] = append(c.patternProperties[rx], v)
+	}
+	if s.AdditionalProperties == nil {
+		c.additionalProperties = false
+	} else if s.AdditionalProperties.Schema != nil {
+		c.additionalSchemas = append(c.additionalSchemas, s.AdditionalProperties.Schema)
+	}
+	for k, v := range s.Dependencies.Names {
+		c.depNames[k] = append(c.depNames[k], v...)
+	}
+	for k, v := range s.Dependencies.Schemas {
+		c.depSchemas[k] = append(c.depSchemas[k], v)
+	}
+	if s.MinProperties.Initialized && s.MinProperties.Val > c.minProperties {
+		c.minProperties = s.MinProperties.Val
+	}
+	if s.MaxProperties.Initialized && (c.maxProperties < 0 || s.MaxProperties.Val < c.maxProperties) {
+		c.maxProperties = s.MaxProperties.Val
+	}
+
+	for _, v := range s.AllOf {
+		if err := c.add(v, decided, visiting); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// absorbObject adds the object constraints of `o`, which come from a
+// schema dependency
+func (c *constraints) absorbObject(o *constraints) {
+	c.required = append(c.required, o.required...)
+	for k, v := range o.properties {
+		c.properties[k] = append(c.properties[k], v...)
+	}
+	for rx, v := range o.patternProperties {
+		c.patternProperties[rx] = append(c.patternProperties[rx], v...)
+	}
+	if !o.additionalProperties {
+		c.additionalProperties = false
+	}
+	c.additionalSchemas = append(c.additionalSchemas, o.additionalSchemas...)
+}
+
+// propertySchemas returns the schemas that the value of the property
+// `name` must validate against
+func (c *constraints) propertySchemas(name string) []*schema.Schema {
+	schemas := append([]*schema.Schema(nil), c.properties[name]...)
+	matched := len(schemas) > 0
+	for rx, l := range c.patternProperties {
+		if rx.MatchString(name) {
+			schemas = append(schemas, l...)
+			matched = true
+		}
+	}
+	if !matched {
+		schemas = append(schemas, c.additionalSchemas...)
+	}
+	return schemas
+}
+
+// allowsProperty returns true if objects may have the property `name`
+func (c *constraints) allowsProperty(name string) bool {
+	if c.additionalProperties {
+		return true
+	}
+	if _, ok := c.properties[name]; ok {
+		return true
+	}
+	for rx := range c.patternProperties {
+		if rx.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsDependencies returns true if objects may have the properties
+// that the property `name` requires
+func (c *constraints) allowsDependencies(name string) bool {
+	for _, dep := range c.depNames[name] {
+		if !c.allowsProperty(dep) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasDependencies returns true if the property `name` has dependencies
+func (c *constraints) hasDependencies(name string) bool {
+	return len(c.depNames[name]) > 0 || len(c.depSchemas[name]) > 0
+}
+
+// patternName returns a property name that matches one of the patterns
+// of "patternProperties", taking turns between them for successive
+// values of `i`
+func (c *constraints) patternName(i int, rnd *rand.Rand) (string, bool) {
+	if len(c.patternProperties) == 0 {
+		return "", false
+	}
+	patterns := make([]*regexp.Regexp, 0, len(c.patternProperties))
+	for rx := range c.patternProperties {
+		patterns = append(patterns, rx)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		return patterns[i].String() < patterns[j].String()
+	})
+
+	pc := constraints{patterns: []*regexp.Regexp{patterns[i%len(patterns)]}, maxLength: -1}
+	v, err := pc.patternString(i/len(patterns), rnd)
+	if err != nil {
+		return "", false
+	}
+	return v.(string), true
+}
+
+func allowsType(types []schema.PrimitiveType, t schema.PrimitiveType) bool {
+	for _, v := range types {
+		if v == t || v == schema.NumberType && t == schema.IntegerType {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *constraints) restrictTypes(types schema.PrimitiveTypes) {
+	if c.types == nil {
+		c.types = append([]schema.PrimitiveType(nil), types...)
+		return
+	}
+
+	var l []schema.PrimitiveType
+	for _, t := range c.types {
+		switch {
+		case allowsType(types, t):
+			l = append(l, t)
+		case t == schema.NumberType && allowsType(types, schema.IntegerType):
+			l = append(l, schema.IntegerType)
+		}
+	}
+	if l == nil {
+		l = []schema.PrimitiveType{}
+	}
+	c.types = l
+}
+
+func (c *constraints) restrictEnum(enum []interface{}) {
+	if c.enum == nil {
+		c.enum = append([]interface{}(nil), enum...)
+		return
+	}
+
+	l := []interface{}{}
+	for _, v := range c.enum {
+		for _, e := range enum {
+			if encodeKey(v) == encodeKey(e) {
+				l = append(l, v)
+				break
+			}
+		}
+	}
+	c.enum = l
+}
+
+// allows returns true if values of type `t` are allowed
+func (c *constraints) allows(t schema.PrimitiveType) bool {
+	if c.types == nil {
+		return true
+	}
+	return allowsType(c.types, t)
+}
+
+// pickType picks the type of the value to generate
+func (c *constraints) pickType() schema.PrimitiveType {
+	if c.types != nil {
+		for _, t := range c.types {
+			if t != schema.NullType {
+				return t
+			}
+		}
+		if len(c.types) > 0 {
+			return c.types[0]
+		}
+		return schema.UnspecifiedType
+	}
+
+	// Guess from the keywords that are present
+	switch {
+	case len(c.properties) > 0 || len(c.patternProperties) > 0 || len(c.required) > 0 ||
+		!c.additionalProperties || c.additionalSchemas != nil || c.minProperties > 0 || c.maxProperties >= 0:
+		return schema.ObjectType
+	case c.items != nil || c.tuple != nil || c.additionalItemSchemas != nil ||
+		c.minItems > 0 || c.maxItems >= 0 || c.uniqueItems:
+		return schema.ArrayType
+	case c.patterns != nil || c.format != "" || c.minLength > 0 || c.maxLength >= 0:
+		return schema.StringType
+	case c.minimum != nil || c.maximum != nil || c.multipleOf != nil:
+		return schema.NumberType
+	}
+	return schema.NullType
+}
+
+func (c *constraints) number(integer bool, variant, attempt int, rnd *rand.Rand) (interface{}, error) {
+	if rnd != nil {
+		return c.randomNumber(integer, rnd)
+	}
+
+	step := 1.0
+	if len(c.multipleOf) > 0 {
+		step = c.multipleOf[0]
+	}
+	// Further attempts move by half steps when numbers need not be
+	// integers, so that some of them are not
+	delta := step
+	if !integer && len(c.multipleOf) == 0 {
+		delta = 0.5
+	}
+
+	// Values start at zero, or at the bound that excludes zero, and
+	// move away from that bound for each variant and attempt. Without
+	// such a bound, attempts alternate between both sides of zero
+	var v float64
+	switch {
+	case c.minimum != nil && (*c.minimum > 0 || *c.minimum == 0 && c.exclusiveMinimum):
+		v = c.above(*c.minimum, c.exclusiveMinimum, integer, step) + float64(variant)*step + float64(attempt)*delta
+	case c.maximum != nil && (*c.maximum < 0 || *c.maximum == 0 && c.exclusiveMaximum):
+		v = -c.above(-*c.maximum, c.exclusiveMaximum, integer, step) - float64(variant)*step - float64(attempt)*delta
+	default:
+		v = float64(variant)*step + float64((attempt+1)/2)*delta
+		if attempt%2 == 0 {
+			v = float64(variant)*step - float64(attempt/2)*delta
+		}
+	}
+	if !integer && len(c.multipleOf) == 0 && variant+attempt == 0 && c.minimum != nil && c.maximum != nil && !c.within(v) {
+		v = *c.minimum + (*c.maximum-*c.minimum)/2
+	}
+	return c.checkNumber(v, integer)
+}
+
+// randomNumber returns a random number within minimum and maximum.
+// Numbers without bounds are picked within 1000 of zero, or of the
+// only bound
+func (c *constraints) randomNumber(integer bool, rnd *rand.Rand) (interface{}, error) {
+	lo, hi := -1000.0, 1000.0
+	switch {
+	case c.minimum != nil && c.maximum != nil:
+		lo, hi = *c.minimum, *c.maximum
+	case c.minimum != nil:
+		lo, hi = *c.minimum, *c.minimum+1000
+	case c.maximum != nil:
+		lo, hi = *c.maximum-1000, *c.maximum
+	}
+
+	var step float64
+	switch {
+	case len(c.multipleOf) > 0:
+		step = c.multipleOf[0]
+	case integer:
+		step = 1
+	}
+
+	var v float64
+	if step > 0 {
+		first := math.Ceil(lo / step)
+		if first*step == lo && c.minimum != nil && c.exclusiveMinimum {
+			first++
+		}
+		last := math.Floor(hi / step)
+		if last*step == hi && c.maximum != nil && c.exclusiveMaximum {
+			last--
+		}
+		if last < first {
+			return nil, errors.New("no number satisfies minimum, maximum and multipleOf")
+		}
+		n := math.Min(last-first, 1e6)
+		v = (first + float64(rnd.Int63n(int64(n)+1))) * step
+	} else {
+		v = lo + rnd.Float64()*(hi-lo)
+		if !c.within(v) {
+			v = lo + (hi-lo)/2
+		}
+	}
+	return c.checkNumber(v, integer)
+}
+
+// checkNumber returns `v` if it satisfies the numeric constraints
+func (c *constraints) checkNumber(v float64, integer bool) (interface{}, error) {
+	if !c.within(v) {
+		return nil, errors.New("no number satisfies minimum, maximum and multipleOf")
+	}
+	if integer && v != math.Trunc(v) {
+		return nil, errors.New("no integer satisfies multipleOf")
+	}
+	for _, m := range c.multipleOf {
+		if q := v / m; q != math.Trunc(q) {
+			return nil, errors.New("can not satisfy several values of multipleOf")
+		}
+	}
+	return v, nil
+}
+
+// within returns true if `v` is between minimum and maximum
+func (c *constraints) within(v float64) bool {
+	return !(c.minimum != nil && (v < *c.minimum || v == *c.minimum && c.exclusiveMinimum) ||
+		c.maximum != nil && (v > *c.maximum || v == *c.maximum && c.exclusiveMaximum))
+}
+
+// above returns the smallest convenient value above the lower bound `lo`
+func (c *constraints) above(lo float64, exclusive, integer bool, step float64) float64 {
+	v := lo
+	if integer {
+		v = math.Ceil(v)
+	}
+	if len(c.multipleOf) > 0 {
+		v = math.Ceil(v/step) * step
+	}
+	if v == lo && exclusive {
+		switch {
+		case integer || len(c.multipleOf) > 0:
+			v += step
+		default:
+			// Stay close to the bound, in case there is another one
+			v += 0.5
+		}
+	}
+	return v
+}
+
+var formatExamples = map[schema.Format]string{
+	schema.FormatDateTime: "1970-01-01T00:00:%02dZ",
+	schema.FormatEmail:    "user%d@example.com",
+	schema.FormatHostname: "host%d.example.com",
+	schema.FormatIPv4:     "192.0.2.%d",
+	schema.FormatIPv6:     "2001:db8::%d",
+	schema.FormatURI:      "https://example.com/%d",
+	"uuid":                "00000000-0000-4000-8000-%012d",
+}
+
+// alphabet holds the runes of random strings. It includes some runes
+// outside of ASCII, which take several bytes in UTF-8
+var alphabet = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 -_.éß日本🙂")
+
+func (c *constraints) string(variant int, rnd *rand.Rand) (interface{}, error) {
+	if len(c.patterns) > 0 {
+		return c.patternString(variant, rnd)
+	}
+
+	var s string
+	if f, ok := formatExamples[c.format]; ok {
+		n := variant + 1
+		if rnd != nil {
+			n = rnd.Intn(50) + 1
+		}
+		s = fmt.Sprintf(f, n)
+	} else if rnd != nil {
+		hi := c.minLength + 12
+		if c.maxLength >= 0 && hi > c.maxLength {
+			hi = c.maxLength
+		}
+		if hi < c.minLength {
+			hi = c.minLength
+		}
+		l := make([]rune, c.minLength+rnd.Intn(hi-c.minLength+1))
+		for i := range l {
+			l[i] = alphabet[rnd.Intn(len(alphabet))]
+		}
+		s = string(l)
+	} else {
+		s = "string"
+		if variant > 0 {
+			s += strconv.Itoa(variant)
+		}
+		if c.maxLength >= 0 && utf8.RuneCountInString(s) > c.maxLength {
+			// Short strings are made unique by their content
+			s = strings.Repeat(string(rune('a'+variant%26)), c.maxLength)
+		}
+		for utf8.RuneCountInString(s) < c.minLength {
+			s += "x"
+		}
+	}
+
+	if n := utf8.RuneCountInString(s); n < c.minLength || c.maxLength >= 0 && n > c.maxLength {
+		return nil, errors.Errorf("can not generate %s string within minLength and maxLength", c.format)
+	}
+	return s, nil
+}
+
+// patternString generates a string that matches every pattern, by
+// expanding the first of them with increasing numbers of repetitions,
+// or randomly. Patterns match anywhere within strings unless they are
+// anchored, so short strings are padded on an unanchored side
+func (c *constraints) patternString(variant int, rnd *rand.Rand) (interface{}, error) {
+	re, err := syntax.Parse(c.patterns[0].String(), syntax.Perl)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse pattern")
+	}
+	re = re.Simplify()
+	start, end := anchors(re)
+
+	for rep := variant; rep < variant+32; rep++ {
+		var b strings.Builder
+		e := expander{rep: rep, rnd: rnd}
+		if !e.expand(&b, re) {
+			return nil, errors.Errorf("can not generate a string for pattern %s", c.patterns[0])
+		}
+		s := b.String()
+		n := utf8.RuneCountInString(s)
+		if n < c.minLength && !(start && end) {
+			pad := make([]rune, c.minLength-n)
+			for i := range pad {
+				pad[i] = 'x'
+				if rnd != nil {
+					pad[i] = alphabet[rnd.Intn(len(alphabet))]
+				}
+			}
+			if end {
+				s = string(pad) + s
+			} else {
+				s += string(pad)
+			}
+			n = c.minLength
+		}
+		if c.maxLength >= 0 && n > c.maxLength {
+			if rnd == nil {
+				break
+			}
+			continue
+		}
+		if n < c.minLength {
+			continue
+		}
+
+		ok := true
+		for _, rx := range c.patterns {
+			if !rx.MatchString(s) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return s, nil
+		}
+	}
+	return nil, errors.Errorf("can not generate a string for pattern %s within minLength and maxLength", c.patterns[0])
+}
+
+// anchors returns whether `re` only matches at the start and at the
+// end of strings
+func anchors(re *syntax.Regexp) (start, end bool) {
+	switch re.Op {
+	case syntax.OpBeginText, syntax.OpBeginLine:
+		return true, false
+	case syntax.OpEndText, syntax.OpEndLine:
+		return false, true
+	case syntax.OpCapture:
+		return anchors(re.Sub[0])
+	case syntax.OpConcat:
+		if len(re.Sub) == 0 {
+			return false, false
+		}
+		start, _ = anchors(re.Sub[0])
+		_, end = anchors(re.Sub[len(re.Sub)-1])
+		return start, end
+	case syntax.OpAlternate:
+		start, end = true, true
+		for _, sub := range re.Sub {
+			s, e := anchors(sub)
+			start = start && s
+			end = end && e
+		}
+		return start, end
+	}
+	return false, false
+}
+
+// expander generates strings that match regular expressions. Unbounded
+// repetitions are repeated `rep` times, unless `rnd` is set, in which
+// case every choice is random
+type expander struct {
+	rep int
+	rnd *rand.Rand
+}
+
+// repeat returns how many times a subexpression is repeated, given
+// the bounds of the repetition (a negative `max` means no bound)
+func (e *expander) repeat(min, max int) int {
+	if e.rnd != nil {
+		hi := min + 3
+		if max >= 0 && hi > max {
+			hi = max
+		}
+		if e.rep > 0 && hi < min+e.rep && (max < 0 || min+e.rep <= max) {
+			// Longer strings are needed
+			hi = min + e.rep
+		}
+		return min + e.rnd.Intn(hi-min+1)
+	}
+	n := e.rep
+	if n < min {
+		n = min
+	}
+	if max >= 0 && n > max {
+		n = max
+	}
+	return n
+}
+
+// expand writes a string that matches `re` to `b`
+func (e *expander) expand(b *strings.Builder, re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return false
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			b.WriteRune(r)
+		}
+	case syntax.OpCharClass:
+		r, ok := e.pickRune(re.Rune)
+		if !ok {
+			return false
+		}
+		b.WriteRune(r)
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		r := 'a'
+		if e.rnd != nil {
+			r = alphabet[e.rnd.Intn(len(alphabet))]
+		}
+		b.WriteRune(r)
+	case syntax.OpCapture:
+		return e.expand(b, re.Sub[0])
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		var n int
+		switch re.Op {
+		case syntax.OpStar:
+			n = e.repeat(0, -1)
+		case syntax.OpPlus:
+			n = e.repeat(1, -1)
+		case syntax.OpQuest:
+			n = e.repeat(0, 1)
+		default:
+			n = e.repeat(re.Min, re.Max)
+		}
+		for i := 0; i < n; i++ {
+			if !e.expand(b, re.Sub[0]) {
+				return false
+			}
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if !e.expand(b, sub) {
+				return false
+			}
+		}
+	case syntax.OpAlternate:
+		subs := re.Sub
+		if e.rnd != nil {
+			i := e.rnd.Intn(len(subs))
+			subs = append(append([]*syntax.Regexp{subs[i]}, subs[:i]...), subs[i+1:]...)
+		}
+		for _, sub := range subs {
+			var alt strings.Builder
+			if e.expand(&alt, sub) {
+				b.WriteString(alt.String())
+				return true
+			}
+		}
+		return false
+	}
+	// Anchors, word boundaries and empty matches produce nothing
+	return true
+}
+
+// pickRune picks a rune from the ranges of a character class. Random
+// runes are printable ASCII characters whenever the class has some.
+// Otherwise lowercase letters, uppercase letters, and digits are
+// preferred
+func (e *expander) pickRune(ranges []rune) (rune, bool) {
+	if len(ranges) == 0 {
+		return 0, false
+	}
+	if e.rnd != nil {
+		var printable []rune
+		for i := 0; i+1 < len(ranges); i += 2 {
+			for r := ranges[i]; r <= ranges[i+1] && r <= '~'; r++ {
+				if r >= ' ' {
+					printable = append(printable, r)
+				}
+			}
+		}
+		if len(printable) > 0 {
+			return printable[e.rnd.Intn(len(printable))], true
+		}
+		i := e.rnd.Intn(len(ranges)/2) * 2
+		return ranges[i], true
+	}
+
+	for _, preferred := range []rune{'a', 'A', '0'} {
+		for i := 0; i+1 < len(ranges); i += 2 {
+			lo, hi := ranges[i], ranges[i+1]
+			if lo <= preferred+25 && preferred <= hi {
+				if lo < preferred {
+					return preferred, true
+				}
+				return lo, true
+			}
+		}
+	}
+	for i := 0; i+1 < len(ranges); i += 2 {
+		// Skip control characters when possible
+		if ranges[i+1] >= ' ' {
+			if ranges[i] < ' ' {
+				return ' ', true
+			}
+			return ranges[i], true
+		}
+	}
+	return ranges[0], true
+}
+
+// typeOf returns the JSON type of a decoded value
+func typeOf(v interface{}) schema.PrimitiveType {
+	switch v := v.(type) {
+	case nil:
+		return schema.NullType
+	case bool:
+		return schema.BooleanType
+	case string:
+		return schema.StringType
+	case []interface{}:
+		return schema.ArrayType
+	case map[string]interface{}:
+		return schema.ObjectType
+	case float64:
+		if v == math.Trunc(v) {
+			return schema.IntegerType
+		}
+		return schema.NumberType
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return schema.IntegerType
+		}
+		return schema.NumberType
+	}
+	return schema.UnspecifiedType
+}
+
+// encodeKey returns a string that identifies the value, for comparisons
+func encodeKey(v interface{}) string {
+	buf, _ := json.Marshal(v)
+	return string(buf)
+}
+
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = copyValue(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = copyValue(e)
+		}
+		return l
+	}
+	return v
+}
+
+func sortedKeys(m map[string][]*schema.Schema) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,234 @@
+package instance_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/lestrrat-go/jsschema/instance"
+	"github.com/lestrrat-go/jsschema/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+// generateJSON generates a value for the schema `src`, checks it with
+// the validator, and returns it as JSON
+func generateJSON(t *testing.T, src string, options ...instance.Option) (string, error) {
+	s, err := schema.Read(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to read schema: %s", err)
+	}
+
+	v, err := instance.Generate(s, options...)
+	if err != nil {
+		return "", err
+	}
+	if err := validator.New(s).Validate(v); err != nil {
+		t.Errorf("generated value %#v is invalid: %s", v, err)
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to marshal instance: %s", err)
+	}
+	return string(buf), nil
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		expected string
+	}{
+		{"empty", `{}`, `null`},
+		{"default", `{"type": "string", "default": "hello"}`, `"hello"`},
+		{"enum", `{"enum": ["red", "green"]}`, `"red"`},
+		{"enum filtered by type", `{"type": "integer", "enum": ["one", 2]}`, `2`},
+		{"boolean", `{"type": "boolean"}`, `false`},
+		{"nullable", `{"type": ["null", "integer"]}`, `0`},
+		{"minimum", `{"type": "integer", "minimum": 3}`, `3`},
+		{"exclusiveMinimum", `{"type": "integer", "minimum": 3, "exclusiveMinimum": true}`, `4`},
+		{"number between bounds", `{"type": "number", "minimum": 1, "maximum": 1.2, "exclusiveMinimum": true}`, `1.1`},
+		{"negative maximum", `{"type": "integer", "minimum": -10, "maximum": -5, "multipleOf": 4}`, `-8`},
+		{"multipleOf", `{"type": "integer", "minimum": 10, "multipleOf": 7}`, `14`},
+		{"string", `{"type": "string"}`, `"string"`},
+		{"minLength", `{"type": "string", "minLength": 10}`, `"stringxxxx"`},
+		{"maxLength", `{"type": "string", "maxLength": 3}`, `"aaa"`},
+		{"format", `{"type": "string", "format": "email"}`, `"user1@example.com"`},
+		{"pattern", `{"type": "string", "pattern": "^[A-Z]{2}-\\d+$"}`, `"AA-0"`},
+		{"pattern and minLength", `{"type": "string", "pattern": "^x[a-c]*$", "minLength": 4}`, `"xaaa"`},
+		{"pattern alternation", `{"type": "string", "pattern": "^(foo|bar)baz$"}`, `"foobaz"`},
+		{"unanchored pattern", `{"type": "string", "pattern": "[0-9]{3}", "minLength": 10}`, `"000xxxxxxx"`},
+		{"pattern anchored at end", `{"type": "string", "pattern": "[0-9]{3}$", "minLength": 10}`, `"xxxxxxx000"`},
+		{"not", `{"type": "string", "not": {"enum": ["string", "string1"]}}`, `"string2"`},
+		{"oneOf", `{"oneOf": [{"type": "number", "multipleOf": 5}, {"type": "number", "multipleOf": 3}]}`, `5`},
+		{"tuple", `{"type": "array", "items": [{"type": "string"}, {"type": "integer", "minimum": 1}], "additionalItems": false}`, `["string",1]`},
+		{"minItems", `{"type": "array", "items": {"type": "integer"}, "minItems": 3, "uniqueItems": true}`, `[0,1,2]`},
+		{"object", `{
+  "type": "object",
+  "required": ["id"],
+  "properties": {
+    "id": {"type": "integer", "minimum": 1},
+    "name": {"type": "string"}
+  }
+}`, `{"id":1,"name":"string"}`},
+		{"allOf", `{
+  "allOf": [
+    {"type": "object", "required": ["a"], "properties": {"a": {"type": "integer"}}},
+    {"required": ["b"], "properties": {"a": {"minimum": 5}, "b": {"type": "boolean"}}}
+  ]
+}`, `{"a":5,"b":false}`},
+		{"oneOf", `{"oneOf": [{"type": "string", "minLength": 5, "maxLength": 2}, {"type": "integer"}]}`, `0`},
+		{"ref", `{
+  "definitions": {"positive": {"type": "integer", "minimum": 1}},
+  "type": "array",
+  "items": {"$ref": "#/definitions/positive"}
+}`, `[1]`},
+		{"dependencies", `{
+  "type": "object",
+  "properties": {"card": {"type": "string"}, "billing": {"type": "string", "minLength": 1}},
+  "dependencies": {"card": ["billing"]}
+}`, `{"billing":"string","card":"string"}`},
+		{"minProperties", `{"type": "object", "minProperties": 2, "additionalProperties": {"type": "integer"}}`, `{"property1":0,"property2":0}`},
+		{"invalid default", `{"type": "string", "minLength": 5, "default": "ab"}`, `"string"`},
+		{"default in allOf", `{"allOf": [{"default": 5}, {"type": "integer", "minimum": 10}]}`, `10`},
+		{"invalid enum value", `{"allOf": [{"enum": ["a", "bbbbbb"]}, {"type": "string", "minLength": 3}]}`, `"bbbbbb"`},
+		{"negative not", `{"type": "integer", "not": {"minimum": 0}}`, `-1`},
+		{"oneOf integer and number", `{"oneOf": [{"type": "integer"}, {"type": "number"}]}`, `0.5`},
+		{"dependency not allowed", `{
+  "type": "object",
+  "properties": {"a": {"type": "string"}},
+  "additionalProperties": false,
+  "dependencies": {"a": ["b"]}
+}`, `{}`},
+		{"minProperties with patternProperties", `{
+  "type": "object",
+  "patternProperties": {"^x-[a-z]+$": {"type": "integer"}},
+  "additionalProperties": false,
+  "minProperties": 2
+}`, `{"x-a":0,"x-aa":0}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := generateJSON(t, test.schema)
+			if !assert.NoError(t, err, "instance.Generate should succeed") {
+				return
+			}
+			if !assert.JSONEq(t, test.expected, v, "instance should match") {
+				return
+			}
+		})
+	}
+}
+
+func TestGenerateRequiredOnly(t *testing.T) {
+	v, err := generateJSON(t, `{
+  "type": "object",
+  "required": ["id"],
+  "properties": {
+    "id": {"type": "string", "format": "uuid"},
+    "tags": {"type": "array", "items": {"type": "string"}}
+  }
+}`, instance.WithOptionalProperties(false))
+	if !assert.NoError(t, err, "instance.Generate should succeed") {
+		return
+	}
+	if !assert.JSONEq(t, `{"id":"00000000-0000-4000-8000-000000000001"}`, v, "instance should match") {
+		return
+	}
+}
+
+func TestGenerateRecursive(t *testing.T) {
+	v, err := generateJSON(t, `{
+  "type": "object",
+  "required": ["name"],
+  "properties": {
+    "name": {"type": "string"},
+    "children": {"type": "array", "items": {"$ref": "#"}}
+  }
+}`)
+	if !assert.NoError(t, err, "instance.Generate should succeed for optional recursion") {
+		return
+	}
+	if !assert.Contains(t, v, `"children"`, "instance should contain children") {
+		return
+	}
+
+	_, err = generateJSON(t, `{
+  "type": "object",
+  "required": ["next"],
+  "properties": {"next": {"$ref": "#"}}
+}`)
+	if !assert.Error(t, err, "instance.Generate should fail for required recursion") {
+		return
+	}
+	if !assert.Equal(t, 1, strings.Count(err.Error(), "#/next"), "error should not repeat the context of each level: %s", err) {
+		return
+	}
+}
+
+func TestGenerateUnsatisfiable(t *testing.T) {
+	for _, src := range []string{
+		`{"type": "integer", "minimum": 5, "maximum": 4}`,
+		`{"type": "string", "minLength": 5, "maxLength": 2}`,
+		`{"allOf": [{"type": "string"}, {"type": "integer"}]}`,
+		`{"type": "array", "items": {"type": "boolean"}, "minItems": 3, "uniqueItems": true}`,
+		`{"type": "object", "required": ["a"], "properties": {"a": {}}, "additionalProperties": false, "dependencies": {"a": ["b"]}}`,
+	} {
+		_, err := generateJSON(t, src)
+		if !assert.Error(t, err, "instance.Generate should fail for %s", src) {
+			return
+		}
+	}
+}
+
+// TestGenerateValid checks the values generated for the schemas under
+// test/ with the validator
+func TestGenerateValid(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "test", "*.json"))
+	if !assert.NoError(t, err, "filepath.Glob should succeed") {
+		return
+	}
+
+	for _, file := range files {
+		if strings.Contains(file, "_pass") || strings.Contains(file, "_fail") {
+			continue
+		}
+
+		s, err := schema.ReadFile(file)
+		if !assert.NoError(t, err, "schema.ReadFile(%s) should succeed", file) {
+			return
+		}
+
+		for _, optional := range []bool{true, false} {
+			v, err := instance.Generate(s, instance.WithOptionalProperties(optional))
+			if !assert.NoError(t, err, "instance.Generate(%s) should succeed", file) {
+				return
+			}
+			if !assert.NoError(t, validator.New(s).Validate(v), "value generated for %s should be valid", file) {
+				return
+			}
+		}
+	}
+}
+
+func TestGenerateExcluded(t *testing.T) {
+	// Values may not be found, but those that are must be valid
+	for _, src := range []string{
+		`{"oneOf": [{"type": "string"}, {"type": "string", "minLength": 1}]}`,
+		`{"type": "object", "not": {"required": ["a"]}, "properties": {"a": {"type": "integer"}}}`,
+	} {
+		s, err := schema.Read(strings.NewReader(src))
+		if !assert.NoError(t, err, "schema.Read should succeed") {
+			return
+		}
+		v, err := instance.Generate(s)
+		if err != nil {
+			continue
+		}
+		if !assert.NoError(t, validator.New(s).Validate(v), "value generated for %s should be valid", src) {
+			return
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package schema
 
 import (
+	"encoding/json"
 	"errors"
 	"regexp"
 	"sync"
@@ -24,6 +25,42 @@ var ErrExpectedArrayOfString = errors.New("invalid value: expected array of stri
 // This is here only for backwards compatibility
 var ErrInvalidStringArray = ErrExpectedArrayOfString
 
+// CircularReferenceError is returned when a chain of references
+// leads back to a schema that has already been visited
+type CircularReferenceError struct {
+	Path []string // the references that were followed, in order
+}
+
+// ReferenceError describes a single reference that could not be resolved
+type ReferenceError struct {
+	Pointer   string   // JSON pointer to the schema containing the reference
+	Reference string   // the value of "$ref"
+	Position  Position // location of "$ref" within the source, if available
+	Err       error    // the error encountered while resolving
+}
+
+// ReferenceErrors is a list of ReferenceError
+type ReferenceErrors []*ReferenceError
+
+// Position describes a location within the source document
+// that a schema was read from
+type Position struct {
+	Filename string // name of the file, if known
+	Offset   int    // byte offset, starting at 0
+	Line     int    // line number, starting at 1
+	Column   int    // column number in bytes, starting at 1
+}
+
+// ParseError describes a problem found while parsing a schema
+type ParseError struct {
+	Pointer  string   // JSON pointer to the offending value
+	Position Position // location within the source document, if available
+	Err      error
+}
+
+// ParseErrors is a list of ParseError
+type ParseErrors []*ParseError
+
 // PrimitiveType represents a JSON Schema primitive type such as
 // "string", "integer", etc.
 type PrimitiveType int
@@ -46,8 +83,14 @@ const (
 
 // Number represents a "number" value in a JSON Schema, such as
 // "minimum", "maximum", etc.
+//
+// Val holds the closest float64 to the number. When the number was
+// read from a JSON document, Exact holds the number exactly as it
+// was written, and is used when serializing the schema as long as
+// it still agrees with Val.
 type Number struct {
 	Val         float64
+	Exact       json.Number
 	Initialized bool
 }
 
@@ -84,8 +127,16 @@ type SchemaList []*Schema
 // Schema represents a JSON Schema object
 type Schema struct {
 	parent          *Schema
+	location        []string // reference tokens from parent to this schema
+	position        Position
+	keywordPos      map[string]Position
+	keywords        []string // keywords present in the source, in order
+	roundTrip       bool
+	keepOrder       bool
+	memberOrder     map[string][]string
 	resolveLock     sync.Mutex
 	resolvedSchemas map[string]interface{}
+	ids             map[string]*Schema
 	resolver        *jsref.Resolver
 	ID              string             `json:"id,omitempty"`
 	Title           string             `json:"title,omitempty"`
@@ -0,0 +1,28 @@
+// Package option implements the options that are accepted by the
+// functions of jsschema and of its subpackages.
+package option
+
+// Interface is implemented by every option. Each package declares its
+// own option types on top of it, e.g. schema.ReadOption
+type Interface interface {
+	Name() string
+	Value() interface{}
+}
+
+type option struct {
+	name  string
+	value interface{}
+}
+
+// New creates a new option named `name`, holding `value`
+func New(name string, value interface{}) Interface {
+	return &option{name: name, value: value}
+}
+
+func (o *option) Name() string {
+	return o.name
+}
+
+func (o *option) Value() interface{} {
+	return o.value
+}
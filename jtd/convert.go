@@ -0,0 +1,612 @@
+package jtd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/pkg/errors"
+)
+
+// FromJSONSchema converts the JSON schema `s` into a JTD schema.
+//
+// JTD can only describe a subset of what JSON schemas describe. When
+// `s` uses constructs that JTD cannot represent, such as "pattern",
+// "anyOf", or numeric ranges other than those of the JTD integer types,
+// FromJSONSchema returns a best-effort schema along with an
+// UnsupportedErrors value that lists every such construct, with its
+// location and how the converted schema differs. Constructs are either
+// dropped, or replaced with the closest JTD equivalent: integers are
+// converted to the narrowest integer type that holds their range, or
+// int32 if they have none.
+//
+// Definitions of the root schema become JTD definitions, and references
+// to them become JTD references. Lists of types that include "null",
+// and {"anyOf": [{"type": "null"}, ...]}, become nullable schemas. A
+// "oneOf" whose alternatives are objects that each require a property
+// with a single distinct string value in its enum becomes the
+// discriminator form.
+func FromJSONSchema(s *schema.Schema) (*Schema, error) {
+	c := fromConverter{root: s}
+	j := c.convert(s, "")
+	if s.Definitions != nil {
+		j.Definitions = make(map[string]*Schema, len(s.Definitions))
+		for _, name := range sortedNames(s.Definitions) {
+			j.Definitions[name] = c.convert(s.Definitions[name], "")
+		}
+	}
+
+	if len(c.errors) > 0 {
+		return j, c.errors
+	}
+	return j, nil
+}
+
+type fromConverter struct {
+	root   *schema.Schema
+	errors UnsupportedErrors
+}
+
+func (c *fromConverter) report(s *schema.Schema, keyword, reason string, args ...interface{}) {
+	c.errors = append(c.errors, &UnsupportedError{
+		Pointer: s.Pointer(),
+		Keyword: keyword,
+		Reason:  fmt.Sprintf(reason, args...),
+	})
+}
+
+// convert converts `s`. If `tag` is not empty, it names the property
+// used as discriminator, which is left out of the converted object.
+func (c *fromConverter) convert(s *schema.Schema, tag string) *Schema {
+	j := &Schema{}
+	if s.Description != "" || s.Title != "" {
+		j.Metadata = make(map[string]interface{})
+		if s.Title != "" {
+			j.Metadata["title"] = s.Title
+		}
+		if s.Description != "" {
+			j.Metadata["description"] = s.Description
+		}
+	}
+
+	if s != c.root && s.Definitions != nil {
+		c.report(s, "definitions", "definitions are only allowed in the root schema, and are dropped")
+	}
+
+	if s.Reference != "" {
+		name, ok := c.definition(s.Reference)
+		if !ok {
+			c.report(s, "$ref", "only references to definitions of the root schema can be represented, and %q is dropped", s.Reference)
+			return j
+		}
+		j.Ref = name
+		return j
+	}
+
+	for _, keyword := range []struct {
+		name    string
+		present bool
+	}{
+		{"allOf", len(s.AllOf) > 0},
+		{"not", s.Not != nil},
+	} {
+		if keyword.present {
+			c.report(s, keyword.name, "the keyword is dropped")
+		}
+	}
+
+	if len(s.OneOf) > 0 {
+		if c.discriminator(j, s) {
+			return j
+		}
+		c.report(s, "oneOf", "alternatives can only be represented as the mapping of a discriminator, and are dropped")
+	}
+	if len(s.AnyOf) > 0 {
+		if alt := nullableAlternative(s.AnyOf); alt != nil {
+			n := c.convert(alt, tag)
+			n.Metadata = mergeMetadata(j.Metadata, n.Metadata)
+			n.Nullable = true
+			return n
+		}
+		c.report(s, "anyOf", "only nullable schemas can be represented, and the alternatives are dropped")
+	}
+
+	types := guessTypes(s)
+	var nonNull []schema.PrimitiveType
+	for _, t := range types {
+		if t == schema.NullType {
+			j.Nullable = true
+			continue
+		}
+		nonNull = append(nonNull, t)
+	}
+
+	if len(s.Enum) > 0 && c.enum(j, s) {
+		return j
+	}
+
+	switch len(nonNull) {
+	case 0:
+		if len(types) > 0 {
+			c.report(s, "type", "values that are always null cannot be represented, and the schema accepts anything")
+		}
+		return j
+	case 1:
+	default:
+		c.report(s, "type", "unions of types cannot be represented, and the schema accepts anything")
+		return j
+	}
+
+	switch nonNull[0] {
+	case schema.BooleanType:
+		j.Type = TypeBoolean
+	case schema.StringType:
+		c.string(j, s)
+	case schema.NumberType:
+		j.Type = TypeFloat64
+		for _, keyword := range []struct {
+			name string
+			n    schema.Number
+		}{{"minimum", s.Minimum}, {"maximum", s.Maximum}, {"multipleOf", s.MultipleOf}} {
+			if keyword.n.Initialized {
+				c.report(s, keyword.name, "numbers are converted to float64, and the keyword is dropped")
+			}
+		}
+	case schema.IntegerType:
+		c.integer(j, s)
+	case schema.ArrayType:
+		c.array(j, s)
+	case schema.ObjectType:
+		c.object(j, s, tag)
+	}
+	return j
+}
+
+// sortedNames returns the keys of `m` in order, so that unsupported
+// constructs are always reported in the same order
+func sortedNames(m map[string]*schema.Schema) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// definition returns the name of the definition of the root schema
+// that `ref` points to, if any
+func (c *fromConverter) definition(ref string) (string, bool) {
+	if !strings.HasPrefix(ref, "#") {
+		return "", false
+	}
+	// Lookup unescapes the reference like Resolve does
+	target, err := c.root.Lookup(ref)
+	if err != nil {
+		return "", false
+	}
+	for name, def := range c.root.Definitions {
+		if def == target {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// deref follows the reference of `s`, if any
+func (c *fromConverter) deref(s *schema.Schema) *schema.Schema {
+	if s.Reference == "" {
+		return s
+	}
+	if t, err := s.Resolve(nil); err == nil {
+		return t
+	}
+	return nil
+}
+
+// guessTypes returns the types of `s`, or the type implied by its
+// keywords if it lists none
+func guessTypes(s *schema.Schema) schema.PrimitiveTypes {
+	if len(s.Type) > 0 {
+		return s.Type
+	}
+	switch {
+	case len(s.Properties) > 0 || len(s.Required) > 0 || s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil:
+		return schema.PrimitiveTypes{schema.ObjectType}
+	case s.Items != nil:
+		return schema.PrimitiveTypes{schema.ArrayType}
+	}
+	return nil
+}
+
+// nullableAlternative returns X if `l` is [{"type": "null"}, X], in
+// any order
+func nullableAlternative(l schema.SchemaList) *schema.Schema {
+	if len(l) != 2 {
+		return nil
+	}
+	for i, s := range l {
+		if len(s.Type) == 1 && s.Type[0] == schema.NullType && s.Reference == "" {
+			return l[1-i]
+		}
+	}
+	return nil
+}
+
+func mergeMetadata(outer, inner map[string]interface{}) map[string]interface{} {
+	if len(outer) == 0 {
+		return inner
+	}
+	m := make(map[string]interface{}, len(outer)+len(inner))
+	for k, v := range inner {
+		m[k] = v
+	}
+	for k, v := range outer {
+		m[k] = v
+	}
+	return m
+}
+
+// enum converts the enum of `s`, and returns true if it could
+func (c *fromConverter) enum(j *Schema, s *schema.Schema) bool {
+	var values []string
+	for _, v := range s.Enum {
+		switch v := v.(type) {
+		case nil:
+			j.Nullable = true
+		case string:
+			values = append(values, v)
+		default:
+			c.report(s, "enum", "only strings can be enumerated, and the values are dropped")
+			return false
+		}
+	}
+	if len(values) == 0 {
+		c.report(s, "enum", "values that are always null cannot be represented, and the schema accepts anything")
+		return true
+	}
+	j.Enum = values
+	return true
+}
+
+func (c *fromConverter) string(j *Schema, s *schema.Schema) {
+	j.Type = TypeString
+	switch s.Format {
+	case "":
+	case schema.FormatDateTime:
+		j.Type = TypeTimestamp
+	default:
+		c.report(s, "format", "only date-time can be represented, and the format is dropped")
+	}
+
+	if s.MinLength.Initialized {
+		c.report(s, "minLength", "the keyword is dropped")
+	}
+	if s.MaxLength.Initialized {
+		c.report(s, "maxLength", "the keyword is dropped")
+	}
+	if s.Pattern != nil {
+		c.report(s, "pattern", "the keyword is dropped")
+	}
+}
+
+func (c *fromConverter) integer(j *Schema, s *schema.Schema) {
+	if s.MultipleOf.Initialized {
+		c.report(s, "multipleOf", "the keyword is dropped")
+	}
+
+	lo, hi := math.Inf(-1), math.Inf(1)
+	if s.Minimum.Initialized {
+		lo = math.Ceil(s.Minimum.Val)
+		if s.ExclusiveMinimum.Bool() && lo == s.Minimum.Val {
+			lo++
+		}
+	}
+	if s.Maximum.Initialized {
+		hi = math.Floor(s.Maximum.Val)
+		if s.ExclusiveMaximum.Bool() && hi == s.Maximum.Val {
+			hi--
+		}
+	}
+
+	// The narrowest type that holds the range, if any
+	r := intRange{typ: TypeInt32, min: math.MinInt32, max: math.MaxInt32}
+	found := false
+	for _, v := range intRanges {
+		if v.min <= lo && hi <= v.max {
+			r, found = v, true
+			break
+		}
+	}
+	if !found && lo >= 0 {
+		r, _ = TypeUint32.intRange()
+	}
+	j.Type = r.typ
+
+	if !found {
+		c.report(s, "type", "integers beyond the range of %s cannot be represented", r.typ)
+	}
+	if s.Minimum.Initialized && lo > r.min {
+		c.report(s, "minimum", "the range is widened to that of %s", r.typ)
+	}
+	if s.Maximum.Initialized && hi < r.max {
+		c.report(s, "maximum", "the range is widened to that of %s", r.typ)
+	}
+}
+
+func (c *fromConverter) array(j *Schema, s *schema.Schema) {
+	j.Elements = &Schema{}
+	if items := s.Items; items != nil && len(items.Schemas) > 0 {
+		if items.TupleMode {
+			c.report(s, "items", "tuples cannot be represented, and the items accept anything")
+		} else {
+			j.Elements = c.convert(items.Schemas[0], "")
+		}
+	}
+
+	if s.MinItems.Initialized {
+		c.report(s, "minItems", "the keyword is dropped")
+	}
+	if s.MaxItems.Initialized {
+		c.report(s, "maxItems", "the keyword is dropped")
+	}
+	if s.UniqueItems.Bool() {
+		c.report(s, "uniqueItems", "the keyword is dropped")
+	}
+}
+
+func (c *fromConverter) object(j *Schema, s *schema.Schema, tag string) {
+	for _, keyword := range []struct {
+		name    string
+		present bool
+	}{
+		{"minProperties", s.MinProperties.Initialized},
+		{"maxProperties", s.MaxProperties.Initialized},
+		{"patternProperties", len(s.PatternProperties) > 0},
+		{"dependencies", len(s.Dependencies.Names) > 0 || len(s.Dependencies.Schemas) > 0},
+	} {
+		if keyword.present {
+			c.report(s, keyword.name, "the keyword is dropped")
+		}
+	}
+
+	ap := s.AdditionalProperties
+	if len(s.Properties) == 0 && len(s.Required) == 0 && tag == "" && ap != nil {
+		// Objects with arbitrary names are maps
+		j.Values = &Schema{}
+		if ap.Schema != nil {
+			j.Values = c.convert(ap.Schema, "")
+		}
+		return
+	}
+
+	j.Properties = make(map[string]*Schema)
+	for _, name := range s.Required {
+		if name == tag {
+			continue
+		}
+		j.Properties[name] = &Schema{}
+		if prop, ok := s.Properties[name]; ok {
+			j.Properties[name] = c.convert(prop, "")
+		}
+	}
+	for _, name := range sortedNames(s.Properties) {
+		if _, ok := j.Properties[name]; ok || name == tag {
+			continue
+		}
+		if j.OptionalProperties == nil {
+			j.OptionalProperties = make(map[string]*Schema)
+		}
+		j.OptionalProperties[name] = c.convert(s.Properties[name], "")
+	}
+
+	j.AdditionalProperties = ap != nil
+	if ap != nil && ap.Schema != nil {
+		c.report(s, "additionalProperties", "schemas of additional properties cannot be represented next to properties, and any value is allowed")
+	}
+}
+
+// discriminator converts the alternatives of the "oneOf" of `s` into
+// the discriminator form, and returns true if it could
+func (c *fromConverter) discriminator(j *Schema, s *schema.Schema) bool {
+	if len(s.Properties) > 0 {
+		return false
+	}
+
+	alts := make([]*schema.Schema, len(s.OneOf))
+	for i, v := range s.OneOf {
+		if alts[i] = c.deref(v); alts[i] == nil {
+			return false
+		}
+	}
+
+	// Find a property that every alternative requires, with a single
+	// string value that differs between the alternatives
+	var tag string
+	for _, name := range alts[0].Required {
+		ok := true
+		seen := make(map[string]struct{})
+		for _, alt := range alts {
+			v, valid := tagValue(alt, name)
+			if _, dup := seen[v]; !valid || dup {
+				ok = false
+				break
+			}
+			seen[v] = struct{}{}
+		}
+		if ok {
+			tag = name
+			break
+		}
+	}
+	if tag == "" {
+		return false
+	}
+
+	j.Discriminator = tag
+	j.Mapping = make(map[string]*Schema, len(alts))
+	for _, alt := range alts {
+		v, _ := tagValue(alt, tag)
+		m := c.convert(alt, tag)
+		if m.Properties == nil {
+			// Mappings must use the properties form
+			m.Properties = make(map[string]*Schema)
+			if m.Values != nil {
+				c.report(alt, "additionalProperties", "mappings of a discriminator cannot be maps, and any value is allowed")
+				m.Values = nil
+				m.AdditionalProperties = true
+			}
+		}
+		m.Nullable = false
+		j.Mapping[v] = m
+	}
+	return true
+}
+
+// tagValue returns the single value allowed for the required property
+// `name` of the object `s`, if any
+func tagValue(s *schema.Schema, name string) (string, bool) {
+	if !s.IsPropRequired(name) {
+		return "", false
+	}
+	types := guessTypes(s)
+	if len(types) != 1 || types[0] != schema.ObjectType {
+		return "", false
+	}
+	prop, ok := s.Properties[name]
+	if !ok || len(prop.Enum) != 1 {
+		return "", false
+	}
+	v, ok := prop.Enum[0].(string)
+	return v, ok
+}
+
+// ToJSONSchema converts the JTD schema `j` into a JSON schema. Every
+// JTD schema can be represented: integer types become integers with
+// the range of the type, timestamps become strings with the date-time
+// format, and the discriminator form becomes a "oneOf" of objects that
+// each require the discriminator property with a single value.
+func ToJSONSchema(j *Schema) (*schema.Schema, error) {
+	if err := j.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid JTD schema")
+	}
+
+	m := toMap(j, "", "")
+	if j.Definitions != nil {
+		defs := make(map[string]interface{}, len(j.Definitions))
+		for name, def := range j.Definitions {
+			defs[name] = toMap(def, "", "")
+		}
+		m["definitions"] = defs
+	}
+
+	buf, err := json.Marshal(m)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode converted schema")
+	}
+	return schema.Read(bytes.NewReader(buf))
+}
+
+// toMap converts `j` into a JSON schema, as decoded by encoding/json.
+// If `tag` is not empty, the object is a mapping of a discriminator,
+// which must have the property `tag` set to `value`.
+func toMap(j *Schema, tag, value string) map[string]interface{} {
+	m := make(map[string]interface{})
+	for _, k := range []string{"title", "description"} {
+		if v, ok := j.Metadata[k].(string); ok {
+			m[k] = v
+		}
+	}
+
+	switch j.form() {
+	case "ref":
+		m["$ref"] = "#/definitions/" + escapeToken(j.Ref)
+	case "type":
+		switch j.Type {
+		case TypeBoolean, TypeString:
+			m["type"] = string(j.Type)
+		case TypeTimestamp:
+			m["type"] = "string"
+			m["format"] = string(schema.FormatDateTime)
+		case TypeFloat32, TypeFloat64:
+			m["type"] = "number"
+		default:
+			r, _ := j.Type.intRange()
+			m["type"] = "integer"
+			m["minimum"] = r.min
+			m["maximum"] = r.max
+		}
+	case "enum":
+		m["type"] = "string"
+		enum := make([]interface{}, len(j.Enum))
+		for i, v := range j.Enum {
+			enum[i] = v
+		}
+		m["enum"] = enum
+	case "elements":
+		m["type"] = "array"
+		m["items"] = toMap(j.Elements, "", "")
+	case "properties":
+		props := make(map[string]interface{})
+		required := []string{}
+		for _, name := range sortedKeys(j.Properties) {
+			props[name] = toMap(j.Properties[name], "", "")
+			required = append(required, name)
+		}
+		for name, prop := range j.OptionalProperties {
+			props[name] = toMap(prop, "", "")
+		}
+		if tag != "" {
+			props[tag] = map[string]interface{}{"type": "string", "enum": []interface{}{value}}
+			required = append(required, tag)
+		}
+		m["type"] = "object"
+		m["properties"] = props
+		if len(required) > 0 {
+			m["required"] = required
+		}
+		m["additionalProperties"] = j.AdditionalProperties
+	case "values":
+		m["type"] = "object"
+		m["additionalProperties"] = toMap(j.Values, "", "")
+	case "discriminator":
+		alts := make([]interface{}, 0, len(j.Mapping))
+		for _, k := range sortedKeys(j.Mapping) {
+			alts = append(alts, toMap(j.Mapping[k], j.Discriminator, k))
+		}
+		m["type"] = "object"
+		m["required"] = []string{j.Discriminator}
+		m["oneOf"] = alts
+	}
+
+	if !j.Nullable {
+		return m
+	}
+	switch j.form() {
+	case "empty":
+		return m
+	case "ref", "discriminator":
+		wrapper := make(map[string]interface{})
+		for _, k := range []string{"title", "description"} {
+			if v, ok := m[k]; ok {
+				wrapper[k] = v
+				delete(m, k)
+			}
+		}
+		wrapper["anyOf"] = []interface{}{map[string]interface{}{"type": "null"}, m}
+		return wrapper
+	}
+	m["type"] = []interface{}{m["type"], "null"}
+	if enum, ok := m["enum"].([]interface{}); ok {
+		m["enum"] = append(enum, nil)
+	}
+	return m
+}
+
+// escapeToken escapes `s` to be used as a JSON pointer reference token
+func escapeToken(s string) string {
+	return strings.TrimPrefix(schema.Pointer("").Append(s).String(), "/")
+}
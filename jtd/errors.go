@@ -0,0 +1,31 @@
+package jtd
+
+import (
+	"strconv"
+	"strings"
+)
+
+// UnsupportedError describes a construct of a JSON schema that cannot
+// be represented in JTD
+type UnsupportedError struct {
+	Pointer string // JSON pointer to the schema containing the construct
+	Keyword string // the keyword of the construct, e.g. "pattern"
+	Reason  string // how the converted schema differs
+}
+
+// UnsupportedErrors is a list of UnsupportedError
+type UnsupportedErrors []*UnsupportedError
+
+// Error returns the string representation of the error
+func (e *UnsupportedError) Error() string {
+	return strconv.Quote(e.Keyword) + " at " + e.Pointer + " cannot be represented: " + e.Reason
+}
+
+// Error returns the string representation of the error
+func (l UnsupportedErrors) Error() string {
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return strconv.Itoa(len(l)) + " construct(s) cannot be represented in JTD: " + strings.Join(msgs, ", ")
+}
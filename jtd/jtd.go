@@ -0,0 +1,366 @@
+// Package jtd converts between JSON schemas and JSON Type Definition
+// schemas, as described in RFC 8927.
+package jtd
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Schema is a JSON Type Definition schema. Exactly one of the forms
+// described in RFC 8927 is used: the empty form, where no field other
+// than Definitions, Metadata and Nullable is set, or the "ref", "type",
+// "enum", "elements", "properties", "values" or "discriminator" form.
+type Schema struct {
+	Definitions map[string]*Schema
+	Metadata    map[string]interface{}
+	Nullable    bool
+
+	Ref  string
+	Type Type
+	Enum []string
+
+	Elements *Schema
+
+	// Properties and OptionalProperties are non-nil in the properties
+	// form, even if they are empty
+	Properties           map[string]*Schema
+	OptionalProperties   map[string]*Schema
+	AdditionalProperties bool
+
+	Values *Schema
+
+	Discriminator string
+	Mapping       map[string]*Schema
+}
+
+// Type is one of the types of the "type" form
+type Type string
+
+// The types of the "type" form
+const (
+	TypeBoolean   Type = "boolean"
+	TypeString    Type = "string"
+	TypeTimestamp Type = "timestamp"
+	TypeFloat32   Type = "float32"
+	TypeFloat64   Type = "float64"
+	TypeInt8      Type = "int8"
+	TypeUint8     Type = "uint8"
+	TypeInt16     Type = "int16"
+	TypeUint16    Type = "uint16"
+	TypeInt32     Type = "int32"
+	TypeUint32    Type = "uint32"
+)
+
+// intRange is the range of values of an integer type
+type intRange struct {
+	typ      Type
+	min, max float64
+}
+
+// intRanges lists the integer types, from the narrowest
+var intRanges = []intRange{
+	{TypeUint8, 0, 255},
+	{TypeInt8, -128, 127},
+	{TypeUint16, 0, 65535},
+	{TypeInt16, -32768, 32767},
+	{TypeUint32, 0, 4294967295},
+	{TypeInt32, -2147483648, 2147483647},
+}
+
+func (t Type) valid() bool {
+	switch t {
+	case TypeBoolean, TypeString, TypeTimestamp, TypeFloat32, TypeFloat64:
+		return true
+	}
+	_, ok := t.intRange()
+	return ok
+}
+
+// intRange returns the range of values of `t`, if it is an integer type
+func (t Type) intRange() (intRange, bool) {
+	for _, r := range intRanges {
+		if r.typ == t {
+			return r, true
+		}
+	}
+	return intRange{}, false
+}
+
+// ReadFile reads a JTD schema from the file `name`. See Read.
+func ReadFile(name string) (*Schema, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %s", name)
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read reads a JTD schema from `in`. The schema must be valid as
+// described in RFC 8927: each schema uses a single form, and references
+// point to existing definitions.
+func Read(in io.Reader) (*Schema, error) {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read JTD schema")
+	}
+
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Validate checks that `s` is a valid root schema: that references
+// point to its definitions, and that the schemas of discriminators
+// are valid mappings.
+func (s *Schema) Validate() error {
+	return s.validate(s, "#", true)
+}
+
+func (s *Schema) validate(root *Schema, ptr string, isRoot bool) error {
+	if !isRoot && s.Definitions != nil {
+		return errors.Errorf("definitions at %s are only allowed in the root schema", ptr)
+	}
+
+	switch s.form() {
+	case "":
+		return errors.Errorf("schema at %s mixes several forms", ptr)
+	case "ref":
+		if _, ok := root.Definitions[s.Ref]; !ok {
+			return errors.Errorf("reference %q at %s does not point to a definition", s.Ref, ptr)
+		}
+	case "type":
+		if !s.Type.valid() {
+			return errors.Errorf("invalid type %q at %s", s.Type, ptr)
+		}
+	case "enum":
+		if len(s.Enum) == 0 {
+			return errors.Errorf("enum at %s is empty", ptr)
+		}
+		seen := make(map[string]struct{}, len(s.Enum))
+		for _, v := range s.Enum {
+			if _, ok := seen[v]; ok {
+				return errors.Errorf("enum at %s contains %q more than once", ptr, v)
+			}
+			seen[v] = struct{}{}
+		}
+	case "properties":
+		for name := range s.OptionalProperties {
+			if _, ok := s.Properties[name]; ok {
+				return errors.Errorf("property %q at %s is both required and optional", name, ptr)
+			}
+		}
+	case "discriminator":
+		if s.Discriminator == "" || s.Mapping == nil {
+			return errors.Errorf("discriminator at %s requires both discriminator and mapping", ptr)
+		}
+		for _, k := range sortedKeys(s.Mapping) {
+			m := s.Mapping[k]
+			mptr := ptr + "/mapping/" + escapeToken(k)
+			if m.form() != "properties" {
+				return errors.Errorf("mapping at %s must use the properties form", mptr)
+			}
+			if m.Nullable {
+				return errors.Errorf("mapping at %s must not be nullable", mptr)
+			}
+			_, required := m.Properties[s.Discriminator]
+			_, optional := m.OptionalProperties[s.Discriminator]
+			if required || optional {
+				return errors.Errorf("mapping at %s must not define the discriminator %q", mptr, s.Discriminator)
+			}
+		}
+	}
+
+	for _, sub := range s.subschemas(ptr) {
+		if err := sub.schema.validate(root, sub.ptr, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type subschema struct {
+	schema *Schema
+	ptr    string
+}
+
+// subschemas lists the schemas directly contained in `s`
+func (s *Schema) subschemas(ptr string) []subschema {
+	var l []subschema
+	for _, k := range sortedKeys(s.Definitions) {
+		l = append(l, subschema{s.Definitions[k], ptr + "/definitions/" + escapeToken(k)})
+	}
+	if s.Elements != nil {
+		l = append(l, subschema{s.Elements, ptr + "/elements"})
+	}
+	for _, k := range sortedKeys(s.Properties) {
+		l = append(l, subschema{s.Properties[k], ptr + "/properties/" + escapeToken(k)})
+	}
+	for _, k := range sortedKeys(s.OptionalProperties) {
+		l = append(l, subschema{s.OptionalProperties[k], ptr + "/optionalProperties/" + escapeToken(k)})
+	}
+	if s.Values != nil {
+		l = append(l, subschema{s.Values, ptr + "/values"})
+	}
+	for _, k := range sortedKeys(s.Mapping) {
+		l = append(l, subschema{s.Mapping[k], ptr + "/mapping/" + escapeToken(k)})
+	}
+	return l
+}
+
+// form returns the name of the form of `s`: "empty", "ref", "type",
+// "enum", "elements", "properties", "values" or "discriminator". It
+// returns an empty string if `s` mixes several forms.
+func (s *Schema) form() string {
+	var forms []string
+	if s.Ref != "" {
+		forms = append(forms, "ref")
+	}
+	if s.Type != "" {
+		forms = append(forms, "type")
+	}
+	if s.Enum != nil {
+		forms = append(forms, "enum")
+	}
+	if s.Elements != nil {
+		forms = append(forms, "elements")
+	}
+	if s.Properties != nil || s.OptionalProperties != nil || s.AdditionalProperties {
+		forms = append(forms, "properties")
+	}
+	if s.Values != nil {
+		forms = append(forms, "values")
+	}
+	if s.Discriminator != "" || s.Mapping != nil {
+		forms = append(forms, "discriminator")
+	}
+
+	switch len(forms) {
+	case 0:
+		return "empty"
+	case 1:
+		return forms[0]
+	}
+	return ""
+}
+
+// MarshalJSON serializes the schema as JSON
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{})
+	if s.Definitions != nil {
+		m["definitions"] = s.Definitions
+	}
+	if len(s.Metadata) > 0 {
+		m["metadata"] = s.Metadata
+	}
+	if s.Nullable {
+		m["nullable"] = true
+	}
+	if s.Ref != "" {
+		m["ref"] = s.Ref
+	}
+	if s.Type != "" {
+		m["type"] = s.Type
+	}
+	if s.Enum != nil {
+		m["enum"] = s.Enum
+	}
+	if s.Elements != nil {
+		m["elements"] = s.Elements
+	}
+	if s.Properties != nil {
+		m["properties"] = s.Properties
+	}
+	if s.OptionalProperties != nil {
+		m["optionalProperties"] = s.OptionalProperties
+	}
+	if s.AdditionalProperties {
+		m["additionalProperties"] = true
+	}
+	if s.Values != nil {
+		m["values"] = s.Values
+	}
+	if s.Discriminator != "" {
+		m["discriminator"] = s.Discriminator
+	}
+	if s.Mapping != nil {
+		m["mapping"] = s.Mapping
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON parses a JSON document into the schema. Unknown
+// keywords are rejected, as RFC 8927 requires.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return errors.Wrap(err, "failed to decode JTD schema")
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	*s = Schema{}
+	for _, k := range keys {
+		var dst interface{}
+		switch k {
+		case "definitions":
+			dst = &s.Definitions
+		case "metadata":
+			dst = &s.Metadata
+		case "nullable":
+			dst = &s.Nullable
+		case "ref":
+			dst = &s.Ref
+		case "type":
+			dst = &s.Type
+		case "enum":
+			dst = &s.Enum
+		case "elements":
+			dst = &s.Elements
+		case "properties":
+			dst = &s.Properties
+		case "optionalProperties":
+			dst = &s.OptionalProperties
+		case "additionalProperties":
+			dst = &s.AdditionalProperties
+		case "values":
+			dst = &s.Values
+		case "discriminator":
+			dst = &s.Discriminator
+		case "mapping":
+			dst = &s.Mapping
+		default:
+			return errors.Errorf("unknown keyword %s", strconv.Quote(k))
+		}
+		if err := json.Unmarshal(m[k], dst); err != nil {
+			return errors.Wrapf(err, "failed to decode %s", strconv.Quote(k))
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys(m map[string]*Schema) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
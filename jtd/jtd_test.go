@@ -0,0 +1,265 @@
+package jtd_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/lestrrat-go/jsschema/jtd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromJSONSchema(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(`{
+  "type": "object",
+  "description": "A pet store",
+  "required": ["name", "pets"],
+  "additionalProperties": false,
+  "properties": {
+    "name": {"type": "string"},
+    "opened": {"type": "string", "format": "date-time"},
+    "rating": {"type": ["integer", "null"], "minimum": 0, "maximum": 255},
+    "status": {"enum": ["open", "closed", null]},
+    "pets": {"type": "array", "items": {"$ref": "#/definitions/pet"}},
+    "tags": {"type": "object", "additionalProperties": {"type": "boolean"}},
+    "owner": {"anyOf": [{"type": "null"}, {"$ref": "#/definitions/person"}]}
+  },
+  "definitions": {
+    "pet": {"oneOf": [{"$ref": "#/definitions/cat"}, {"$ref": "#/definitions/dog"}]},
+    "cat": {
+      "type": "object",
+      "required": ["kind"],
+      "properties": {"kind": {"enum": ["cat"]}, "lives": {"type": "integer", "minimum": -128, "maximum": 127}}
+    },
+    "dog": {
+      "type": "object",
+      "required": ["kind", "breed"],
+      "additionalProperties": false,
+      "properties": {"kind": {"type": "string", "enum": ["dog"]}, "breed": {"type": "string"}}
+    },
+    "person": {"type": "object", "properties": {"weight": {"type": "number"}}}
+  }
+}`))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	j, err := jtd.FromJSONSchema(s)
+	if !assert.NoError(t, err, "FromJSONSchema should succeed") {
+		return
+	}
+
+	buf, err := json.Marshal(j)
+	if !assert.NoError(t, err, "json.Marshal should succeed") {
+		return
+	}
+	expected := `{
+  "metadata": {"description": "A pet store"},
+  "properties": {
+    "name": {"type": "string"},
+    "pets": {"elements": {"ref": "pet"}}
+  },
+  "optionalProperties": {
+    "opened": {"type": "timestamp"},
+    "rating": {"type": "uint8", "nullable": true},
+    "status": {"enum": ["open", "closed"], "nullable": true},
+    "tags": {"values": {"type": "boolean"}},
+    "owner": {"ref": "person", "nullable": true}
+  },
+  "definitions": {
+    "pet": {
+      "discriminator": "kind",
+      "mapping": {
+        "cat": {"optionalProperties": {"lives": {"type": "int8"}}, "properties": {}, "additionalProperties": true},
+        "dog": {"properties": {"breed": {"type": "string"}}}
+      }
+    },
+    "cat": {"properties": {"kind": {"enum": ["cat"]}}, "optionalProperties": {"lives": {"type": "int8"}}, "additionalProperties": true},
+    "dog": {"properties": {"kind": {"enum": ["dog"]}, "breed": {"type": "string"}}},
+    "person": {"properties": {}, "optionalProperties": {"weight": {"type": "float64"}}, "additionalProperties": true}
+  }
+}`
+	if !assert.JSONEq(t, expected, string(buf), "converted schema should match") {
+		return
+	}
+}
+
+func TestFromJSONSchemaUnsupported(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(`{
+  "type": "object",
+  "required": ["code"],
+  "properties": {
+    "code": {"type": "string", "pattern": "^[A-Z]+$"},
+    "id": {"anyOf": [{"type": "string"}, {"type": "integer"}]},
+    "price": {"type": "number", "minimum": 0},
+    "count": {"type": "integer", "minimum": 1, "maximum": 10},
+    "total": {"type": "integer"},
+    "tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true}
+  }
+}`))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	j, err := jtd.FromJSONSchema(s)
+	if !assert.Error(t, err, "FromJSONSchema should fail") {
+		return
+	}
+	if !assert.NotNil(t, j, "a best-effort schema should be returned") {
+		return
+	}
+
+	l, ok := err.(jtd.UnsupportedErrors)
+	if !assert.True(t, ok, "error should be UnsupportedErrors") {
+		return
+	}
+	var found []string
+	for _, e := range l {
+		found = append(found, e.Pointer+" "+e.Keyword)
+	}
+	expected := []string{
+		"#/properties/code pattern",
+		"#/properties/count minimum",
+		"#/properties/count maximum",
+		"#/properties/id anyOf",
+		"#/properties/price minimum",
+		"#/properties/tags uniqueItems",
+		"#/properties/total type",
+	}
+	if !assert.Equal(t, expected, found, "unsupported constructs should be reported in order") {
+		return
+	}
+
+	buf, _ := json.Marshal(j.OptionalProperties["count"])
+	if !assert.JSONEq(t, `{"type": "uint8"}`, string(buf), "integers should use the narrowest type") {
+		return
+	}
+	buf, _ = json.Marshal(j.OptionalProperties["total"])
+	if !assert.JSONEq(t, `{"type": "int32"}`, string(buf), "unbounded integers should use int32") {
+		return
+	}
+}
+
+func TestFromJSONSchemaEscapedRef(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(`{
+  "type": "object",
+  "required": ["a", "b"],
+  "properties": {
+    "a": {"$ref": "#/definitions/a%20b"},
+    "b": {"$ref": "#/definitions/c~1d"}
+  },
+  "definitions": {
+    "a b": {"type": "string"},
+    "c/d": {"type": "boolean"}
+  }
+}`))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	j, err := jtd.FromJSONSchema(s)
+	if !assert.NoError(t, err, "FromJSONSchema should succeed") {
+		return
+	}
+
+	buf, err := json.Marshal(j.Properties)
+	if !assert.NoError(t, err, "json.Marshal should succeed") {
+		return
+	}
+	if !assert.JSONEq(t, `{"a": {"ref": "a b"}, "b": {"ref": "c/d"}}`, string(buf), "references should be unescaped") {
+		return
+	}
+}
+
+func TestToJSONSchema(t *testing.T) {
+	src := `{
+  "metadata": {"description": "An event"},
+  "properties": {
+    "id": {"type": "uint32"},
+    "at": {"type": "timestamp"},
+    "level": {"enum": ["info", "error"], "nullable": true},
+    "payload": {
+      "discriminator": "kind",
+      "mapping": {
+        "click": {"properties": {"x": {"type": "int16"}, "y": {"type": "int16"}}},
+        "key": {"properties": {"code": {"type": "string"}}, "optionalProperties": {"shift": {"type": "boolean"}}}
+      }
+    },
+    "labels": {"values": {"type": "string"}},
+    "parent": {"ref": "event", "nullable": true},
+    "extra": {}
+  },
+  "optionalProperties": {
+    "scores": {"elements": {"type": "float64"}}
+  },
+  "definitions": {
+    "event": {"properties": {"id": {"type": "uint32"}}}
+  }
+}`
+	j, err := jtd.Read(strings.NewReader(src))
+	if !assert.NoError(t, err, "jtd.Read should succeed") {
+		return
+	}
+
+	s, err := jtd.ToJSONSchema(j)
+	if !assert.NoError(t, err, "ToJSONSchema should succeed") {
+		return
+	}
+	if !assert.Equal(t, "An event", s.Description, "description should be converted") {
+		return
+	}
+	id := s.Properties["id"]
+	if !assert.Equal(t, schema.PrimitiveTypes{schema.IntegerType}, id.Type, "integer types should become integers") {
+		return
+	}
+	if !assert.Equal(t, float64(4294967295), id.Maximum.Val, "integer range should be converted") {
+		return
+	}
+	if !assert.Equal(t, schema.FormatDateTime, s.Properties["at"].Format, "timestamps should become date-time strings") {
+		return
+	}
+	if !assert.Len(t, s.Properties["payload"].OneOf, 2, "discriminator should become oneOf") {
+		return
+	}
+	if !assert.True(t, s.IsPropRequired("payload"), "properties should be required") {
+		return
+	}
+	if !assert.False(t, s.IsPropRequired("scores"), "optional properties should not be required") {
+		return
+	}
+
+	// Converting back should produce the original schema
+	back, err := jtd.FromJSONSchema(s)
+	if !assert.NoError(t, err, "FromJSONSchema should succeed") {
+		return
+	}
+	buf, err := json.Marshal(back)
+	if !assert.NoError(t, err, "json.Marshal should succeed") {
+		return
+	}
+	if !assert.JSONEq(t, src, string(buf), "schema should round trip") {
+		return
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	for _, src := range []string{
+		`{"type": "int64"}`,
+		`{"type": "string", "enum": ["a"]}`,
+		`{"ref": "missing"}`,
+		`{"enum": []}`,
+		`{"enum": ["a", "a"]}`,
+		`{"format": "email"}`,
+		`{"elements": {"definitions": {}}}`,
+		`{"properties": {"a": {}}, "optionalProperties": {"a": {}}}`,
+		`{"discriminator": "kind", "mapping": {"a": {"type": "string"}}}`,
+		`{"discriminator": "kind", "mapping": {"a": {"properties": {"kind": {}}}}}`,
+		`{"discriminator": "kind"}`,
+	} {
+		_, err := jtd.Read(strings.NewReader(src))
+		if !assert.Error(t, err, "jtd.Read should fail for %s", src) {
+			return
+		}
+	}
+}
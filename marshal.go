@@ -1,26 +1,197 @@
 package schema
 
 import (
+	"bytes"
 	"encoding/json"
 	"regexp"
+	"sort"
 	"strconv"
 
 	"github.com/lestrrat-go/pdebug"
 	"github.com/pkg/errors"
 )
 
+// extractContext holds the state shared while extracting
+// a tree of schemas
+type extractContext struct {
+	path      []string   // reference tokens to the schema being extracted
+	source    *sourceMap // positions in the source document, if available
+	lenient   bool       // report all errors instead of just the first one
+	roundTrip bool       // remember the keywords present in the source
+	keepOrder bool       // remember the order of keys in the source
+	floats    bool       // convert arbitrary numbers to float64
+	errs      ParseErrors
+}
+
+func (ctx *extractContext) push(tokens ...string) {
+	ctx.path = append(ctx.path, tokens...)
+}
+
+func (ctx *extractContext) pop(n int) {
+	ctx.path = ctx.path[:len(ctx.path)-n]
+}
+
+// pointer returns the JSON pointer (without the leading '#') to the
+// location specified by `tokens`, relative to the current schema
+func (ctx *extractContext) pointer(tokens ...string) string {
+	return appendPointer(appendPointer("", ctx.path...), tokens...)
+}
+
+// fail records an error found at the location specified by `tokens`,
+// relative to the current schema. Extraction carries on regardless,
+// so that as much of the schema as possible is populated
+func (ctx *extractContext) fail(err error, tokens ...string) {
+	ptr := ctx.pointer(tokens...)
+	pe := &ParseError{Pointer: "#" + ptr, Err: err}
+	if ctx.source != nil {
+		pe.Position = ctx.source.keyPosition(ptr)
+	}
+	ctx.errs = append(ctx.errs, pe)
+}
+
+// error returns the errors recorded during extraction: all of them
+// as ParseErrors in lenient mode, or the first one otherwise
+func (ctx *extractContext) error() error {
+	if len(ctx.errs) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(ctx.errs, func(i, j int) bool {
+		if a, b := ctx.errs[i].Position.Offset, ctx.errs[j].Position.Offset; a != b {
+			return a < b
+		}
+		return ctx.errs[i].Pointer < ctx.errs[j].Pointer
+	})
+
+	if ctx.lenient {
+		return ctx.errs
+	}
+	return ctx.errs[0]
+}
+
+// extractSubschema creates a new schema from `m`, which is located
+// at `tokens` relative to the current schema
+func (ctx *extractContext) extractSubschema(m map[string]interface{}, tokens ...string) *Schema {
+	s := New()
+	ctx.push(tokens...)
+	s.extract(ctx, m)
+	ctx.pop(len(tokens))
+	return s
+}
+
+// recordPositions stores the source positions of the schema
+// and its keywords, if they are available
+func (ctx *extractContext) recordPositions(s *Schema, m map[string]interface{}) {
+	if ctx.source == nil {
+		return
+	}
+
+	ptr := ctx.pointer()
+	s.position = ctx.source.valuePosition(ptr)
+	s.keywordPos = make(map[string]Position, len(m))
+	for k := range m {
+		s.keywordPos[k] = ctx.source.keyPosition(appendPointer(ptr, k))
+	}
+}
+
+// value converts the numbers in an arbitrary value found in the
+// source into float64 if WithUseNumber(false) was specified
+func (ctx *extractContext) value(v interface{}) interface{} {
+	if !ctx.floats {
+		return v
+	}
+
+	switch val := v.(type) {
+	case json.Number:
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, x := range val {
+			m[k] = ctx.value(x)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(val))
+		for i, x := range val {
+			l[i] = ctx.value(x)
+		}
+		return l
+	}
+	return v
+}
+
+// keyOrder returns the keys of the object at `ptr` (relative to the
+// current schema) in the order that they appear in the source. If
+// positions are not available, the keys are sorted
+func (ctx *extractContext) keyOrder(m map[string]interface{}, tokens ...string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	if ctx.source == nil {
+		sort.Strings(keys)
+		return keys
+	}
+
+	ptr := ctx.pointer(tokens...)
+	offsets := make(map[string]int, len(keys))
+	for _, k := range keys {
+		offsets[k] = ctx.source.keys[appendPointer(ptr, k)]
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return offsets[keys[i]] < offsets[keys[j]]
+	})
+	return keys
+}
+
+// recordKeywords stores the keywords found in the source of the
+// schema, so that it can later be serialized to the same content
+func (ctx *extractContext) recordKeywords(s *Schema, m map[string]interface{}) {
+	if !ctx.roundTrip && !ctx.keepOrder {
+		return
+	}
+
+	s.roundTrip = true
+	s.keywords = ctx.keyOrder(m)
+	if !ctx.keepOrder {
+		return
+	}
+
+	s.keepOrder = true
+	for _, name := range []string{"definitions", "properties", "patternProperties", "dependencies"} {
+		if v, ok := m[name].(map[string]interface{}); ok {
+			if s.memberOrder == nil {
+				s.memberOrder = make(map[string][]string)
+			}
+			s.memberOrder[name] = ctx.keyOrder(v, name)
+		}
+	}
+}
+
 func extractNumber(n *Number, m map[string]interface{}, s string) error {
 	v, ok := m[s]
 	if !ok {
 		return nil
 	}
 
-	val, ok := v.(float64)
-	if !ok {
+	switch val := v.(type) {
+	case float64:
+		n.Val = val
+		n.Exact = ""
+	case json.Number:
+		f, err := val.Float64()
+		if err != nil {
+			return errors.Wrap(err, "failed to extract number")
+		}
+		n.Val = f
+		n.Exact = val
+	default:
 		return errors.Wrap(errInvalidType("float64", v), "failed to extract number")
 	}
 
-	n.Val = val
 	n.Initialized = true
 	return nil
 }
@@ -31,12 +202,25 @@ func extractInt(n *Integer, m map[string]interface{}, s string) error {
 		return nil
 	}
 
-	val, ok := v.(float64)
-	if !ok {
+	switch val := v.(type) {
+	case float64:
+		n.Val = int(val)
+	case json.Number:
+		// Parse as an integer first, so that large values are exact
+		if i, err := strconv.ParseInt(val.String(), 10, 0); err == nil {
+			n.Val = int(i)
+			break
+		}
+
+		f, err := val.Float64()
+		if err != nil {
+			return errors.Wrap(err, "failed to extract int")
+		}
+		n.Val = int(f)
+	default:
 		return errors.Wrap(errInvalidType("float64", v), "failed to extract int")
 	}
 
-	n.Val = int(val)
 	n.Initialized = true
 	return nil
 }
@@ -168,10 +352,10 @@ func extractRegexp(r **regexp.Regexp, m map[string]interface{}, s string) error
 	return nil
 }
 
-func extractSchema(s **Schema, m map[string]interface{}, name string) error {
+func extractSchema(ctx *extractContext, s **Schema, m map[string]interface{}, name string) {
 	v, ok := m[name]
 	if !ok {
-		return nil
+		return
 	}
 
 	if pdebug.Enabled {
@@ -180,93 +364,80 @@ func extractSchema(s **Schema, m map[string]interface{}, name string) error {
 
 	val, ok := v.(map[string]interface{})
 	if !ok {
-		return errors.Wrap(
+		ctx.fail(errors.Wrapf(
 			errInvalidType("map[string]interface{}", v),
-			"failed to extract schema",
-		)
+			"failed to extract '%s'", name,
+		), name)
+		return
 	}
 
-	return extractSingleSchema(s, val)
-}
-
-func extractSingleSchema(s **Schema, m map[string]interface{}) error {
-	*s = New()
-	if err := (*s).Extract(m); err != nil {
-		return errors.Wrap(err, "failed to extract schema")
-	}
-	return nil
+	*s = ctx.extractSubschema(val, name)
 }
 
-func (l *SchemaList) extractIfPresent(m map[string]interface{}, name string) error {
+func (l *SchemaList) extractIfPresent(ctx *extractContext, m map[string]interface{}, name string) {
 	v, ok := m[name]
 	if !ok {
-		return nil
+		return
 	}
 
 	if pdebug.Enabled {
 		pdebug.Printf("Found property '%s'", name)
 	}
 
-	return l.Extract(v)
+	ctx.push(name)
+	l.extract(ctx, v)
+	ctx.pop(1)
 }
 
 // Extract takes either a list of `map[string]interface{}` or
 // a single `map[string]interface{}` to initialize this list
 // of schemas
 func (l *SchemaList) Extract(v interface{}) error {
+	var ctx extractContext
+	l.extract(&ctx, v)
+	return ctx.error()
+}
+
+func (l *SchemaList) extract(ctx *extractContext, v interface{}) {
 	switch val := v.(type) {
 	case []interface{}:
 		*l = make([]*Schema, len(val))
-		var s *Schema
 		for i, d := range val {
 			m, ok := d.(map[string]interface{})
 			if !ok {
-				return errors.Wrap(
+				ctx.fail(errors.Wrap(
 					errInvalidType("map[string]interface{}", d),
 					"failed to extract schema list",
-				)
-			}
-			if err := extractSingleSchema(&s, m); err != nil {
-				return errors.Wrap(err, "failed to extract schema list")
+				), strconv.Itoa(i))
+				// Keep the positions of the rest of the list intact
+				(*l)[i] = New()
+				continue
 			}
-			(*l)[i] = s
+			(*l)[i] = ctx.extractSubschema(m, strconv.Itoa(i))
 		}
-		return nil
 	case map[string]interface{}:
-		var s *Schema
-		if err := extractSingleSchema(&s, val); err != nil {
-			return errors.Wrap(err, "failed to extract schema list")
-		}
-		*l = []*Schema{s}
-		return nil
+		*l = []*Schema{ctx.extractSubschema(val)}
 	default:
-		return errors.Wrap(
+		ctx.fail(errors.Wrap(
 			errInvalidType("[]*Schema or *Schema", v),
 			"failed to extract schema list",
-		)
-	}
-}
-
-func extractSchemaMapEntry(s *Schema, name string, m map[string]interface{}) error {
-	if pdebug.Enabled {
-		g := pdebug.Marker("Schema map entry '%s'", name)
-		defer g.End()
+		))
 	}
-	return s.Extract(m)
 }
 
-func extractSchemaMap(m map[string]interface{}, name string) (map[string]*Schema, error) {
+func extractSchemaMap(ctx *extractContext, m map[string]interface{}, name string) map[string]*Schema {
 	v, ok := m[name]
 	if !ok {
-		return nil, nil
+		return nil
 	}
 
 	val, ok := v.(map[string]interface{})
 	if !ok {
-		return nil, errors.Wrap(
+		ctx.fail(errors.Wrapf(
 			errInvalidType("map[string]interface{}", v),
-			"failed to extract schema map",
-		)
+			"failed to extract '%s'", name,
+		), name)
+		return nil
 	}
 
 	r := make(map[string]*Schema)
@@ -274,39 +445,34 @@ func extractSchemaMap(m map[string]interface{}, name string) (map[string]*Schema
 		// data better be a map
 		m, ok := data.(map[string]interface{})
 		if !ok {
-			return nil, errors.Wrap(
+			ctx.fail(errors.Wrap(
 				errInvalidType("map[string]interface{}", data),
 				"failed to extract sub field",
-			)
-		}
-
-		s := New()
-		if err := extractSchemaMapEntry(s, k, m); err != nil {
-			return nil, err
+			), name, k)
+			continue
 		}
-		r[k] = s
 
-		if k == "domain" {
-			if pdebug.Enabled {
-				pdebug.Printf("after extractSchemaMapEntry: %#v", s.Extras)
-			}
+		if pdebug.Enabled {
+			pdebug.Printf("Schema map entry '%s'", k)
 		}
+		r[k] = ctx.extractSubschema(m, name, k)
 	}
-	return r, nil
+	return r
 }
 
-func extractRegexpToSchemaMap(m map[string]interface{}, name string) (map[*regexp.Regexp]*Schema, error) {
+func extractRegexpToSchemaMap(ctx *extractContext, m map[string]interface{}, name string) map[*regexp.Regexp]*Schema {
 	v, ok := m[name]
 	if !ok {
-		return nil, nil
+		return nil
 	}
 
 	val, ok := v.(map[string]interface{})
 	if !ok {
-		return nil, errors.Wrap(
+		ctx.fail(errors.Wrapf(
 			errInvalidType("map[string]interface{}", v),
-			"failed to extract regexp to schema map",
-		)
+			"failed to extract '%s'", name,
+		), name)
+		return nil
 	}
 
 	r := make(map[*regexp.Regexp]*Schema)
@@ -314,30 +480,28 @@ func extractRegexpToSchemaMap(m map[string]interface{}, name string) (map[*regex
 		// data better be a map
 		m, ok := data.(map[string]interface{})
 		if !ok {
-			return nil, errors.Wrap(
+			ctx.fail(errors.Wrap(
 				errInvalidType("map[string]interface{}", data),
 				"failed to extract regexp to schema map",
-			)
-		}
-		s := New()
-		if err := s.Extract(m); err != nil {
-			return nil, errors.Wrap(err, "failed to extract schema within schema map")
+			), name, k)
+			continue
 		}
 
 		rx, err := regexp.Compile(k)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to compile regular expression for regexp to schema map")
+			ctx.fail(errors.Wrap(err, "failed to compile regular expression for regexp to schema map"), name, k)
+			continue
 		}
 
-		r[rx] = s
+		r[rx] = ctx.extractSubschema(m, name, k)
 	}
-	return r, nil
+	return r
 }
 
-func extractItems(res **ItemSpec, m map[string]interface{}, name string) error {
+func extractItems(ctx *extractContext, res **ItemSpec, m map[string]interface{}, name string) {
 	v, ok := m[name]
 	if !ok {
-		return nil
+		return
 	}
 
 	if pdebug.Enabled {
@@ -350,43 +514,41 @@ func extractItems(res **ItemSpec, m map[string]interface{}, name string) error {
 		tupleMode = true
 	case map[string]interface{}:
 	default:
-		return errors.Wrap(
+		ctx.fail(errors.Wrap(
 			errInvalidType("[]interface{} or map[string]interface{}", v),
 			"failed to extract items",
-		)
+		), name)
+		return
 	}
 
 	items := ItemSpec{}
 	items.TupleMode = tupleMode
-
-	var err error
-
-	if err = items.Schemas.extractIfPresent(m, name); err != nil {
-		return errors.Wrap(err, "failed to schema for item")
-	}
+	items.Schemas.extractIfPresent(ctx, m, name)
 	*res = &items
-	return nil
 }
 
-func extractDependecies(res *DependencyMap, m map[string]interface{}, name string) error {
+func extractDependecies(ctx *extractContext, res *DependencyMap, m map[string]interface{}, name string) {
 	v, ok := m[name]
 	if !ok {
-		return nil
+		return
 	}
 
 	m, ok = v.(map[string]interface{})
 	if !ok {
-		return errors.Wrap(
+		ctx.fail(errors.Wrap(
 			errInvalidType("map[string]interface{}", v),
 			"failed to extract dependencies",
-		)
+		), name)
+		return
 	}
 
 	if len(m) == 0 {
-		return nil
+		return
 	}
 
-	return res.extract(m)
+	ctx.push(name)
+	res.extract(ctx, m)
+	ctx.pop(1)
 }
 
 func extractType(pt *PrimitiveTypes, m map[string]interface{}, name string) error {
@@ -428,7 +590,7 @@ func extractType(pt *PrimitiveTypes, m map[string]interface{}, name string) erro
 	}
 }
 
-func (dm *DependencyMap) extract(m map[string]interface{}) error {
+func (dm *DependencyMap) extract(ctx *extractContext, m map[string]interface{}) {
 	dm.Names = make(map[string][]string)
 	dm.Schemas = make(map[string]*Schema)
 	for k, p := range m {
@@ -437,171 +599,187 @@ func (dm *DependencyMap) extract(m map[string]interface{}) error {
 			// This list needs to be a list of strings
 			var l []string
 			if err := convertStringList(&l, val); err != nil {
-				return err
+				ctx.fail(err, k)
+				continue
 			}
 
 			dm.Names[k] = l
 		case map[string]interface{}:
-			s := New()
-			if err := s.Extract(val); err != nil {
-				return err
-			}
-			dm.Schemas[k] = s
+			dm.Schemas[k] = ctx.extractSubschema(val, k)
 		default:
-			return errors.Wrap(
+			ctx.fail(errors.Wrap(
 				errInvalidType("[]interface{} or map[string]interface{}", p),
-				"failed to extract 'type'",
-			)
+				"failed to extract dependency",
+			), k)
 		}
 	}
-
-	return nil
 }
 
 // UnmarshalJSON takes a JSON string and initializes
 // the schema
 func (s *Schema) UnmarshalJSON(data []byte) error {
-	m := map[string]interface{}{}
-	if err := json.Unmarshal(data, &m); err != nil {
+	return s.unmarshalJSON(data, "", &extractContext{})
+}
+
+func (s *Schema) unmarshalJSON(data []byte, filename string, ctx *extractContext) error {
+	v, src, err := decodeWithPositions(data, filename)
+	if err != nil {
 		return err
 	}
+	return s.extractDocument(v, src, ctx)
+}
 
-	return s.Extract(m)
+// extractDocument extracts the schema from the decoded document `v`,
+// whose positions are recorded in `src`
+func (s *Schema) extractDocument(v interface{}, src *sourceMap, ctx *extractContext) error {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return &ParseError{
+			Pointer:  "#",
+			Position: src.valuePosition(""),
+			Err:      errInvalidType("object", v),
+		}
+	}
+
+	ctx.source = src
+	s.extract(ctx, m)
+	return ctx.error()
 }
 
 // Extract takes a `map[string]interface{}` and initializes
 // the schema
 func (s *Schema) Extract(m map[string]interface{}) error {
+	var ctx extractContext
+	s.extract(&ctx, m)
+	return ctx.error()
+}
+
+func (s *Schema) extract(ctx *extractContext, m map[string]interface{}) {
 	if pdebug.Enabled {
 		g := pdebug.IPrintf("START Schema.Extract")
 		defer g.IRelease("END Schema.Extract")
 	}
 
-	var err error
+	ctx.recordPositions(s, m)
+	ctx.recordKeywords(s, m)
 
-	if err = extractString(&s.ID, m, "id"); err != nil {
-		return errors.Wrapf(err, "failed to extract 'id'")
+	if err := extractString(&s.ID, m, "id"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'id'"), "id")
 	}
 
-	if err = extractString(&s.Title, m, "title"); err != nil {
-		return errors.Wrap(err, "failed to extract 'title'")
+	if err := extractString(&s.Title, m, "title"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'title'"), "title")
 	}
 
-	if err = extractString(&s.Description, m, "description"); err != nil {
-		return errors.Wrap(err, "failed to extract 'description'")
+	if err := extractString(&s.Description, m, "description"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'description'"), "description")
 	}
 
-	if err = extractStringList(&s.Required, m, "required"); err != nil {
-		return errors.Wrap(err, "failed to extract 'required'")
+	if err := extractStringList(&s.Required, m, "required"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'required'"), "required")
 	}
 
-	if err = extractJSPointer(&s.SchemaRef, m, "$schema"); err != nil {
-		return errors.Wrap(err, "failed to extract '$schema'")
+	if err := extractJSPointer(&s.SchemaRef, m, "$schema"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract '$schema'"), "$schema")
 	}
 
-	if err = extractJSPointer(&s.Reference, m, "$ref"); err != nil {
-		return errors.Wrap(err, "failed to extract '$ref'")
+	if err := extractJSPointer(&s.Reference, m, "$ref"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract '$ref'"), "$ref")
 	}
 
-	if err = extractFormat(&s.Format, m, "format"); err != nil {
-		return errors.Wrap(err, "failed to extract 'format'")
+	if err := extractFormat(&s.Format, m, "format"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'format'"), "format")
 	}
 
-	if err = extractInterfaceList(&s.Enum, m, "enum"); err != nil {
-		return errors.Wrap(err, "failed to extract 'enum'")
+	if err := extractInterfaceList(&s.Enum, m, "enum"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'enum'"), "enum")
 	}
-
-	if err = extractInterface(&s.Default, m, "default"); err != nil {
-		return errors.Wrap(err, "failed to extract 'default'")
+	for i, v := range s.Enum {
+		s.Enum[i] = ctx.value(v)
 	}
 
-	if err = extractType(&s.Type, m, "type"); err != nil {
-		return errors.Wrap(err, "failed to extract 'type'")
+	if err := extractInterface(&s.Default, m, "default"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'default'"), "default")
 	}
+	s.Default = ctx.value(s.Default)
 
-	if s.Definitions, err = extractSchemaMap(m, "definitions"); err != nil {
-		return errors.Wrap(err, "failed to extract 'definitions'")
+	if err := extractType(&s.Type, m, "type"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'type'"), "type")
 	}
 
-	if err = extractItems(&s.Items, m, "items"); err != nil {
-		return errors.Wrap(err, "failed to extract 'items'")
-	}
+	s.Definitions = extractSchemaMap(ctx, m, "definitions")
 
-	if err = extractRegexp(&s.Pattern, m, "pattern"); err != nil {
-		return errors.Wrap(err, "failed to extract 'patterns'")
-	}
+	extractItems(ctx, &s.Items, m, "items")
 
-	if extractInt(&s.MinLength, m, "minLength"); err != nil {
-		return errors.Wrap(err, "failed to extract 'minLength'")
+	if err := extractRegexp(&s.Pattern, m, "pattern"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'pattern'"), "pattern")
 	}
 
-	if extractInt(&s.MaxLength, m, "maxLength"); err != nil {
-		return errors.Wrap(err, "failed to extract 'maxLength'")
+	if err := extractInt(&s.MinLength, m, "minLength"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'minLength'"), "minLength")
 	}
 
-	if extractInt(&s.MinItems, m, "minItems"); err != nil {
-		return errors.Wrap(err, "failed to extract 'minItems'")
+	if err := extractInt(&s.MaxLength, m, "maxLength"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'maxLength'"), "maxLength")
 	}
 
-	if extractInt(&s.MaxItems, m, "maxItems"); err != nil {
-		return errors.Wrap(err, "failed to extract 'maxItems'")
+	if err := extractInt(&s.MinItems, m, "minItems"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'minItems'"), "minItems")
 	}
 
-	if err = extractBool(&s.UniqueItems, m, "uniqueItems", false); err != nil {
-		return errors.Wrap(err, "failed to extract 'uniqueItems'")
+	if err := extractInt(&s.MaxItems, m, "maxItems"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'maxItems'"), "maxItems")
 	}
 
-	if err = extractInt(&s.MaxProperties, m, "maxProperties"); err != nil {
-		return errors.Wrap(err, "failed to extract 'maxProperties'")
+	if err := extractBool(&s.UniqueItems, m, "uniqueItems", false); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'uniqueItems'"), "uniqueItems")
 	}
 
-	if err = extractInt(&s.MinProperties, m, "minProperties"); err != nil {
-		return errors.Wrap(err, "failed to extract 'minProperties'")
+	if err := extractInt(&s.MaxProperties, m, "maxProperties"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'maxProperties'"), "maxProperties")
 	}
 
-	if err = extractNumber(&s.Minimum, m, "minimum"); err != nil {
-		return errors.Wrap(err, "failed to extract 'minimum'")
+	if err := extractInt(&s.MinProperties, m, "minProperties"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'minProperties'"), "minProperties")
 	}
 
-	if err = extractBool(&s.ExclusiveMinimum, m, "exclusiveMinimum", false); err != nil {
-		return errors.Wrap(err, "failed to extract 'exclusiveMinimum'")
+	if err := extractNumber(&s.Minimum, m, "minimum"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'minimum'"), "minimum")
 	}
 
-	if err = extractNumber(&s.Maximum, m, "maximum"); err != nil {
-		return errors.Wrap(err, "failed to extract 'maximum'")
+	if err := extractBool(&s.ExclusiveMinimum, m, "exclusiveMinimum", false); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'exclusiveMinimum'"), "exclusiveMinimum")
 	}
 
-	if err = extractBool(&s.ExclusiveMaximum, m, "exclusiveMaximum", false); err != nil {
-		return errors.Wrap(err, "failed to extract 'exclusiveMaximum'")
+	if err := extractNumber(&s.Maximum, m, "maximum"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'maximum'"), "maximum")
 	}
 
-	if err = extractNumber(&s.MultipleOf, m, "multipleOf"); err != nil {
-		return errors.Wrap(err, "failed to extract 'multipleOf'")
+	if err := extractBool(&s.ExclusiveMaximum, m, "exclusiveMaximum", false); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'exclusiveMaximum'"), "exclusiveMaximum")
 	}
 
-	if s.Properties, err = extractSchemaMap(m, "properties"); err != nil {
-		return errors.Wrap(err, "failed to extract 'properties'")
+	if err := extractNumber(&s.MultipleOf, m, "multipleOf"); err != nil {
+		ctx.fail(errors.Wrap(err, "failed to extract 'multipleOf'"), "multipleOf")
 	}
 
-	if err = extractDependecies(&s.Dependencies, m, "dependencies"); err != nil {
-		return errors.Wrap(err, "failed to extract 'dependencies'")
-	}
+	s.Properties = extractSchemaMap(ctx, m, "properties")
+
+	extractDependecies(ctx, &s.Dependencies, m, "dependencies")
 
 	if _, ok := m["additionalItems"]; !ok {
 		// doesn't exist. it's an empty schema
 		s.AdditionalItems = &AdditionalItems{}
 	} else {
 		var b Bool
-		if err = extractBool(&b, m, "additionalItems", true); err == nil {
+		if err := extractBool(&b, m, "additionalItems", true); err == nil {
 			if b.Bool() {
 				s.AdditionalItems = &AdditionalItems{}
 			}
 		} else {
 			// Oh, it's not a boolean?
 			var apSchema *Schema
-			if err = extractSchema(&apSchema, m, "additionalItems"); err != nil {
-				return errors.Wrap(err, "failed to extract 'additionalItems'")
-			}
+			extractSchema(ctx, &apSchema, m, "additionalItems")
 			s.AdditionalItems = &AdditionalItems{apSchema}
 		}
 	}
@@ -611,39 +789,27 @@ func (s *Schema) Extract(m map[string]interface{}) error {
 		s.AdditionalProperties = &AdditionalProperties{}
 	} else {
 		var b Bool
-		if err = extractBool(&b, m, "additionalProperties", true); err == nil {
+		if err := extractBool(&b, m, "additionalProperties", true); err == nil {
 			if b.Bool() {
 				s.AdditionalProperties = &AdditionalProperties{}
 			}
 		} else {
 			// Oh, it's not a boolean?
 			var apSchema *Schema
-			if err = extractSchema(&apSchema, m, "additionalProperties"); err != nil {
-				return errors.Wrap(err, "failed to extract 'additionalProperties'")
-			}
+			extractSchema(ctx, &apSchema, m, "additionalProperties")
 			s.AdditionalProperties = &AdditionalProperties{apSchema}
 		}
 	}
 
-	if s.PatternProperties, err = extractRegexpToSchemaMap(m, "patternProperties"); err != nil {
-		return errors.Wrap(err, "failed to extract 'patternProperties'")
-	}
+	s.PatternProperties = extractRegexpToSchemaMap(ctx, m, "patternProperties")
 
-	if err = s.AllOf.extractIfPresent(m, "allOf"); err != nil {
-		return errors.Wrap(err, "failed to extract 'allOf'")
-	}
+	s.AllOf.extractIfPresent(ctx, m, "allOf")
 
-	if err = s.AnyOf.extractIfPresent(m, "anyOf"); err != nil {
-		return errors.Wrap(err, "failed to extract 'anyOf'")
-	}
+	s.AnyOf.extractIfPresent(ctx, m, "anyOf")
 
-	if err = s.OneOf.extractIfPresent(m, "oneOf"); err != nil {
-		return errors.Wrap(err, "failed to extract 'oneOf'")
-	}
+	s.OneOf.extractIfPresent(ctx, m, "oneOf")
 
-	if err = extractSchema(&s.Not, m, "not"); err != nil {
-		return errors.Wrap(err, "failed to extract 'not'")
-	}
+	extractSchema(ctx, &s.Not, m, "not")
 
 	s.applyParentSchema()
 
@@ -656,14 +822,12 @@ func (s *Schema) Extract(m map[string]interface{}) error {
 		if pdebug.Enabled {
 			pdebug.Printf("Extracting extra field '%s'", k)
 		}
-		s.Extras[k] = v
+		s.Extras[k] = ctx.value(v)
 	}
 
 	if pdebug.Enabled {
 		pdebug.Printf("Successfully extracted schema")
 	}
-
-	return nil
 }
 
 func place(m map[string]interface{}, name string, v interface{}) {
@@ -712,6 +876,14 @@ func placeNumber(m map[string]interface{}, name string, n Number) {
 	if !n.Initialized {
 		return
 	}
+
+	// Prefer the exact representation, unless Val has been changed
+	if n.Exact != "" {
+		if f, err := n.Exact.Float64(); err == nil && f == n.Val {
+			place(m, name, n.Exact)
+			return
+		}
+	}
 	place(m, name, n.Val)
 }
 
@@ -722,6 +894,138 @@ func placeInteger(m map[string]interface{}, name string, n Integer) {
 	place(m, name, n.Val)
 }
 
+// orderedObject is a JSON object whose members are serialized in
+// the given order. Members not listed in `order` are serialized
+// after the rest, in sorted order
+type orderedObject struct {
+	values map[string]interface{}
+	order  []string
+}
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	keys := make([]string, 0, len(o.values))
+	seen := make(map[string]struct{}, len(o.values))
+	for _, k := range o.order {
+		if _, ok := o.values[k]; !ok {
+			continue
+		}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		keys = append(keys, k)
+	}
+
+	var rest []string
+	for k := range o.values {
+		if _, ok := seen[k]; !ok {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	keys = append(keys, rest...)
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		kbuf, err := json.Marshal(k)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to serialize key %s", strconv.Quote(k))
+		}
+		buf.Write(kbuf)
+		buf.WriteByte(':')
+
+		vbuf, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to serialize value for %s", strconv.Quote(k))
+		}
+		buf.Write(vbuf)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// hasKeyword returns true if the schema was read with WithRoundTrip
+// and the keyword `name` was present in the source
+func (s *Schema) hasKeyword(name string) bool {
+	if !s.roundTrip {
+		return false
+	}
+
+	for _, k := range s.keywords {
+		if k == name {
+			return true
+		}
+	}
+	return false
+}
+
+// placeRoundTrip fills in keywords that were present in the source,
+// but whose values are indistinguishable from their defaults and
+// therefore would otherwise be omitted
+func placeRoundTrip(m map[string]interface{}, s *Schema) {
+	for _, k := range s.keywords {
+		if _, ok := m[k]; ok {
+			continue
+		}
+
+		switch k {
+		case "id":
+			place(m, k, s.ID)
+		case "title":
+			place(m, k, s.Title)
+		case "description":
+			place(m, k, s.Description)
+		case "$schema":
+			place(m, k, s.SchemaRef)
+		case "$ref":
+			place(m, k, s.Reference)
+		case "format":
+			place(m, k, string(s.Format))
+		case "default":
+			place(m, k, s.Default)
+		case "required":
+			place(m, k, []string{})
+		case "enum", "type", "allOf", "anyOf", "oneOf":
+			place(m, k, []interface{}{})
+		case "definitions", "properties", "patternProperties", "dependencies":
+			place(m, k, map[string]interface{}{})
+		case "additionalItems":
+			if s.AdditionalItems != nil {
+				place(m, k, true)
+			}
+		case "additionalProperties":
+			if s.AdditionalProperties != nil {
+				place(m, k, true)
+			}
+		}
+	}
+}
+
+// placeOrdered replaces the objects that hold named members in `m`,
+// so that their members are serialized in the order found in the source
+func placeOrdered(m map[string]interface{}, s *Schema) {
+	for name, order := range s.memberOrder {
+		var values map[string]interface{}
+		switch v := m[name].(type) {
+		case map[string]*Schema:
+			values = make(map[string]interface{}, len(v))
+			for k, sc := range v {
+				values[k] = sc
+			}
+		case map[string]interface{}:
+			values = v
+		default:
+			continue
+		}
+		m[name] = orderedObject{values: values, order: order}
+	}
+}
+
 func canBeType(s *Schema, primType PrimitiveType) bool {
 	if len(s.Type) == 0 {
 		return true
@@ -736,6 +1040,16 @@ func canBeType(s *Schema, primType PrimitiveType) bool {
 
 // MarshalJSON serializes the schema into a JSON string
 func (s *Schema) MarshalJSON() ([]byte, error) {
+	m := s.marshalMap()
+	if s.keepOrder {
+		return json.Marshal(orderedObject{values: m, order: s.keywords})
+	}
+	return json.Marshal(m)
+}
+
+// marshalMap returns the keywords of the schema and their values,
+// as they should be serialized
+func (s *Schema) marshalMap() map[string]interface{} {
 	m := make(map[string]interface{})
 
 	placeString(m, "id", s.ID)
@@ -763,7 +1077,8 @@ func (s *Schema) MarshalJSON() ([]byte, error) {
 		// additionalItems only makes sense if we are an array type, no
 		// need to inject 'false' for things that are
 		// object/int/float/etc.
-		if canBeType(s, ArrayType) {
+		// When round-tripping, nil means that 'false' was explicitly given
+		if s.roundTrip || canBeType(s, ArrayType) {
 			place(m, "additionalItems", false)
 		}
 	}
@@ -827,12 +1142,12 @@ func (s *Schema) MarshalJSON() ([]byte, error) {
 		// If we are an Object type.
 		// https://spacetelescope.github.io/understanding-json-schema/reference/object.html#properties
 		// additionalProperties only has meaning for Object types.
-		if canBeType(s, ObjectType) {
+		if s.roundTrip || canBeType(s, ObjectType) {
 			placeBool(m, "additionalProperties", Bool{Val: false, Initialized: true})
 		}
 	}
 
-	if s.MultipleOf.Val != 0 {
+	if s.MultipleOf.Val != 0 || s.roundTrip {
 		placeNumber(m, "multipleOf", s.MultipleOf)
 	}
 
@@ -862,5 +1177,12 @@ func (s *Schema) MarshalJSON() ([]byte, error) {
 		}
 	}
 
-	return json.Marshal(m)
+	if s.roundTrip {
+		placeRoundTrip(m, s)
+	}
+
+	if s.keepOrder {
+		placeOrdered(m, s)
+	}
+	return m
 }
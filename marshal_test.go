@@ -1,7 +1,10 @@
 package schema_test
 
 import (
+	"bytes"
 	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -63,3 +66,331 @@ func TestMarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestLenientParse(t *testing.T) {
+	const src = `{
+  "type": "object",
+  "maxItems": "ten",
+  "properties": {
+    "name": { "type": "string", "minLength": 1 },
+    "kind": { "type": "strnig" }
+  },
+  "patternProperties": {
+    "^(x-": { "type": "string" }
+  }
+}`
+
+	_, err := schema.Read(strings.NewReader(src))
+	if !assert.Error(t, err, "schema.Read should fail") {
+		return
+	}
+	if !assert.IsType(t, &schema.ParseError{}, err, "error should be a single ParseError") {
+		return
+	}
+
+	s, err := schema.Read(strings.NewReader(src), schema.WithLenient(true))
+	if !assert.Error(t, err, "schema.Read should fail") {
+		return
+	}
+
+	list, ok := err.(schema.ParseErrors)
+	if !assert.True(t, ok, "error should be ParseErrors") {
+		return
+	}
+
+	var pointers []string
+	for _, e := range list {
+		pointers = append(pointers, e.Pointer)
+	}
+	if !assert.Equal(t, []string{"#/maxItems", "#/properties/kind/type", "#/patternProperties/^(x-"}, pointers, "all errors should be reported in order") {
+		return
+	}
+
+	if !assert.NotNil(t, s, "best-effort schema should be returned") {
+		return
+	}
+	if !assert.Equal(t, 1, s.Properties["name"].MinLength.Val, "valid parts of the schema should be extracted") {
+		return
+	}
+	if !assert.Equal(t, s, s.Properties["name"].Root(), "parent schemas should be set") {
+		return
+	}
+
+	_, err = schema.Read(strings.NewReader(`{"type": }`), schema.WithLenient(true))
+	if !assert.IsType(t, &schema.ParseError{}, err, "malformed JSON should be a single ParseError") {
+		return
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	const src = `{
+  "type": "string",
+  "title": "",
+  "additionalItems": true,
+  "additionalProperties": false,
+  "multipleOf": 0,
+  "default": null,
+  "enum": [],
+  "required": [],
+  "definitions": {},
+  "dependencies": {}
+}`
+
+	s, err := schema.Read(strings.NewReader(src))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+	output, err := json.Marshal(s)
+	if !assert.NoError(t, err, "json.Marshal should succeed") {
+		return
+	}
+	if !assert.NotEqual(t, semanticJSON(t, []byte(src)), semanticJSON(t, output), "content should differ without round-trip mode") {
+		return
+	}
+
+	s, err = schema.Read(strings.NewReader(src), schema.WithRoundTrip(true))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+	output, err = json.Marshal(s)
+	if !assert.NoError(t, err, "json.Marshal should succeed") {
+		return
+	}
+	if !assert.Equal(t, semanticJSON(t, []byte(src)), semanticJSON(t, output), "content should be the same in round-trip mode") {
+		return
+	}
+
+	const numbers = `{"enum":[9007199254740993],"default":0.30000000000000001}`
+	s, err = schema.Read(strings.NewReader(numbers), schema.WithRoundTrip(true), schema.WithUseNumber(false))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+	output, err = json.Marshal(s)
+	if !assert.NoError(t, err, "json.Marshal should succeed") {
+		return
+	}
+	if !assert.Equal(t, semanticJSON(t, []byte(numbers)), semanticJSON(t, output), "numbers should be the same in round-trip mode") {
+		return
+	}
+
+	s.Description = "added later"
+	output, err = json.Marshal(s)
+	if !assert.NoError(t, err, "json.Marshal should succeed") {
+		return
+	}
+	if !assert.Contains(t, string(output), `"description":"added later"`, "keywords set after reading should be emitted") {
+		return
+	}
+}
+
+// TestRoundTripGolden uses the schemas under test/ as golden files:
+// reading and writing each of them should produce the same content,
+// with keys in the same order
+func TestRoundTripGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("test", "*.json"))
+	if !assert.NoError(t, err, "filepath.Glob should succeed") {
+		return
+	}
+
+	for _, file := range files {
+		if strings.Contains(file, "_pass") || strings.Contains(file, "_fail") {
+			continue
+		}
+
+		golden, err := ioutil.ReadFile(file)
+		if !assert.NoError(t, err, "ioutil.ReadFile(%s) should succeed", file) {
+			return
+		}
+
+		s, err := schema.Read(bytes.NewReader(golden), schema.WithPreserveKeyOrder(true))
+		if !assert.NoError(t, err, "schema.Read(%s) should succeed", file) {
+			return
+		}
+
+		output, err := json.MarshalIndent(s, "", "  ")
+		if !assert.NoError(t, err, "json.MarshalIndent(%s) should succeed", file) {
+			return
+		}
+
+		if !assert.Equal(t, semanticJSON(t, golden), semanticJSON(t, output), "content of %s should be preserved", file) {
+			return
+		}
+		if !assert.Equal(t, jsonKeys(t, golden), jsonKeys(t, output), "key order of %s should be preserved", file) {
+			return
+		}
+	}
+}
+
+// semanticJSON decodes `data`, with numbers as json.Number so that
+// they are compared exactly
+func semanticJSON(t *testing.T, data []byte) interface{} {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if !assert.NoError(t, dec.Decode(&v), "Decode should succeed") {
+		t.FailNow()
+	}
+	return v
+}
+
+// jsonKeys returns the keys of all objects in `data`, in the order
+// that they appear. Values of "enum" and "default" are skipped, as
+// the key order of arbitrary values is not preserved
+func jsonKeys(t *testing.T, data []byte) []string {
+	var keys []string
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	var walk func(bool) error
+	walk = func(record bool) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		delim, ok := tok.(json.Delim)
+		if !ok {
+			return nil
+		}
+
+		for dec.More() {
+			if delim == '{' {
+				tok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				k := tok.(string)
+				if record {
+					keys = append(keys, k)
+				}
+				if err := walk(record && k != "enum" && k != "default"); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := walk(record); err != nil {
+				return err
+			}
+		}
+
+		_, err = dec.Token()
+		return err
+	}
+
+	if !assert.NoError(t, walk(true), "decoding tokens should succeed") {
+		t.FailNow()
+	}
+	return keys
+}
+
+func TestNumberPrecision(t *testing.T) {
+	const src = `{
+  "maximum": 9007199254740993,
+  "multipleOf": 0.01,
+  "maxLength": 9007199254740993,
+  "enum": [9007199254740993, 1.10],
+  "default": 12345678901234567890
+}`
+
+	s, err := schema.Read(strings.NewReader(src))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+	if !assert.Equal(t, "9007199254740993", s.Maximum.Rat().RatString(), "maximum should be exact") {
+		return
+	}
+	if !assert.Equal(t, "1/100", s.MultipleOf.Rat().RatString(), "multipleOf should be exact") {
+		return
+	}
+	if !assert.Equal(t, 9007199254740993, s.MaxLength.Val, "maxLength should be exact") {
+		return
+	}
+	if !assert.Equal(t, json.Number("9007199254740993"), s.Enum[0], "enum values should be json.Number by default") {
+		return
+	}
+	if !assert.Equal(t, json.Number("12345678901234567890"), s.Default, "default should be json.Number by default") {
+		return
+	}
+	if !assert.True(t, s.EnumContains(json.Number("9007199254740993")), "exact value should be in enum") {
+		return
+	}
+	if !assert.True(t, s.EnumContains(1.1), "equivalent float64 should be in enum") {
+		return
+	}
+	if !assert.False(t, s.EnumContains(json.Number("9007199254740992")), "close value should not be in enum") {
+		return
+	}
+
+	output, err := json.Marshal(s)
+	if !assert.NoError(t, err, "json.Marshal should succeed") {
+		return
+	}
+	for _, literal := range []string{`"maximum":9007199254740993`, `"multipleOf":0.01`, `"default":12345678901234567890`, `"enum":[9007199254740993,1.10]`} {
+		if !assert.Contains(t, string(output), literal, "output should contain exact number") {
+			return
+		}
+	}
+
+	s.Maximum.Val = 10
+	output, err = json.Marshal(s)
+	if !assert.NoError(t, err, "json.Marshal should succeed") {
+		return
+	}
+	if !assert.Contains(t, string(output), `"maximum":10`, "modified numbers should be emitted") {
+		return
+	}
+
+	s, err = schema.Read(strings.NewReader(src), schema.WithUseNumber(false))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+	if !assert.IsType(t, float64(0), s.Enum[0], "enum values should be float64 with WithUseNumber(false)") {
+		return
+	}
+}
+
+func TestValidateNumberPrecision(t *testing.T) {
+	const src = `{
+  "type": "object",
+  "properties": {
+    "id": {"type": "integer", "enum": [9007199254740993, 1]}
+  }
+}`
+
+	s, err := schema.Read(strings.NewReader(src), schema.WithUseNumber(true))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	decode := func(data string) interface{} {
+		var v interface{}
+		dec := json.NewDecoder(strings.NewReader(data))
+		dec.UseNumber()
+		if !assert.NoError(t, dec.Decode(&v), "Decode should succeed") {
+			t.FailNow()
+		}
+		return v
+	}
+
+	v := validator.New(s)
+	for _, data := range []string{`{"id": 9007199254740993}`, `{"id": 1}`, `{}`} {
+		if !assert.NoError(t, v.Validate(decode(data)), "Validate should succeed for %s", data) {
+			return
+		}
+	}
+
+	err = v.Validate(decode(`{"id": 9007199254740992}`))
+	if !assert.Error(t, err, "Validate should fail for a value that is only equal as float64") {
+		return
+	}
+	verr, ok := err.(*validator.Error)
+	if !assert.True(t, ok, "error should be a validator.Error") {
+		return
+	}
+	if !assert.Equal(t, "#/id", verr.Pointer, "pointer to the value should match") {
+		return
+	}
+	if !assert.Equal(t, "4:31", verr.Position.String(), "position of enum should match") {
+		return
+	}
+}
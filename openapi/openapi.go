@@ -0,0 +1,498 @@
+// Package openapi converts between the schema objects of OpenAPI 3.0
+// documents and JSON schemas.
+//
+// OpenAPI 3.0 uses a dialect of JSON Schema: instead of lists of types,
+// schemas may be marked as "nullable", references point within
+// "#/components/schemas", and keywords such as "patternProperties" or
+// "dependencies" are not supported. Keywords that only annotate schemas,
+// such as "discriminator", "readOnly", "writeOnly" or "example", are kept
+// as extra fields of the JSON schemas, and restored when converting back.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/pkg/errors"
+)
+
+const componentsPrefix = "#/components/schemas/"
+
+// ReadFile reads the OpenAPI document in the file `name`, and returns
+// the schemas within its components. See Extract.
+func ReadFile(name string) (map[string]*schema.Schema, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %s", name)
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read reads an OpenAPI document, written in JSON or YAML, from `in`,
+// and returns the schemas within its components. See Extract.
+func Read(in io.Reader) (map[string]*schema.Schema, error) {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read OpenAPI document")
+	}
+
+	// YAML is a superset of JSON, so both can be decoded alike
+	v, err := schema.DecodeYAML(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode OpenAPI document")
+	}
+	doc, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("OpenAPI document must be an object")
+	}
+	return Extract(doc)
+}
+
+// Extract converts the schemas in "components/schemas" of the OpenAPI
+// 3.0 document `doc`, as decoded by encoding/json, into JSON schemas,
+// keyed by their names. The schemas are the definitions of a common
+// root schema, so that references such as "#/components/schemas/Pet",
+// which are converted to "#/definitions/Pet", can be resolved.
+//
+// Nullable schemas accept null in addition to their types: their type
+// becomes a list that includes "null", and null is added to their enum,
+// if any, as not every document lists it there as OpenAPI 3.0.3
+// requires. Schemas that are nullable but have no type are converted
+// to {"anyOf": [{"type": "null"}, ...]}.
+func Extract(doc map[string]interface{}) (map[string]*schema.Schema, error) {
+	version, _ := doc["openapi"].(string)
+	if !strings.HasPrefix(version, "3.0.") {
+		return nil, errors.Errorf("unsupported OpenAPI version %q: only 3.0 documents are supported", version)
+	}
+
+	var components map[string]interface{}
+	if v, ok := doc["components"]; ok {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("components must be an object")
+		}
+		if v, ok := m["schemas"]; ok {
+			if components, ok = v.(map[string]interface{}); !ok {
+				return nil, errors.New("components/schemas must be an object")
+			}
+		}
+	}
+
+	definitions := make(map[string]interface{}, len(components))
+	for name, v := range components {
+		c, err := fromOpenAPI(v, componentsPrefix+escapeToken(name))
+		if err != nil {
+			return nil, err
+		}
+		definitions[name] = c
+	}
+
+	buf, err := json.Marshal(map[string]interface{}{"definitions": definitions})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode converted schemas")
+	}
+	root, err := schema.Read(bytes.NewReader(buf))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read converted schemas")
+	}
+
+	schemas := make(map[string]*schema.Schema, len(root.Definitions))
+	for name, s := range root.Definitions {
+		schemas[name] = s
+	}
+	return schemas, nil
+}
+
+// fromOpenAPI converts the OpenAPI schema object `v`, located at `ptr`,
+// into a JSON schema
+func fromOpenAPI(v interface{}, ptr string) (interface{}, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("schema at %s must be an object", ptr)
+	}
+
+	// Keywords next to references are ignored in OpenAPI 3.0
+	if ref, ok := m["$ref"].(string); ok {
+		ref, err := fromRef(ref, ptr)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"$ref": ref}, nil
+	}
+
+	out := make(map[string]interface{}, len(m))
+	for k, e := range m {
+		out[k] = e
+	}
+	if err := convertSubschemas(out, ptr, fromOpenAPI); err != nil {
+		return nil, err
+	}
+	if err := convertMapping(out, ptr, fromRef); err != nil {
+		return nil, err
+	}
+
+	nullable, _ := out["nullable"].(bool)
+	delete(out, "nullable")
+	if !nullable {
+		return out, nil
+	}
+
+	if enum, ok := out["enum"].([]interface{}); ok && !containsNull(enum) {
+		out["enum"] = append(append([]interface{}{}, enum...), nil)
+	}
+	if t, ok := out["type"].(string); ok {
+		out["type"] = []interface{}{t, "null"}
+		return out, nil
+	}
+	if _, ok := out["enum"]; ok {
+		return out, nil
+	}
+
+	// Annotations stay with the outer schema
+	wrapper := map[string]interface{}{}
+	for _, k := range annotations {
+		if e, ok := out[k]; ok {
+			wrapper[k] = e
+			delete(out, k)
+		}
+	}
+	wrapper["anyOf"] = []interface{}{map[string]interface{}{"type": "null"}, out}
+	return wrapper, nil
+}
+
+// annotations are the keywords that describe a schema, rather than
+// constrain its values
+var annotations = []string{"title", "description", "default", "example", "readOnly", "writeOnly", "deprecated", "externalDocs", "xml"}
+
+// fromRef converts an OpenAPI reference into a JSON schema reference
+func fromRef(ref, ptr string) (string, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return ref, nil
+	}
+	if !strings.HasPrefix(ref, componentsPrefix) {
+		return "", errors.Errorf("reference %q at %s does not point within components/schemas", ref, ptr)
+	}
+	return "#/definitions/" + strings.TrimPrefix(ref, componentsPrefix), nil
+}
+
+// convertSubschemas applies `convert` to the subschemas of `m`, in place
+func convertSubschemas(m map[string]interface{}, ptr string, convert func(interface{}, string) (interface{}, error)) error {
+	if props, ok := m["properties"].(map[string]interface{}); ok {
+		out := make(map[string]interface{}, len(props))
+		for name, v := range props {
+			c, err := convert(v, ptr+"/properties/"+escapeToken(name))
+			if err != nil {
+				return err
+			}
+			out[name] = c
+		}
+		m["properties"] = out
+	}
+
+	for _, k := range []string{"additionalProperties", "items", "not"} {
+		if v, ok := m[k].(map[string]interface{}); ok {
+			c, err := convert(v, ptr+"/"+k)
+			if err != nil {
+				return err
+			}
+			m[k] = c
+		}
+	}
+
+	for _, k := range []string{"allOf", "anyOf", "oneOf"} {
+		l, ok := m[k].([]interface{})
+		if !ok {
+			continue
+		}
+		out := make([]interface{}, len(l))
+		for i, v := range l {
+			c, err := convert(v, fmt.Sprintf("%s/%s/%d", ptr, k, i))
+			if err != nil {
+				return err
+			}
+			out[i] = c
+		}
+		m[k] = out
+	}
+	return nil
+}
+
+// convertMapping applies `convert` to the references in the mapping of
+// the discriminator of `m`, if any
+func convertMapping(m map[string]interface{}, ptr string, convert func(string, string) (string, error)) error {
+	d, ok := m["discriminator"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	mapping, ok := d["mapping"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	out := make(map[string]interface{}, len(mapping))
+	for k, v := range mapping {
+		ref, ok := v.(string)
+		// Values may also be names of schemas
+		if !ok || !strings.Contains(ref, "/") {
+			out[k] = v
+			continue
+		}
+		c, err := convert(ref, ptr+"/discriminator/mapping/"+escapeToken(k))
+		if err != nil {
+			return err
+		}
+		out[k] = c
+	}
+
+	dc := make(map[string]interface{}, len(d))
+	for k, v := range d {
+		dc[k] = v
+	}
+	dc["mapping"] = out
+	m["discriminator"] = dc
+	return nil
+}
+
+// Convert converts JSON schemas into OpenAPI 3.0 schema objects, which
+// can be used as "components/schemas" of an OpenAPI document. It is the
+// reverse of Extract: references to definitions are converted to
+// references to components, and lists of types that include "null"
+// are converted to nullable types. Other lists of types are converted
+// to {"anyOf": [{"type": ...}, ...]}.
+//
+// The definitions of the schemas become components too, named after
+// the definitions. Convert fails if they conflict with each other, or
+// if the schemas use keywords that OpenAPI 3.0 does not support, such
+// as "patternProperties", "dependencies" or tuples of items.
+func Convert(schemas map[string]*schema.Schema) (map[string]interface{}, error) {
+	components := make(map[string]interface{})
+	add := func(name string, v interface{}) error {
+		if prev, ok := components[name]; ok && !reflect.DeepEqual(prev, v) {
+			return errors.Errorf("conflicting schemas named %q", name)
+		}
+		components[name] = v
+		return nil
+	}
+
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		buf, err := json.Marshal(schemas[name])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to encode schema %q", name)
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(buf, &m); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode schema %q", name)
+		}
+
+		c := converter{root: name}
+		if defs, ok := m["definitions"].(map[string]interface{}); ok {
+			delete(m, "definitions")
+			for def, v := range defs {
+				d, err := c.toOpenAPI(v, "#/definitions/"+escapeToken(def))
+				if err != nil {
+					return nil, errors.Wrapf(err, "failed to convert schema %q", name)
+				}
+				if err := add(def, d); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		v, err := c.toOpenAPI(m, "#")
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to convert schema %q", name)
+		}
+		if err := add(name, v); err != nil {
+			return nil, err
+		}
+	}
+	return components, nil
+}
+
+// unsupported lists the keywords that OpenAPI 3.0 does not support
+var unsupported = []string{"additionalItems", "dependencies", "patternProperties"}
+
+type converter struct {
+	// name of the component that the root schema becomes
+	root string
+}
+
+// toOpenAPI converts the JSON schema `v`, located at `ptr`, into an
+// OpenAPI schema object
+func (c converter) toOpenAPI(v interface{}, ptr string) (interface{}, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("schema at %s must be an object", ptr)
+	}
+
+	if ref, ok := m["$ref"].(string); ok {
+		ref, err := c.toRef(ref, ptr)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"$ref": ref}, nil
+	}
+
+	for _, k := range unsupported {
+		if _, ok := m[k]; ok {
+			return nil, errors.Errorf("%s at %s is not supported by OpenAPI 3.0", k, ptr)
+		}
+	}
+	if _, ok := m["items"].([]interface{}); ok {
+		return nil, errors.Errorf("tuple of items at %s is not supported by OpenAPI 3.0", ptr)
+	}
+	if _, ok := m["definitions"]; ok {
+		return nil, errors.Errorf("definitions at %s are not supported by OpenAPI 3.0", ptr)
+	}
+
+	out := make(map[string]interface{}, len(m))
+	for k, e := range m {
+		switch k {
+		case "$schema", "id":
+		default:
+			out[k] = e
+		}
+	}
+	if err := convertSubschemas(out, ptr, c.toOpenAPI); err != nil {
+		return nil, err
+	}
+	if err := convertMapping(out, ptr, c.toRef); err != nil {
+		return nil, err
+	}
+
+	nullable := false
+	if t, ok := out["type"].(string); ok {
+		out["type"] = []interface{}{t}
+	}
+	if types, ok := out["type"].([]interface{}); ok {
+		var l []interface{}
+		for _, t := range types {
+			if t == "null" {
+				nullable = true
+				continue
+			}
+			l = append(l, t)
+		}
+
+		delete(out, "type")
+		switch len(l) {
+		case 0:
+			if _, ok := out["enum"]; !ok {
+				out["enum"] = []interface{}{nil}
+			}
+		case 1:
+			out["type"] = l[0]
+		default:
+			alternatives := make([]interface{}, len(l))
+			for i, t := range l {
+				alternatives[i] = map[string]interface{}{"type": t}
+			}
+			if _, ok := out["anyOf"]; ok {
+				allOf, _ := out["allOf"].([]interface{})
+				out["allOf"] = append(allOf, map[string]interface{}{"anyOf": alternatives})
+			} else {
+				out["anyOf"] = alternatives
+			}
+		}
+	}
+	if enum, ok := out["enum"].([]interface{}); ok && containsNull(enum) {
+		nullable = true
+	}
+
+	// {"anyOf": [{"type": "null"}, X]} is the nullable form of X. The
+	// alternatives have already been converted at this point
+	if l, ok := out["anyOf"].([]interface{}); ok && len(l) == 2 {
+		for i, e := range l {
+			if !reflect.DeepEqual(e, nullOnly) {
+				continue
+			}
+			if merged, ok := mergeSchema(out, l[1-i]); ok {
+				out = merged
+				nullable = true
+			}
+			break
+		}
+	}
+
+	if nullable {
+		out["nullable"] = true
+	}
+	return out, nil
+}
+
+// nullOnly is the OpenAPI equivalent of {"type": "null"}
+var nullOnly = map[string]interface{}{"enum": []interface{}{nil}, "nullable": true}
+
+// mergeSchema returns the schema `m` with its "anyOf" replaced by the
+// keywords of `v`, if they do not overlap. References are kept in
+// "allOf", as OpenAPI ignores keywords next to them.
+func mergeSchema(m map[string]interface{}, v interface{}) (map[string]interface{}, bool) {
+	sub, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	out := make(map[string]interface{}, len(m)+len(sub))
+	for k, e := range m {
+		if k != "anyOf" {
+			out[k] = e
+		}
+	}
+
+	if _, ok := sub["$ref"]; ok {
+		allOf, _ := out["allOf"].([]interface{})
+		out["allOf"] = append(allOf, sub)
+		return out, true
+	}
+	for k, e := range sub {
+		if _, ok := out[k]; ok {
+			return nil, false
+		}
+		out[k] = e
+	}
+	return out, true
+}
+
+// toRef converts a JSON schema reference into an OpenAPI reference
+func (c converter) toRef(ref, ptr string) (string, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return ref, nil
+	}
+
+	if rest := strings.TrimPrefix(ref, "#/definitions/"); rest != ref {
+		return componentsPrefix + rest, nil
+	}
+	if ref == "#" || strings.HasPrefix(ref, "#/") {
+		return componentsPrefix + escapeToken(c.root) + strings.TrimPrefix(ref, "#"), nil
+	}
+	return "", errors.Errorf("reference %q at %s cannot be converted", ref, ptr)
+}
+
+func containsNull(l []interface{}) bool {
+	for _, v := range l {
+		if v == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeToken escapes `s` to be used as a JSON pointer reference token
+func escapeToken(s string) string {
+	return strings.TrimPrefix(schema.Pointer("").Append(s).String(), "/")
+}
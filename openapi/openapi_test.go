@@ -0,0 +1,248 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/lestrrat-go/jsschema/openapi"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+const petStore = `openapi: 3.0.3
+info:
+  title: Pet store
+  version: 1.0.0
+paths:
+  /pets:
+    get:
+      responses:
+        200:
+          description: The pets
+components:
+  schemas:
+    Pet:
+      oneOf:
+        - $ref: '#/components/schemas/Cat'
+        - $ref: '#/components/schemas/Dog'
+      discriminator:
+        propertyName: kind
+        mapping:
+          cat: '#/components/schemas/Cat'
+          dog: Dog
+    Cat:
+      type: object
+      required: [kind]
+      properties:
+        kind:
+          type: string
+          enum: [cat]
+        name:
+          type: string
+          nullable: true
+          readOnly: true
+          example: Tom
+        color:
+          type: string
+          enum: [black, white, null]
+          nullable: true
+        owner:
+          allOf:
+            - $ref: '#/components/schemas/Owner'
+          nullable: true
+          description: The owner, if any
+    Dog:
+      type: object
+      properties:
+        kind:
+          type: string
+        tags:
+          type: array
+          items:
+            type: string
+        extra:
+          type: object
+          additionalProperties:
+            type: integer
+            minimum: 0
+    Owner:
+      type: object
+      properties:
+        pet:
+          $ref: '#/components/schemas/Pet'
+`
+
+func TestRead(t *testing.T) {
+	schemas, err := openapi.Read(strings.NewReader(petStore))
+	if !assert.NoError(t, err, "openapi.Read should succeed") {
+		return
+	}
+	if !assert.Len(t, schemas, 4, "all components should be extracted") {
+		return
+	}
+
+	cat := schemas["Cat"]
+	name := cat.Properties["name"]
+	if !assert.Equal(t, schema.PrimitiveTypes{schema.StringType, schema.NullType}, name.Type, "nullable type should include null") {
+		return
+	}
+	if !assert.Equal(t, true, name.Extras["readOnly"], "readOnly should be kept") {
+		return
+	}
+	if !assert.Equal(t, "Tom", name.Extras["example"], "example should be kept") {
+		return
+	}
+	if !assert.Equal(t, []interface{}{"black", "white", nil}, cat.Properties["color"].Enum, "nullable enum should include null") {
+		return
+	}
+
+	owner := cat.Properties["owner"]
+	if !assert.Equal(t, "The owner, if any", owner.Description, "annotations should stay with the outer schema") {
+		return
+	}
+	if !assert.Len(t, owner.AnyOf, 2, "nullable schema without type should become anyOf") {
+		return
+	}
+	if !assert.Equal(t, schema.PrimitiveTypes{schema.NullType}, owner.AnyOf[0].Type, "first alternative should be null") {
+		return
+	}
+	target, err := owner.AnyOf[1].AllOf[0].Resolve(nil)
+	if !assert.NoError(t, err, "reference should resolve") {
+		return
+	}
+	if !assert.Equal(t, schemas["Owner"], target, "reference should point to the converted component") {
+		return
+	}
+
+	pet := schemas["Pet"]
+	if !assert.Equal(t, "#/definitions/Cat", pet.OneOf[0].Reference, "reference should be converted") {
+		return
+	}
+	mapping := pet.Extras["discriminator"].(map[string]interface{})["mapping"]
+	if !assert.Equal(t, map[string]interface{}{"cat": "#/definitions/Cat", "dog": "Dog"}, mapping, "discriminator mapping should be converted") {
+		return
+	}
+}
+
+func TestReadNullableEnum(t *testing.T) {
+	schemas, err := openapi.Read(strings.NewReader(`{
+  "openapi": "3.0.0",
+  "components": {"schemas": {"Size": {"type": "string", "enum": ["small", "large"], "nullable": true}}}
+}`))
+	if !assert.NoError(t, err, "openapi.Read should succeed") {
+		return
+	}
+	if !assert.Equal(t, []interface{}{"small", "large", nil}, schemas["Size"].Enum, "null should be added to the enum") {
+		return
+	}
+}
+
+func TestConvert(t *testing.T) {
+	schemas, err := openapi.Read(strings.NewReader(petStore))
+	if !assert.NoError(t, err, "openapi.Read should succeed") {
+		return
+	}
+
+	components, err := openapi.Convert(schemas)
+	if !assert.NoError(t, err, "openapi.Convert should succeed") {
+		return
+	}
+
+	// Converting back should produce the original components
+	var doc struct {
+		Components struct {
+			Schemas map[string]interface{} `yaml:"schemas"`
+		} `yaml:"components"`
+	}
+	if !assert.NoError(t, yaml.Unmarshal([]byte(petStore), &doc), "yaml.Unmarshal should succeed") {
+		return
+	}
+	if !assert.Equal(t, normalize(t, doc.Components.Schemas), normalize(t, components), "components should round trip") {
+		return
+	}
+}
+
+func TestConvertTypes(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(`{
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {"id": {"type": ["integer", "string"]}},
+  "type": "object",
+  "properties": {
+    "id": {"$ref": "#/definitions/id"},
+    "parent": {"$ref": "#"},
+    "none": {"type": "null"}
+  }
+}`))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	components, err := openapi.Convert(map[string]*schema.Schema{"Node": s})
+	if !assert.NoError(t, err, "openapi.Convert should succeed") {
+		return
+	}
+
+	expected := map[string]interface{}{
+		"id": map[string]interface{}{
+			"anyOf": []interface{}{map[string]interface{}{"type": "integer"}, map[string]interface{}{"type": "string"}},
+		},
+		"Node": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"id":     map[string]interface{}{"$ref": "#/components/schemas/id"},
+				"parent": map[string]interface{}{"$ref": "#/components/schemas/Node"},
+				"none":   map[string]interface{}{"enum": []interface{}{nil}, "nullable": true},
+			},
+		},
+	}
+	if !assert.Equal(t, normalize(t, expected), normalize(t, components), "components should match") {
+		return
+	}
+}
+
+func TestConvertUnsupported(t *testing.T) {
+	for _, src := range []string{
+		`{"patternProperties": {"^x-": {}}}`,
+		`{"dependencies": {"a": ["b"]}}`,
+		`{"items": [{"type": "string"}]}`,
+		`{"properties": {"a": {"definitions": {"b": {}}}}}`,
+	} {
+		s, err := schema.Read(strings.NewReader(src))
+		if !assert.NoError(t, err, "schema.Read should succeed") {
+			return
+		}
+		_, err = openapi.Convert(map[string]*schema.Schema{"Root": s})
+		if !assert.Error(t, err, "openapi.Convert should fail for %s", src) {
+			return
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	for _, src := range []string{
+		`{"swagger": "2.0"}`,
+		`{"openapi": "3.1.0"}`,
+		`{"openapi": "3.0.0", "components": {"schemas": {"A": {"$ref": "#/components/parameters/B"}}}}`,
+		`{"openapi": "3.0.0", "components": {"schemas": {"A": []}}}`,
+	} {
+		_, err := openapi.Read(strings.NewReader(src))
+		if !assert.Error(t, err, "openapi.Read should fail for %s", src) {
+			return
+		}
+	}
+}
+
+// normalize converts `v` into the values that encoding/json produces
+func normalize(t *testing.T, v interface{}) interface{} {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to encode: %s", err)
+	}
+	var out interface{}
+	if err := json.Unmarshal(buf, &out); err != nil {
+		t.Fatalf("failed to decode: %s", err)
+	}
+	return out
+}
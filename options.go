@@ -0,0 +1,81 @@
+package schema
+
+import "github.com/lestrrat-go/jsschema/internal/option"
+
+// ReadOption is an option that can be passed to Read and ReadFile
+type ReadOption interface {
+	option.Interface
+}
+
+const (
+	optkeyFollowReferences  = "follow-references"
+	optkeyLeave             = "leave"
+	optkeyLenient           = "lenient"
+	optkeyPreserveKeyOrder  = "preserve-key-order"
+	optkeyResolveReferences = "resolve-references"
+	optkeyRoundTrip         = "round-trip"
+	optkeyUseNumber         = "use-number"
+)
+
+// WithResolveReferences specifies if every reference in the schema
+// should be resolved as soon as the schema has been decoded. When
+// enabled, Read fails with a ReferenceErrors value listing each
+// reference that could not be resolved, along with its location.
+//
+// By default references are resolved lazily, when they are first used.
+func WithResolveReferences(b bool) ReadOption {
+	return option.New(optkeyResolveReferences, b)
+}
+
+// WithLenient specifies if parsing should carry on after encountering
+// problems in the schema, such as values of the wrong type or invalid
+// regular expressions. When enabled and problems are found, Read returns
+// a best-effort Schema along with a ParseErrors value that lists every
+// problem, each with its JSON pointer. Malformed JSON is still reported
+// as a single error, without a Schema.
+//
+// By default Read fails on the first problem that it encounters.
+func WithLenient(b bool) ReadOption {
+	return option.New(optkeyLenient, b)
+}
+
+// WithRoundTrip specifies if the schema should remember exactly which
+// keywords were present in the source. When enabled, MarshalJSON
+// reproduces the same content that was read: keywords whose values
+// are indistinguishable from their defaults once parsed (e.g.
+// `"additionalProperties": true`, `"multipleOf": 0`, `"default": null`,
+// or empty lists) are emitted as they were, and no implicit values
+// such as `"additionalProperties": false` are added.
+//
+// Numbers within arbitrary values are kept exactly, regardless of
+// WithUseNumber. Keywords that are set after the schema has been read
+// are emitted as they normally would be.
+func WithRoundTrip(b bool) ReadOption {
+	return option.New(optkeyRoundTrip, b)
+}
+
+// WithPreserveKeyOrder specifies if MarshalJSON should emit keywords,
+// as well as the names within "definitions", "properties",
+// "patternProperties" and "dependencies", in the same order that they
+// appeared in the source. It implies WithRoundTrip(true). Keys that
+// were not present in the source are emitted after the rest, in sorted
+// order. The order of keys within other values such as "enum" or
+// "default" is not preserved.
+func WithPreserveKeyOrder(b bool) ReadOption {
+	return option.New(optkeyPreserveKeyOrder, b)
+}
+
+// WithUseNumber specifies if numbers within arbitrary values, such as
+// those in "enum", "default" and extra fields, should be stored as
+// json.Number, so that large integers and decimal fractions are kept
+// exactly as they were written. This is the default.
+//
+// WithUseNumber(false) stores them as float64 instead, as they would
+// be if the schema was decoded by encoding/json, for code that expects
+// these Go types. The validator package accepts either representation.
+//
+// Numeric keywords such as "minimum" always keep their exact
+// representation in Number.Exact, regardless of this option.
+func WithUseNumber(b bool) ReadOption {
+	return option.New(optkeyUseNumber, b)
+}
@@ -0,0 +1,233 @@
+package schema
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Pointer is a JSON pointer in the form that Schema.Pointer returns,
+// e.g. "#/properties/address/properties/zip". Reference tokens are
+// escaped as described in RFC 6901, but not percent-encoded.
+type Pointer string
+
+// String returns the pointer as a string
+func (p Pointer) String() string {
+	return string(p)
+}
+
+// Append returns a new pointer with the given reference tokens
+// appended, escaping them as necessary
+func (p Pointer) Append(tokens ...string) Pointer {
+	return Pointer(appendPointer(string(p), tokens...))
+}
+
+// Tokens returns the unescaped reference tokens of the pointer
+func (p Pointer) Tokens() ([]string, error) {
+	return splitPointer(strings.TrimPrefix(string(p), "#"))
+}
+
+// appendPointer appends the given reference tokens to the JSON pointer
+// `ptr`, escaping them as necessary.
+func appendPointer(ptr string, tokens ...string) string {
+	for _, tok := range tokens {
+		tok = strings.Replace(tok, "~", "~0", -1)
+		tok = strings.Replace(tok, "/", "~1", -1)
+		ptr = ptr + "/" + tok
+	}
+	return ptr
+}
+
+// splitPointer splits a JSON pointer into its unescaped reference
+// tokens. The pointer may be given in its URI fragment representation
+// (e.g. "#/definitions/foo"), in which case it is percent-decoded.
+func splitPointer(ptr string) ([]string, error) {
+	fragment := strings.HasPrefix(ptr, "#")
+	if fragment {
+		ptr = ptr[1:]
+	}
+
+	if ptr == "" {
+		return nil, nil
+	}
+
+	if ptr[0] != '/' {
+		return nil, errors.Errorf("invalid JSON pointer %s: must start with '/'", strconv.Quote(ptr))
+	}
+
+	tokens := strings.Split(ptr[1:], "/")
+	for i, tok := range tokens {
+		if fragment {
+			v, err := url.PathUnescape(tok)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to unescape token %s", strconv.Quote(tok))
+			}
+			tok = v
+		}
+		tok = strings.Replace(tok, "~1", "/", -1)
+		tok = strings.Replace(tok, "~0", "~", -1)
+		tokens[i] = tok
+	}
+	return tokens, nil
+}
+
+// Pointer returns the JSON pointer, in its URI fragment representation,
+// that locates this schema within its root schema. For example, the
+// schema for the "zip" property of the "address" property would be
+// located at "#/properties/address/properties/zip".
+func (s *Schema) Pointer() string {
+	return "#" + s.pointerFrom(nil)
+}
+
+// AbsoluteURI returns the URI of this schema, composed of the id of the
+// closest schema in the hierarchy that declares one, and the JSON pointer
+// from that schema to this one (e.g. "http://example.com/root.json#/definitions/foo").
+// If no schema in the hierarchy declares an id, the result is the same
+// as that of Pointer.
+func (s *Schema) AbsoluteURI() string {
+	res := s.resourceRoot()
+
+	var base string
+	if res.ID != "" && !isAnchorID(res.ID) {
+		base = canonicalID(res.parent, res.ID)
+	}
+	return base + "#" + s.pointerFrom(res)
+}
+
+// pointerFrom returns the JSON pointer from `ancestor` to this schema.
+// If `ancestor` is nil, the pointer from the root schema is returned
+func (s *Schema) pointerFrom(ancestor *Schema) string {
+	var schemas []*Schema
+	for cur := s; cur != ancestor && cur.parent != nil; cur = cur.parent {
+		schemas = append(schemas, cur)
+	}
+
+	var ptr string
+	for i := len(schemas) - 1; i >= 0; i-- {
+		ptr = appendPointer(ptr, schemas[i].location...)
+	}
+	return ptr
+}
+
+// Lookup returns the subschema located at the JSON pointer `ptr`,
+// relative to this schema. The pointer may be given either as a plain
+// JSON pointer ("/properties/foo") or as a URI fragment
+// ("#/properties/foo").
+//
+// Unlike Resolve, Lookup navigates the typed schema tree directly,
+// and does not follow any references.
+func (s *Schema) Lookup(ptr string) (*Schema, error) {
+	tokens, err := splitPointer(ptr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse JSON pointer")
+	}
+
+	cur := s
+	for i := 0; i < len(tokens); i++ {
+		keyword := tokens[i]
+
+		// Most keywords are followed by a name or an index
+		var arg string
+		var hasArg bool
+		next := func() (string, error) {
+			if i+1 >= len(tokens) {
+				return "", errors.Errorf("missing reference token after %s", strconv.Quote(keyword))
+			}
+			i++
+			return tokens[i], nil
+		}
+
+		var child *Schema
+		switch keyword {
+		case "definitions", "properties", "patternProperties", "dependencies":
+			if arg, err = next(); err != nil {
+				return nil, err
+			}
+			hasArg = true
+			switch keyword {
+			case "definitions":
+				child = cur.Definitions[arg]
+			case "properties":
+				child = cur.Properties[arg]
+			case "dependencies":
+				child = cur.Dependencies.Schemas[arg]
+			case "patternProperties":
+				for rx, v := range cur.PatternProperties {
+					if rx.String() == arg {
+						child = v
+						break
+					}
+				}
+			}
+		case "allOf", "anyOf", "oneOf":
+			if arg, err = next(); err != nil {
+				return nil, err
+			}
+			hasArg = true
+			var list SchemaList
+			switch keyword {
+			case "allOf":
+				list = cur.AllOf
+			case "anyOf":
+				list = cur.AnyOf
+			case "oneOf":
+				list = cur.OneOf
+			}
+			child, err = list.at(arg)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to lookup %s", strconv.Quote(keyword))
+			}
+		case "items":
+			if items := cur.Items; items != nil {
+				if items.TupleMode {
+					if arg, err = next(); err != nil {
+						return nil, err
+					}
+					hasArg = true
+					child, err = items.Schemas.at(arg)
+					if err != nil {
+						return nil, errors.Wrap(err, "failed to lookup 'items'")
+					}
+				} else if len(items.Schemas) > 0 {
+					child = items.Schemas[0]
+				}
+			}
+		case "additionalItems":
+			if v := cur.AdditionalItems; v != nil {
+				child = v.Schema
+			}
+		case "additionalProperties":
+			if v := cur.AdditionalProperties; v != nil {
+				child = v.Schema
+			}
+		case "not":
+			child = cur.Not
+		default:
+			return nil, errors.Errorf("unknown schema keyword %s in JSON pointer %s", strconv.Quote(keyword), strconv.Quote(ptr))
+		}
+
+		if child == nil {
+			name := keyword
+			if hasArg {
+				name = appendPointer(keyword, arg)
+			}
+			return nil, errors.Errorf("schema %s not found in JSON pointer %s", strconv.Quote(name), strconv.Quote(ptr))
+		}
+		cur = child
+	}
+	return cur, nil
+}
+
+func (l SchemaList) at(idx string) (*Schema, error) {
+	i, err := strconv.Atoi(idx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid array index %s", strconv.Quote(idx))
+	}
+
+	if i < 0 || i >= len(l) {
+		return nil, errors.Errorf("array index %d out of range", i)
+	}
+	return l[i], nil
+}
@@ -0,0 +1,142 @@
+package schema_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookup(t *testing.T) {
+	const src = `{
+  "definitions": {
+    "a/b": { "type": "string" },
+    "tilde~": { "type": "integer" }
+  },
+  "properties": {
+    "address": {
+      "properties": {
+        "zip": { "type": "string" }
+      },
+      "additionalProperties": { "type": "number" }
+    },
+    "tags": {
+      "items": { "type": "string" }
+    },
+    "pair": {
+      "items": [ { "type": "string" }, { "type": "integer" } ]
+    }
+  },
+  "patternProperties": {
+    "^x-": { "type": "boolean" }
+  },
+  "dependencies": {
+    "zip": { "required": ["address"] }
+  },
+  "allOf": [ {}, { "title": "second" } ],
+  "not": { "type": "null" }
+}`
+	s, err := schema.Read(strings.NewReader(src))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	tests := map[string]*schema.Schema{
+		"":                                    s,
+		"#":                                   s,
+		"/definitions/a~1b":                   s.Definitions["a/b"],
+		"#/definitions/tilde~0":               s.Definitions["tilde~"],
+		"#/properties/address/properties/zip": s.Properties["address"].Properties["zip"],
+		"/properties/address/additionalProperties": s.Properties["address"].AdditionalProperties.Schema,
+		"/properties/tags/items":                   s.Properties["tags"].Items.Schemas[0],
+		"/properties/pair/items/1":                 s.Properties["pair"].Items.Schemas[1],
+		"/dependencies/zip":                        s.Dependencies.Schemas["zip"],
+		"/allOf/1":                                 s.AllOf[1],
+		"/not":                                     s.Not,
+	}
+	for ptr, expected := range tests {
+		found, err := s.Lookup(ptr)
+		if !assert.NoError(t, err, "s.Lookup(%s) should succeed", ptr) {
+			return
+		}
+		if !assert.Equal(t, expected, found, "s.Lookup(%s) should return the expected schema", ptr) {
+			return
+		}
+	}
+
+	found, err := s.Lookup("#/patternProperties/%5Ex-")
+	if !assert.NoError(t, err, "s.Lookup should succeed for patternProperties") {
+		return
+	}
+	if !assert.Equal(t, schema.PrimitiveTypes{schema.BooleanType}, found.Type, "type should match") {
+		return
+	}
+
+	for _, ptr := range []string{"definitions", "/definitions/nope", "/allOf/2", "/allOf/x", "/items", "/title", "/properties"} {
+		_, err := s.Lookup(ptr)
+		if !assert.Error(t, err, "s.Lookup(%s) should fail", ptr) {
+			return
+		}
+	}
+}
+
+func TestPointer(t *testing.T) {
+	const src = `{
+  "id": "http://example.com/root.json",
+  "definitions": {
+    "other": {
+      "id": "other.json",
+      "properties": {
+        "a/b": { "type": "string" }
+      }
+    }
+  },
+  "properties": {
+    "address": {
+      "properties": {
+        "zip": { "type": "string" }
+      }
+    },
+    "pair": {
+      "items": [ { "type": "string" }, { "type": "integer" } ]
+    }
+  }
+}`
+	s, err := schema.Read(strings.NewReader(src))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	zip := s.Properties["address"].Properties["zip"]
+	if !assert.Equal(t, "#/properties/address/properties/zip", zip.Pointer(), "pointer should match") {
+		return
+	}
+	if !assert.Equal(t, "http://example.com/root.json#/properties/address/properties/zip", zip.AbsoluteURI(), "absolute URI should match") {
+		return
+	}
+
+	found, err := s.Lookup(zip.Pointer())
+	if !assert.NoError(t, err, "s.Lookup should succeed") {
+		return
+	}
+	if !assert.Equal(t, zip, found, "s.Lookup should return the same schema") {
+		return
+	}
+
+	if !assert.Equal(t, "#/properties/pair/items/1", s.Properties["pair"].Items.Schemas[1].Pointer(), "pointer should match") {
+		return
+	}
+
+	nested := s.Definitions["other"].Properties["a/b"]
+	if !assert.Equal(t, "#/definitions/other/properties/a~1b", nested.Pointer(), "pointer should match") {
+		return
+	}
+	if !assert.Equal(t, "http://example.com/other.json#/properties/a~1b", nested.AbsoluteURI(), "absolute URI should be relative to closest id") {
+		return
+	}
+
+	if !assert.Equal(t, "#", s.Pointer(), "pointer for root should match") {
+		return
+	}
+}
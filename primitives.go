@@ -3,6 +3,8 @@ package schema
 import (
 	"encoding/json"
 	"errors"
+	"math/big"
+	"reflect"
 )
 
 // UnmarshalJSON initializes the primitive type from
@@ -132,3 +134,95 @@ func (pt PrimitiveTypes) Less(i, j int) bool {
 func (pt PrimitiveTypes) Swap(i, j int) {
 	pt[i], pt[j] = pt[j], pt[i]
 }
+
+// Rat returns the exact value of the number. If the number was read
+// from a JSON document, the value is computed from its textual
+// representation, otherwise from Val
+func (n Number) Rat() *big.Rat {
+	if n.Exact != "" {
+		if f, err := n.Exact.Float64(); err == nil && f == n.Val {
+			if r, ok := new(big.Rat).SetString(n.Exact.String()); ok {
+				return r
+			}
+		}
+	}
+	return new(big.Rat).SetFloat64(n.Val)
+}
+
+// toRat converts a numeric value to its exact representation
+func toRat(v interface{}) (*big.Rat, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		return new(big.Rat).SetString(n.String())
+	case float64:
+		r := new(big.Rat).SetFloat64(n)
+		return r, r != nil
+	case float32:
+		r := new(big.Rat).SetFloat64(float64(n))
+		return r, r != nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Rat).SetInt64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(rv.Uint())), true
+	}
+	return nil, false
+}
+
+func isFloat(v interface{}) bool {
+	switch v.(type) {
+	case float32, float64:
+		return true
+	}
+	return false
+}
+
+// valuesEqual compares two arbitrary JSON values. Numbers are compared
+// by their exact values, regardless of their Go types. If either of
+// the numbers is a float, which may not hold the exact value to begin
+// with, they are compared as float64 instead
+func valuesEqual(a, b interface{}) bool {
+	if ra, ok := toRat(a); ok {
+		rb, ok := toRat(b)
+		if !ok {
+			return false
+		}
+
+		if isFloat(a) || isFloat(b) {
+			fa, _ := ra.Float64()
+			fb, _ := rb.Float64()
+			return fa == fb
+		}
+		return ra.Cmp(rb) == 0
+	}
+
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, x := range av {
+			y, ok := bv[k]
+			if !ok || !valuesEqual(x, y) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !valuesEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,566 @@
+package schema
+
+import (
+	"encoding"
+	"encoding/json"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	numberType        = reflect.TypeOf(json.Number(""))
+	rawMessageType    = reflect.TypeOf(json.RawMessage(nil))
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Reflect builds a schema that describes the JSON representation of
+// the Go type of `v`, as produced by encoding/json:
+//
+//   - struct fields are named after their json tags, and are required
+//     unless they are pointers or have the "omitempty" option. Fields
+//     promoted from embedded structs follow the same rules as in
+//     encoding/json: ambiguous fields at the same depth are left out
+//   - slices and arrays become arrays, with the element type in Items
+//   - maps become objects, with the element type in AdditionalProperties
+//   - pointers, slices and maps also accept null, which is how their
+//     nil values are encoded, unless they are fields with "omitempty"
+//   - time.Time becomes a string with the "date-time" format
+//   - named struct types are placed in "definitions" and referred to
+//     using "$ref", which allows recursive types
+//
+// Constraints can be specified using the "jsonschema" struct tag, which
+// contains a comma separated list of keyword=value pairs. For example
+// `jsonschema:"minLength=3,enum=a|b"`. The supported keywords are title,
+// description, format, pattern, enum, default, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, multipleOf, minLength, maxLength,
+// minItems, maxItems, uniqueItems, minProperties and maxProperties.
+// Values within enum are separated by "|".
+func Reflect(v interface{}) (*Schema, error) {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil, errors.New("can not reflect nil")
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	r := reflector{
+		root:  t,
+		names: make(map[reflect.Type]string),
+		used:  make(map[string]struct{}),
+		defs:  make(map[string]*Schema),
+	}
+
+	s, err := r.reflectType(t, true)
+	if err != nil {
+		return nil, err
+	}
+	if isNilable(t) {
+		s = allowNull(s)
+	}
+	s.SchemaRef = SchemaURL + "#"
+	if len(r.defs) > 0 {
+		s.Definitions = r.defs
+	}
+	s.applyParentSchema()
+	return s, nil
+}
+
+// newReflectedSchema creates a schema that, like one read from a document
+// without "additionalItems" and "additionalProperties", allows additional
+// items and properties. This matches encoding/json, which ignores fields
+// that it does not know about
+func newReflectedSchema() *Schema {
+	s := New()
+	s.AdditionalItems = &AdditionalItems{}
+	s.AdditionalProperties = &AdditionalProperties{}
+	return s
+}
+
+type reflector struct {
+	root  reflect.Type
+	names map[reflect.Type]string // names of the definitions for each type
+	used  map[string]struct{}
+	defs  map[string]*Schema
+}
+
+// reflectType returns the schema for the type `t`. Named struct types
+// other than the root are placed in the definitions, unless `inline`
+// is true
+func (r *reflector) reflectType(t reflect.Type, inline bool) (*Schema, error) {
+	s := newReflectedSchema()
+
+	switch {
+	case t == timeType:
+		s.Type = PrimitiveTypes{StringType}
+		s.Format = FormatDateTime
+		return s, nil
+	case t == numberType:
+		s.Type = PrimitiveTypes{NumberType}
+		return s, nil
+	case t == rawMessageType:
+		return s, nil
+	case t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType):
+		// The representation of the type is unknown
+		return s, nil
+	case t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType):
+		s.Type = PrimitiveTypes{StringType}
+		return s, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		s.Type = PrimitiveTypes{BooleanType}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s.Type = PrimitiveTypes{IntegerType}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		s.Type = PrimitiveTypes{IntegerType}
+		s.Minimum = Number{Val: 0, Exact: "0", Initialized: true}
+	case reflect.Float32, reflect.Float64:
+		s.Type = PrimitiveTypes{NumberType}
+	case reflect.String:
+		s.Type = PrimitiveTypes{StringType}
+	case reflect.Interface:
+		// Anything goes
+	case reflect.Ptr:
+		return r.reflectType(t.Elem(), false)
+	case reflect.Slice, reflect.Array:
+		// Byte slices are encoded as base64 strings
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			s.Type = PrimitiveTypes{StringType}
+			break
+		}
+
+		items, err := r.reflectElem(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		s.Type = PrimitiveTypes{ArrayType}
+		s.Items = &ItemSpec{Schemas: SchemaList{items}}
+		if t.Kind() == reflect.Array {
+			s.MinItems = Integer{Val: t.Len(), Initialized: true}
+			s.MaxItems = Integer{Val: t.Len(), Initialized: true}
+		}
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			if !t.Key().Implements(textMarshalerType) {
+				return nil, errors.Errorf("unsupported map key type %s", t.Key())
+			}
+		}
+
+		values, err := r.reflectElem(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		s.Type = PrimitiveTypes{ObjectType}
+		s.AdditionalProperties = &AdditionalProperties{Schema: values}
+	case reflect.Struct:
+		if t == r.root && !inline {
+			s.Reference = "#"
+			return s, nil
+		}
+		if t.Name() != "" && !inline {
+			return r.reference(t)
+		}
+		if err := r.reflectStruct(s, t); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("unsupported type %s", t)
+	}
+	return s, nil
+}
+
+// reflectElem returns the schema for the elements of slices, arrays
+// and maps of type `t`
+func (r *reflector) reflectElem(t reflect.Type) (*Schema, error) {
+	s, err := r.reflectType(t, false)
+	if err != nil {
+		return nil, err
+	}
+	if isNilable(t) {
+		s = allowNull(s)
+	}
+	return s, nil
+}
+
+// isNilable returns true if values of type `t` may be encoded as null
+func isNilable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// allowNull returns a schema that accepts null, along with the values
+// that `s` accepts
+func allowNull(s *Schema) *Schema {
+	if s.Reference == "" && len(s.AllOf) == 0 {
+		switch {
+		case len(s.Type) > 0:
+			s.Type = append(s.Type, NullType)
+			if len(s.Enum) > 0 {
+				s.Enum = append(s.Enum, nil)
+			}
+			return s
+		case len(s.Enum) == 0:
+			// Anything goes, including null
+			return s
+		}
+	}
+
+	// Keywords next to $ref are ignored, so both are wrapped instead
+	null := newReflectedSchema()
+	null.Type = PrimitiveTypes{NullType}
+	wrapper := newReflectedSchema()
+	wrapper.AnyOf = SchemaList{null, s}
+	return wrapper
+}
+
+// reference returns a reference to the definition of the named struct
+// type `t`, creating the definition if necessary
+func (r *reflector) reference(t reflect.Type) (*Schema, error) {
+	name, ok := r.names[t]
+	if !ok {
+		name = t.Name()
+		for i := 2; ; i++ {
+			if _, ok := r.used[name]; !ok {
+				break
+			}
+			name = t.Name() + strconv.Itoa(i)
+		}
+		r.used[name] = struct{}{}
+		r.names[t] = name
+
+		def, err := r.reflectType(t, true)
+		if err != nil {
+			return nil, err
+		}
+		r.defs[name] = def
+	}
+
+	s := newReflectedSchema()
+	s.Reference = appendPointer("#", "definitions", name)
+	return s, nil
+}
+
+func (r *reflector) reflectStruct(s *Schema, t reflect.Type) error {
+	s.Type = PrimitiveTypes{ObjectType}
+	s.Properties = make(map[string]*Schema)
+	if err := r.reflectFields(s, t); err != nil {
+		return err
+	}
+	sort.Strings(s.Required)
+	return nil
+}
+
+func (r *reflector) reflectFields(s *Schema, t reflect.Type) error {
+	for _, f := range structFields(t) {
+		ft := f.Type
+
+		var fs *Schema
+		var err error
+		if strings.Contains(f.opts, ",string") && isStringable(ft) {
+			fs = newReflectedSchema()
+			fs.Type = PrimitiveTypes{StringType}
+		} else {
+			fs, err = r.reflectType(ft, false)
+			if err != nil {
+				return errors.Wrapf(err, "failed to reflect field %s", f.Name)
+			}
+		}
+
+		if c, ok := f.Tag.Lookup("jsonschema"); ok {
+			if fs.Reference != "" {
+				// Keywords next to $ref are ignored, so the constraints
+				// are added to a schema that includes the reference
+				ref := fs
+				fs = newReflectedSchema()
+				fs.AllOf = SchemaList{ref}
+			}
+			if err := applyConstraints(fs, ft, c); err != nil {
+				return errors.Wrapf(err, "invalid jsonschema tag for field %s", f.Name)
+			}
+		}
+
+		omitEmpty := strings.Contains(f.opts, ",omitempty")
+		if isNilable(ft) && !omitEmpty {
+			fs = allowNull(fs)
+		}
+
+		s.Properties[f.name] = fs
+		// Fields of nil embedded pointers are left out by encoding/json
+		if ft.Kind() != reflect.Ptr && !omitEmpty && !f.viaPtr {
+			s.Required = append(s.Required, f.name)
+		}
+	}
+	return nil
+}
+
+// structField is a field of a struct, or of the structs embedded
+// within it, that encoding/json encodes
+type structField struct {
+	reflect.StructField
+	name   string // name of the member in the JSON object
+	opts   string // options in the json tag, e.g. ",omitempty"
+	tagged bool   // true if the name comes from the json tag
+	depth  int    // depth of embedding
+	viaPtr bool   // true if the field is promoted through a pointer
+}
+
+// embeddedStruct is a struct type whose fields are promoted
+type embeddedStruct struct {
+	typ    reflect.Type
+	viaPtr bool // true if it is embedded through a pointer
+}
+
+// structFields returns the fields that encoding/json encodes for the
+// struct type `t`. As in encoding/json, the fields of embedded structs
+// are promoted, and among the fields that share a name, the one that
+// is embedded the least deeply wins, followed by the one that is named
+// by its json tag. If there is no single winner, they are all left out
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	visited := make(map[reflect.Type]struct{})
+	next := []embeddedStruct{{typ: t}}
+	for depth := 0; len(next) > 0; depth++ {
+		current := next
+		next = nil
+		for _, e := range current {
+			if _, ok := visited[e.typ]; ok {
+				continue
+			}
+			fields = append(fields, directFields(e, depth, &next)...)
+		}
+		// Types that appear more than once at the same depth have their
+		// fields listed once for each, so that they are ambiguous
+		for _, e := range current {
+			visited[e.typ] = struct{}{}
+		}
+	}
+
+	byName := make(map[string][]structField)
+	var names []string
+	for _, f := range fields {
+		if _, ok := byName[f.name]; !ok {
+			names = append(names, f.name)
+		}
+		byName[f.name] = append(byName[f.name], f)
+	}
+
+	var dominant []structField
+	for _, name := range names {
+		l := byName[name]
+		sort.SliceStable(l, func(i, j int) bool {
+			if l[i].depth != l[j].depth {
+				return l[i].depth < l[j].depth
+			}
+			return l[i].tagged && !l[j].tagged
+		})
+		if len(l) > 1 && l[0].depth == l[1].depth && l[0].tagged == l[1].tagged {
+			continue
+		}
+		dominant = append(dominant, l[0])
+	}
+	return dominant
+}
+
+// directFields returns the fields that are declared by the embedded
+// struct `e`. Embedded structs whose fields are promoted are appended
+// to `next`
+func directFields(e embeddedStruct, depth int, next *[]embeddedStruct) []structField {
+	var fields []structField
+	for i := 0; i < e.typ.NumField(); i++ {
+		f := e.typ.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := tag, ""
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			name, opts = tag[:i], tag[i:]
+		}
+
+		// Embedded structs without a name have their fields promoted
+		if f.Anonymous && name == "" {
+			et := f.Type
+			if et.Kind() == reflect.Ptr {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct {
+				*next = append(*next, embeddedStruct{typ: et, viaPtr: e.viaPtr || f.Type.Kind() == reflect.Ptr})
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+
+		tagged := name != ""
+		if !tagged {
+			name = f.Name
+		}
+		fields = append(fields, structField{StructField: f, name: name, opts: opts, tagged: tagged, depth: depth, viaPtr: e.viaPtr})
+	}
+	return fields
+}
+
+func isStringable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// applyConstraints applies the constraints in the jsonschema struct tag
+// to the schema of a field of type `t`
+func applyConstraints(s *Schema, t reflect.Type, tag string) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	for _, c := range strings.Split(tag, ",") {
+		if c == "" {
+			continue
+		}
+
+		name, value := c, ""
+		if i := strings.IndexByte(c, '='); i >= 0 {
+			name, value = c[:i], c[i+1:]
+		}
+
+		var err error
+		switch name {
+		case "title":
+			s.Title = value
+		case "description":
+			s.Description = value
+		case "format":
+			s.Format = Format(value)
+		case "pattern":
+			s.Pattern, err = regexp.Compile(value)
+		case "enum":
+			s.Enum = nil
+			for _, v := range strings.Split(value, "|") {
+				var ev interface{}
+				if ev, err = tagValue(t, v); err != nil {
+					break
+				}
+				s.Enum = append(s.Enum, ev)
+			}
+		case "default":
+			s.Default, err = tagValue(t, value)
+		case "minimum":
+			s.Minimum, err = tagNumber(value)
+		case "maximum":
+			s.Maximum, err = tagNumber(value)
+		case "multipleOf":
+			s.MultipleOf, err = tagNumber(value)
+		case "exclusiveMinimum":
+			s.ExclusiveMinimum, err = tagBool(value)
+		case "exclusiveMaximum":
+			s.ExclusiveMaximum, err = tagBool(value)
+		case "uniqueItems":
+			s.UniqueItems, err = tagBool(value)
+		case "minLength":
+			s.MinLength, err = tagInteger(value)
+		case "maxLength":
+			s.MaxLength, err = tagInteger(value)
+		case "minItems":
+			s.MinItems, err = tagInteger(value)
+		case "maxItems":
+			s.MaxItems, err = tagInteger(value)
+		case "minProperties":
+			s.MinProperties, err = tagInteger(value)
+		case "maxProperties":
+			s.MaxProperties, err = tagInteger(value)
+		default:
+			err = errors.Errorf("unknown keyword %s", strconv.Quote(name))
+		}
+		if err != nil {
+			return errors.Wrapf(err, "invalid value for %s", name)
+		}
+	}
+	return nil
+}
+
+// tagValue converts a value in a struct tag into a value of the JSON
+// type that corresponds to the Go type `t`
+func tagValue(t reflect.Type, v string) (interface{}, error) {
+	switch t.Kind() {
+	case reflect.Bool:
+		return strconv.ParseBool(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		f, _, err := parseTagNumber(v)
+		return f, err
+	}
+	return v, nil
+}
+
+func tagNumber(v string) (Number, error) {
+	f, exact, err := parseTagNumber(v)
+	if err != nil {
+		return Number{}, err
+	}
+	return Number{Val: f, Exact: exact, Initialized: true}, nil
+}
+
+// parseTagNumber parses a number in a struct tag, which must be finite
+// for JSON to represent it. The exact representation is the number as
+// written if it is valid JSON (once any "+" sign is removed), and the
+// shortest representation of the parsed value otherwise (e.g. for
+// hexadecimal numbers)
+func parseTagNumber(v string) (float64, json.Number, error) {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, "", err
+	}
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, "", errors.Errorf("%s is not a finite number", strconv.Quote(v))
+	}
+
+	exact := strings.TrimPrefix(v, "+")
+	if !json.Valid([]byte(exact)) {
+		exact = strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return f, json.Number(exact), nil
+}
+
+func tagInteger(v string) (Integer, error) {
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return Integer{}, err
+	}
+	return Integer{Val: i, Initialized: true}, nil
+}
+
+func tagBool(v string) (Bool, error) {
+	// A keyword without a value, such as "uniqueItems", means true
+	if v == "" {
+		return Bool{Val: true, Initialized: true}, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return Bool{}, err
+	}
+	return Bool{Val: b, Initialized: true}, nil
+}
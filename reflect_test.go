@@ -0,0 +1,248 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/lestrrat-go/jsschema/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+type reflectBase struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+type reflectNode struct {
+	Name     string         `json:"name"`
+	Children []*reflectNode `json:"children,omitempty"`
+}
+
+type reflectUser struct {
+	reflectBase
+	Name     string            `json:"name" jsonschema:"minLength=3,maxLength=64,description=Full name"`
+	Role     string            `json:"role" jsonschema:"enum=admin|user"`
+	Age      *uint             `json:"age" jsonschema:"maximum=150"`
+	Score    float64           `json:"score,omitempty"`
+	Tags     []string          `json:"tags,omitempty" jsonschema:"uniqueItems"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Tree     *reflectNode      `json:"tree,omitempty"`
+	Count    int64             `json:"count,string"`
+	Ignored  string            `json:"-"`
+	internal string
+}
+
+func TestReflect(t *testing.T) {
+	s, err := schema.Reflect(reflectUser{})
+	if !assert.NoError(t, err, "schema.Reflect should succeed") {
+		return
+	}
+
+	buf, err := json.Marshal(s)
+	if !assert.NoError(t, err, "json.Marshal should succeed") {
+		return
+	}
+
+	expected := `{
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "type": "object",
+  "required": ["count", "created", "id", "name", "role"],
+  "properties": {
+    "id": {"type": "string"},
+    "created": {"type": "string", "format": "date-time"},
+    "name": {"type": "string", "minLength": 3, "maxLength": 64, "description": "Full name"},
+    "role": {"type": "string", "enum": ["admin", "user"]},
+    "age": {"type": ["integer", "null"], "minimum": 0, "maximum": 150},
+    "score": {"type": "number"},
+    "tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true},
+    "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+    "tree": {"$ref": "#/definitions/reflectNode"},
+    "count": {"type": "string"}
+  },
+  "definitions": {
+    "reflectNode": {
+      "type": "object",
+      "required": ["name"],
+      "properties": {
+        "name": {"type": "string"},
+        "children": {"type": "array", "items": {"anyOf": [{"type": "null"}, {"$ref": "#/definitions/reflectNode"}]}}
+      }
+    }
+  }
+}`
+	if !assert.JSONEq(t, expected, string(buf), "schema should match") {
+		return
+	}
+
+	if !assert.NoError(t, s.Validate(), "tree should be consistent") {
+		return
+	}
+	if !assert.NoError(t, s.CheckRefs(), "references should resolve") {
+		return
+	}
+}
+
+func TestReflectRecursiveRoot(t *testing.T) {
+	s, err := schema.Reflect(&reflectNode{})
+	if !assert.NoError(t, err, "schema.Reflect should succeed") {
+		return
+	}
+
+	items := s.Properties["children"].Items.Schemas[0].AnyOf[1]
+	if !assert.Equal(t, "#", items.Reference, "recursive reference should point to the root") {
+		return
+	}
+	resolved, err := items.Resolve(nil)
+	if !assert.NoError(t, err, "Resolve should succeed") {
+		return
+	}
+	if !assert.True(t, resolved == s, "reference should resolve to the root") {
+		return
+	}
+}
+
+type reflectInner struct {
+	Shadowed string `json:"shadowed"`
+	Deep     string `json:"deep"`
+}
+
+type reflectLeft struct {
+	reflectInner
+	Both string
+	Left string
+}
+
+type reflectRight struct {
+	Both   string
+	Tagged string `json:"Left"`
+}
+
+type reflectOptional struct {
+	Extra string `json:"extra"`
+}
+
+type reflectEmbedding struct {
+	reflectLeft
+	reflectRight
+	*reflectOptional
+	Shadowed int `json:"shadowed"`
+}
+
+func TestReflectEmbedded(t *testing.T) {
+	s, err := schema.Reflect(reflectEmbedding{})
+	if !assert.NoError(t, err, "schema.Reflect should succeed") {
+		return
+	}
+
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	if !assert.ElementsMatch(t, []string{"shadowed", "deep", "Left", "extra"}, names, "ambiguous fields should be left out") {
+		return
+	}
+	if !assert.Equal(t, schema.PrimitiveTypes{schema.IntegerType}, s.Properties["shadowed"].Type, "outer field should win") {
+		return
+	}
+	if !assert.Equal(t, []string{"Left", "deep", "shadowed"}, s.Required, "fields of embedded pointers should not be required") {
+		return
+	}
+
+	buf, err := json.Marshal(reflectEmbedding{reflectRight: reflectRight{Tagged: "tagged"}})
+	if !assert.NoError(t, err, "json.Marshal should succeed") {
+		return
+	}
+	if !assert.JSONEq(t, `{"shadowed": 0, "deep": "", "Left": "tagged"}`, string(buf), "encoding/json should agree") {
+		return
+	}
+}
+
+type reflectNullable struct {
+	Ptr       *string            `json:"ptr"`
+	Slice     []int              `json:"slice"`
+	Bytes     []byte             `json:"bytes"`
+	Map       map[string]*int    `json:"map"`
+	Node      *reflectNode       `json:"node"`
+	Enum      *string            `json:"enum" jsonschema:"enum=a|b"`
+	Ref       *reflectNode       `json:"ref" jsonschema:"description=A node"`
+	Omitted   *string            `json:"omitted,omitempty"`
+	Nested    [][]string         `json:"nested"`
+	Interface interface{}        `json:"interface"`
+	Values    map[string][]int64 `json:"values,omitempty"`
+}
+
+func TestReflectZeroValues(t *testing.T) {
+	for _, v := range []interface{}{reflectBase{}, reflectNode{}, reflectEmbedding{}, reflectNullable{}, []string(nil), map[string]int(nil)} {
+		s, err := schema.Reflect(v)
+		if !assert.NoError(t, err, "schema.Reflect(%T) should succeed", v) {
+			return
+		}
+
+		// None of these types have tags that exclude their zero values.
+		// They are validated in the form that they would be decoded in
+		buf, err := json.Marshal(v)
+		if !assert.NoError(t, err, "json.Marshal(%T) should succeed", v) {
+			return
+		}
+		var data interface{}
+		if !assert.NoError(t, json.Unmarshal(buf, &data), "json.Unmarshal should succeed") {
+			return
+		}
+		if !assert.NoError(t, validator.New(s).Validate(data), "zero value of %T (%s) should be valid", v, buf) {
+			return
+		}
+	}
+}
+
+func TestReflectErrors(t *testing.T) {
+	type badTag struct {
+		Name string `jsonschema:"minLength=three"`
+	}
+	type badKeyword struct {
+		Name string `jsonschema:"colour=red"`
+	}
+	type badType struct {
+		C chan int
+	}
+	type infinite struct {
+		N float64 `jsonschema:"maximum=Inf"`
+	}
+	type infiniteDefault struct {
+		N float64 `jsonschema:"default=-Inf"`
+	}
+
+	for _, v := range []interface{}{nil, badTag{}, badKeyword{}, badType{}, infinite{}, infiniteDefault{}} {
+		_, err := schema.Reflect(v)
+		if !assert.Error(t, err, "schema.Reflect(%T) should fail", v) {
+			return
+		}
+	}
+
+	_, err := schema.Reflect(struct{ V map[float64]string }{})
+	if !assert.True(t, err != nil && strings.Contains(err.Error(), "map key"), "unsupported map keys should be reported") {
+		return
+	}
+}
+
+func TestReflectTagNumbers(t *testing.T) {
+	type numbers struct {
+		N int `jsonschema:"minimum=+5,maximum=0x1p4,multipleOf=9007199254740993"`
+	}
+
+	s, err := schema.Reflect(numbers{})
+	if !assert.NoError(t, err, "schema.Reflect should succeed") {
+		return
+	}
+	buf, err := json.Marshal(s.Properties["N"])
+	if !assert.NoError(t, err, "json.Marshal should succeed") {
+		return
+	}
+	for _, literal := range []string{`"minimum":5`, `"maximum":16`, `"multipleOf":9007199254740993`} {
+		if !assert.Contains(t, string(buf), literal, "numbers should be valid JSON") {
+			return
+		}
+	}
+}
@@ -1,12 +1,15 @@
 package schema
 
 import (
-	"encoding/json"
+	"bufio"
 	"io"
+	"io/ioutil"
 	"net/url"
 	"os"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/lestrrat-go/jsref"
 	"github.com/lestrrat-go/jsref/provider"
@@ -44,90 +47,220 @@ func (s *Schema) initialize() {
 }
 
 // ReadFile reads the file `f` and parses its content to create
-// a new Schema object
-func ReadFile(f string) (*Schema, error) {
+// a new Schema object. Files with a ".yaml" or ".yml" extension
+// are parsed as YAML (see ReadYAML)
+func ReadFile(f string, options ...ReadOption) (*Schema, error) {
 	in, err := os.Open(f)
 	if err != nil {
 		return nil, err
 	}
 	defer in.Close()
-	return Read(in)
+	return read(bufio.NewReader(in), f, IsYAMLFile(f), options)
 }
 
 // Read reads from `in` and parses its content to create
 // a new Schema object
-func Read(in io.Reader) (*Schema, error) {
+func Read(in io.Reader, options ...ReadOption) (*Schema, error) {
+	return read(in, "", false, options)
+}
+
+func read(in io.Reader, filename string, yamlInput bool, options []ReadOption) (*Schema, error) {
+	var ctx extractContext
+	var resolveRefs bool
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyLenient:
+			ctx.lenient = o.Value().(bool)
+		case optkeyPreserveKeyOrder:
+			ctx.keepOrder = o.Value().(bool)
+		case optkeyResolveReferences:
+			resolveRefs = o.Value().(bool)
+		case optkeyRoundTrip:
+			ctx.roundTrip = o.Value().(bool)
+		case optkeyUseNumber:
+			ctx.floats = !o.Value().(bool)
+		}
+	}
+	// Numbers must be kept exactly to reproduce the source
+	if ctx.roundTrip || ctx.keepOrder {
+		ctx.floats = false
+	}
+
 	s := New()
-	if err := s.Decode(in); err != nil {
+	if err := s.decode(in, filename, yamlInput, &ctx); err != nil {
+		if _, ok := err.(ParseErrors); ok {
+			// Lenient mode: return whatever we could extract
+			return s, err
+		}
 		return nil, err
 	}
+
+	if resolveRefs {
+		if err := s.CheckRefs(); err != nil {
+			return nil, err
+		}
+	}
 	return s, nil
 }
 
 // Decode reads from `in` and parses its content to
-// initialize the schema object
+// initialize the schema object. Only the first JSON value is read
+// from readers that implement io.ByteReader (e.g. *bufio.Reader),
+// so that subsequent calls may decode the values that follow it.
+// Other readers are buffered, and may be read past the value
 func (s *Schema) Decode(in io.Reader) error {
-	dec := json.NewDecoder(in)
-	if err := dec.Decode(s); err != nil {
-		return err
+	return s.decode(in, "", false, &extractContext{})
+}
+
+func (s *Schema) decode(in io.Reader, filename string, yamlInput bool, ctx *extractContext) error {
+	var data []byte
+	var err error
+	if yamlInput {
+		data, err = ioutil.ReadAll(in)
+	} else {
+		data, err = readJSONValue(in)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to read schema")
+	}
+
+	if yamlInput {
+		err = s.unmarshalYAML(data, filename, ctx)
+	} else {
+		err = s.unmarshalJSON(data, filename, ctx)
 	}
 	s.applyParentSchema()
-	return nil
+	return err
 }
 
-func (s *Schema) setParent(v *Schema) {
+func (s *Schema) setParent(v *Schema, location []string) {
 	s.parent = v
+	s.location = location
 }
 
 func (s *Schema) applyParentSchema() {
 	// Find all components that may be a Schema
-	for _, v := range s.Definitions {
-		v.setParent(s)
+	s.eachSubschema(func(tokens []string, v *Schema) {
+		v.setParent(s, tokens)
 		v.applyParentSchema()
+	})
+}
+
+// Validate checks the structure of the schema tree rooted at `s`: every
+// subschema must be linked to the schema and keyword that contain it,
+// which is what Root, Scope, Pointer and Resolve rely upon. Trees that
+// are read using Read, ReadFile or ReadYAML are always consistent, but
+// trees that are assembled or modified by hand may not be.
+//
+// Note that Validate does not check the schema against the JSON Schema
+// meta-schema, nor does it validate any data.
+func (s *Schema) Validate() error {
+	return s.validateTree("#", make(map[*Schema]struct{}))
+}
+
+func (s *Schema) validateTree(ptr string, seen map[*Schema]struct{}) error {
+	seen[s] = struct{}{}
+
+	var err error
+	s.eachSubschema(func(tokens []string, v *Schema) {
+		if err != nil {
+			return
+		}
+
+		child := appendPointer(ptr, tokens...)
+		switch {
+		case v == nil:
+			err = errors.Errorf("schema at %s is nil", child)
+		case v.parent == nil:
+			err = errors.Errorf("schema at %s has no parent", child)
+		case v.parent != s:
+			err = errors.Errorf("schema at %s has the wrong parent (%s)", child, v.parent.Pointer())
+		case appendPointer("", v.location...) != appendPointer("", tokens...):
+			err = errors.Errorf("schema at %s has the wrong location (%s)", child, appendPointer("#", v.location...))
+		}
+		if err != nil {
+			return
+		}
+
+		if _, ok := seen[v]; ok {
+			err = errors.Errorf("schema at %s appears more than once in the tree", child)
+			return
+		}
+		err = v.validateTree(child, seen)
+	})
+	return err
+}
+
+func sortedSchemaMapKeys(m map[string]*Schema) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// eachSubschema calls `fn` for each schema directly contained within
+// this schema, along with the JSON pointer tokens that lead from this
+// schema to the child.
+func (s *Schema) eachSubschema(fn func([]string, *Schema)) {
+	for _, k := range sortedSchemaMapKeys(s.Definitions) {
+		fn([]string{"definitions", k}, s.Definitions[k])
 	}
 
 	if props := s.AdditionalProperties; props != nil {
 		if sc := props.Schema; sc != nil {
-			sc.setParent(s)
-			sc.applyParentSchema()
+			fn([]string{"additionalProperties"}, sc)
 		}
 	}
 	if items := s.AdditionalItems; items != nil {
 		if sc := items.Schema; sc != nil {
-			sc.setParent(s)
-			sc.applyParentSchema()
+			fn([]string{"additionalItems"}, sc)
 		}
 	}
 	if items := s.Items; items != nil {
-		for _, v := range items.Schemas {
-			v.setParent(s)
-			v.applyParentSchema()
+		for i, v := range items.Schemas {
+			if items.TupleMode {
+				fn([]string{"items", strconv.Itoa(i)}, v)
+			} else {
+				fn([]string{"items"}, v)
+			}
 		}
 	}
 
-	for _, v := range s.Properties {
-		v.setParent(s)
-		v.applyParentSchema()
+	for _, k := range sortedSchemaMapKeys(s.Properties) {
+		fn([]string{"properties", k}, s.Properties[k])
 	}
 
-	for _, v := range s.AllOf {
-		v.setParent(s)
-		v.applyParentSchema()
+	patterns := make([]string, 0, len(s.PatternProperties))
+	byPattern := make(map[string]*Schema, len(s.PatternProperties))
+	for rx, v := range s.PatternProperties {
+		patterns = append(patterns, rx.String())
+		byPattern[rx.String()] = v
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		fn([]string{"patternProperties", pattern}, byPattern[pattern])
 	}
 
-	for _, v := range s.AnyOf {
-		v.setParent(s)
-		v.applyParentSchema()
+	for _, k := range sortedSchemaMapKeys(s.Dependencies.Schemas) {
+		fn([]string{"dependencies", k}, s.Dependencies.Schemas[k])
 	}
 
-	for _, v := range s.OneOf {
-		v.setParent(s)
-		v.applyParentSchema()
+	for i, v := range s.AllOf {
+		fn([]string{"allOf", strconv.Itoa(i)}, v)
+	}
+
+	for i, v := range s.AnyOf {
+		fn([]string{"anyOf", strconv.Itoa(i)}, v)
+	}
+
+	for i, v := range s.OneOf {
+		fn([]string{"oneOf", strconv.Itoa(i)}, v)
 	}
 
 	if v := s.Not; v != nil {
-		v.setParent(s)
-		v.applyParentSchema()
+		fn([]string{"not"}, v)
 	}
 }
 
@@ -158,12 +291,83 @@ func (s *Schema) Root() *Schema {
 	return s.parent.Root()
 }
 
+// isAnchorID returns true if the id is a location-independent
+// identifier, such as "#foo"
+func isAnchorID(id string) bool {
+	return len(id) > 1 && id[0] == '#' && id[1] != '/'
+}
+
+// resourceRoot returns the closest schema in the hierarchy (including
+// itself) that starts a new resource, i.e. has a non-fragment id.
+// If there are none, the root schema is returned.
+func (s *Schema) resourceRoot() *Schema {
+	if s.parent == nil || (s.ID != "" && s.ID[0] != '#') {
+		return s
+	}
+	return s.parent.resourceRoot()
+}
+
+func canonicalID(base *Schema, id string) string {
+	if base != nil {
+		if u, err := base.ResolveURL(id); err == nil {
+			id = u.String()
+		}
+	}
+	return strings.TrimSuffix(id, "#")
+}
+
+// idIndex returns the index of identifiers found within this schema.
+// Plain name fragments ("#foo") are indexed if they belong to the
+// same resource as this schema, while absolute ids are indexed
+// regardless of how deep they are in the hierarchy.
+func (s *Schema) idIndex() map[string]*Schema {
+	s.resolveLock.Lock()
+	defer s.resolveLock.Unlock()
+
+	if s.ids != nil {
+		return s.ids
+	}
+
+	idx := make(map[string]*Schema)
+	if s.ID != "" && !isAnchorID(s.ID) {
+		idx[canonicalID(nil, s.ID)] = s
+	}
+
+	var walk func(*Schema, bool)
+	walk = func(parent *Schema, sameResource bool) {
+		parent.eachSubschema(func(_ []string, v *Schema) {
+			inResource := sameResource
+			switch {
+			case v.ID == "":
+			case isAnchorID(v.ID):
+				if _, ok := idx[v.ID]; inResource && !ok {
+					idx[v.ID] = v
+				}
+			default:
+				if key := canonicalID(parent, v.ID); key != "" {
+					if _, ok := idx[key]; !ok {
+						idx[key] = v
+					}
+				}
+				inResource = false
+			}
+			walk(v, inResource)
+		})
+	}
+	walk(s, true)
+
+	s.ids = idx
+	return idx
+}
+
 func (s *Schema) findSchemaByID(id string) (*Schema, error) {
 	if s.ID == id {
 		return s, nil
 	}
 
-	// XXX Quite unimplemented
+	if v, ok := s.idIndex()[id]; ok {
+		return v, nil
+	}
 	return nil, errors.Errorf("schema %s not found", strconv.Quote(id))
 }
 
@@ -198,6 +402,9 @@ func (s *Schema) IsResolved() bool {
 
 // Resolve returns the schema after it has been resolved.
 // If s.Reference is the empty string, the current schema is returned.
+// If the referenced schema itself is a reference, it is followed until
+// a schema without a reference is found. A chain of references that
+// leads back to itself results in a *CircularReferenceError.
 //
 // `ctx` is an optional context to resolve the reference with. If not
 // specified, the root schema as returned by `Root` will be used.
@@ -217,6 +424,40 @@ func (s *Schema) Resolve(ctx interface{}) (ref *Schema, err error) {
 		}()
 	}
 
+	chain := []*Schema{s}
+	for cur := s; ; {
+		ref, err = cur.resolveReference(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if ref.Reference == "" {
+			return ref, nil
+		}
+
+		for _, seen := range chain {
+			if seen != ref {
+				continue
+			}
+
+			path := make([]string, len(chain))
+			for i, v := range chain {
+				path[i] = v.Reference
+			}
+			return nil, &CircularReferenceError{Path: path}
+		}
+
+		// Subsequent references are resolved against the document
+		// that they belong to
+		chain = append(chain, ref)
+		cur = ref
+		ctx = nil
+	}
+}
+
+// resolveReference resolves s.Reference exactly once, without following
+// any references that the resolved schema may contain.
+func (s *Schema) resolveReference(ctx interface{}) (ref *Schema, err error) {
 	var thing interface{}
 	var ok bool
 	s.resolveLock.Lock()
@@ -239,11 +480,7 @@ func (s *Schema) Resolve(ctx interface{}) (ref *Schema, err error) {
 		if pdebug.Enabled {
 			pdebug.Printf("Cache MISS on '%s'", s.Reference)
 		}
-		var err error
-		if ctx == nil {
-			ctx = s.Root()
-		}
-		thing, err := s.resolver.Resolve(ctx, s.Reference)
+		thing, err := s.lookupReference(ctx)
 		if err != nil {
 			err = errors.Wrapf(err, "failed to resolve reference %s", strconv.Quote(s.Reference))
 			s.resolveLock.Lock()
@@ -254,7 +491,7 @@ func (s *Schema) Resolve(ctx interface{}) (ref *Schema, err error) {
 
 		ref, ok = thing.(*Schema)
 		if !ok {
-			err = errors.Wrapf(err, "resolved reference %s is not a schema", strconv.Quote(s.Reference))
+			err = errors.Errorf("resolved reference %s is not a schema", strconv.Quote(s.Reference))
 			s.resolveLock.Lock()
 			s.resolvedSchemas[s.Reference] = err
 			s.resolveLock.Unlock()
@@ -268,6 +505,98 @@ func (s *Schema) Resolve(ctx interface{}) (ref *Schema, err error) {
 	return ref, nil
 }
 
+// lookupReference fetches the object that s.Reference points to.
+// JSON pointers are looked up in the typed tree or handed to the
+// resolver, while plain name fragments are looked up from the index
+// of ids of the target document.
+func (s *Schema) lookupReference(ctx interface{}) (interface{}, error) {
+	i := strings.IndexByte(s.Reference, '#')
+	if i < 0 || !isAnchorID(s.Reference[i:]) {
+		if ctx == nil {
+			ctx = s.Root()
+		}
+
+		// Pointers local to a schema can be looked up directly, without
+		// going through the resolver
+		if root, ok := ctx.(*Schema); ok && i == 0 {
+			if v, err := root.Lookup(s.Reference); err == nil {
+				return v, nil
+			}
+		}
+		return s.resolver.Resolve(ctx, s.Reference)
+	}
+
+	base, anchor := s.Reference[:i], s.Reference[i:]
+
+	var doc *Schema
+	if base == "" {
+		if v, ok := ctx.(*Schema); ok {
+			doc = v
+		} else {
+			doc = s.resourceRoot()
+		}
+	} else {
+		var err error
+		doc, err = s.findResource(base)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return doc.findSchemaByID(anchor)
+}
+
+// findResource looks for the document identified by `uri`, first
+// within the current hierarchy, then using the resolver.
+func (s *Schema) findResource(uri string) (*Schema, error) {
+	key := canonicalID(s, uri)
+	root := s.Root()
+	if v, ok := root.idIndex()[key]; ok {
+		return v, nil
+	}
+
+	thing, err := s.resolver.Resolve(root, key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch document %s", strconv.Quote(key))
+	}
+
+	doc, ok := thing.(*Schema)
+	if !ok {
+		return nil, errors.Errorf("document %s is not a schema", strconv.Quote(key))
+	}
+	return doc, nil
+}
+
+// CheckRefs attempts to resolve every reference found within the
+// schema tree. If any of them can not be resolved, or if they form
+// a circular chain of references, a ReferenceErrors value listing
+// all of the offending references is returned.
+func (s *Schema) CheckRefs() error {
+	var list ReferenceErrors
+	s.checkRefs("#", &list)
+	if len(list) > 0 {
+		return list
+	}
+	return nil
+}
+
+func (s *Schema) checkRefs(ptr string, list *ReferenceErrors) {
+	if s.Reference != "" {
+		if _, err := s.Resolve(nil); err != nil {
+			*list = append(*list, &ReferenceError{
+				Pointer:   ptr,
+				Reference: s.Reference,
+				Position:  s.KeywordPosition("$ref"),
+				Err:       err,
+			})
+		}
+	}
+
+	s.eachSubschema(func(tokens []string, v *Schema) {
+		v.checkRefs(appendPointer(ptr, tokens...), list)
+	})
+}
+
 // IsPropRequired can be used to query this schema if a
 // given property name is required.
 func (s *Schema) IsPropRequired(pname string) bool {
@@ -279,12 +608,34 @@ func (s *Schema) IsPropRequired(pname string) bool {
 	return false
 }
 
+// EnumContains returns true if `v` is one of the values listed in
+// the "enum" keyword of this schema. Numbers are compared by their
+// exact values, regardless of whether they are represented as
+// json.Number or Go integer types. float64 values, which may have
+// already lost precision, are compared as float64.
+func (s *Schema) EnumContains(v interface{}) bool {
+	for _, e := range s.Enum {
+		if valuesEqual(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
 // Scope returns the scope ID for this schema
 func (s *Schema) Scope() string {
 	if pdebug.Enabled {
 		g := pdebug.IPrintf("START Schema.Scope")
 		defer g.IRelease("END Schema.Scope")
 	}
+	// Plain name fragments do not change the resolution scope
+	if isAnchorID(s.ID) {
+		if s.parent == nil {
+			return ""
+		}
+		return s.parent.Scope()
+	}
+
 	if s.ID != "" || s.parent == nil {
 		if pdebug.Enabled {
 			pdebug.Printf("Returning id '%s'", s.ID)
@@ -3,6 +3,7 @@ package schema_test
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -104,6 +105,56 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestDecodeStream(t *testing.T) {
+	in := strings.NewReader(`{"title": "first"}
+{
+  "title": "second"
+}`)
+
+	var first, second schema.Schema
+	if !assert.NoError(t, first.Decode(in), "Decode should succeed") {
+		return
+	}
+	if !assert.NoError(t, second.Decode(in), "Decode should succeed") {
+		return
+	}
+	if !assert.Equal(t, "first", first.Title, "first schema should be decoded") {
+		return
+	}
+	if !assert.Equal(t, "second", second.Title, "second schema should be decoded") {
+		return
+	}
+	if !assert.Equal(t, "3:3", second.KeywordPosition("title").String(), "position should be relative to where decoding started") {
+		return
+	}
+}
+
+// countingReader counts the calls to Read
+type countingReader struct {
+	r     io.Reader
+	calls int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.calls++
+	return r.r.Read(p)
+}
+
+func TestReadBuffered(t *testing.T) {
+	src := `{"title": "` + strings.Repeat("x", 10000) + `"}`
+	in := &countingReader{r: strings.NewReader(src)}
+	s, err := schema.Read(in)
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+	if !assert.Len(t, s.Title, 10000, "title should be read") {
+		return
+	}
+	if !assert.True(t, in.calls < 10, "input should be read in blocks (%d calls)", in.calls) {
+		return
+	}
+}
+
 func TestExtras(t *testing.T) {
 	const src = `{
   "extra1": "foo",
@@ -121,3 +172,302 @@ func TestExtras(t *testing.T) {
 		}
 	}
 }
+
+func TestCircularReference(t *testing.T) {
+	const src = `{
+  "$ref": "#/definitions/a",
+  "definitions": {
+    "a": { "$ref": "#/definitions/b" },
+    "b": { "$ref": "#/definitions/a" },
+    "c": { "$ref": "#/definitions/d" },
+    "d": { "type": "string" }
+  }
+}`
+	s, err := schema.Read(strings.NewReader(src))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	_, err = s.Resolve(nil)
+	if !assert.Error(t, err, "s.Resolve should fail") {
+		return
+	}
+
+	cerr, ok := err.(*schema.CircularReferenceError)
+	if !assert.True(t, ok, "error should be a CircularReferenceError") {
+		return
+	}
+	if !assert.Equal(t, []string{"#/definitions/a", "#/definitions/b", "#/definitions/a"}, cerr.Path, "cycle path should match") {
+		return
+	}
+
+	resolved, err := s.Definitions["c"].Resolve(nil)
+	if !assert.NoError(t, err, "chained references should be followed") {
+		return
+	}
+	if !assert.Equal(t, s.Definitions["d"], resolved, "resolved schema should match") {
+		return
+	}
+}
+
+func TestCheckRefs(t *testing.T) {
+	const src = `{
+  "definitions": {
+    "a": { "$ref": "#/definitions/b" },
+    "b": { "$ref": "#/definitions/a" },
+    "c": { "type": "string" }
+  },
+  "properties": {
+    "good": { "$ref": "#/definitions/c" },
+    "typo": { "$ref": "#/definitions/cc" }
+  }
+}`
+	s, err := schema.Read(strings.NewReader(src))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	err = s.CheckRefs()
+	if !assert.Error(t, err, "s.CheckRefs should fail") {
+		return
+	}
+
+	list, ok := err.(schema.ReferenceErrors)
+	if !assert.True(t, ok, "error should be ReferenceErrors") {
+		return
+	}
+
+	var pointers []string
+	for _, e := range list {
+		pointers = append(pointers, e.Pointer)
+	}
+	if !assert.Equal(t, []string{"#/definitions/a", "#/definitions/b", "#/properties/typo"}, pointers, "offending pointers should match") {
+		return
+	}
+	if !assert.Equal(t, "9:15", list[2].Position.String(), "position of the reference should match") {
+		return
+	}
+
+	if !assert.NoError(t, s.Definitions["c"].CheckRefs(), "subtree without references should pass") {
+		return
+	}
+}
+
+func TestReadWithResolveReferences(t *testing.T) {
+	const src = `{
+  "definitions": {
+    "name": { "type": "string" }
+  },
+  "properties": {
+    "name": { "$ref": "#/definitions/name" },
+    "nickname": { "$ref": "#/definitions/nmae" }
+  }
+}`
+
+	_, err := schema.Read(strings.NewReader(src))
+	if !assert.NoError(t, err, "schema.Read should succeed without eager resolution") {
+		return
+	}
+
+	_, err = schema.Read(strings.NewReader(src), schema.WithResolveReferences(true))
+	if !assert.Error(t, err, "schema.Read should fail with eager resolution") {
+		return
+	}
+
+	list, ok := err.(schema.ReferenceErrors)
+	if !assert.True(t, ok, "error should be ReferenceErrors") {
+		return
+	}
+	if !assert.Len(t, list, 1, "there should be exactly one broken reference") {
+		return
+	}
+	if !assert.Equal(t, "#/properties/nickname", list[0].Pointer, "pointer should match") {
+		return
+	}
+	if !assert.Equal(t, "#/definitions/nmae", list[0].Reference, "reference should match") {
+		return
+	}
+}
+
+func TestResolveAnchor(t *testing.T) {
+	const src = `{
+  "id": "http://example.com/root.json",
+  "definitions": {
+    "address": {
+      "id": "#address",
+      "type": "object"
+    },
+    "other": {
+      "id": "http://example.com/other.json",
+      "definitions": {
+        "zip": {
+          "id": "#zip",
+          "type": "string"
+        },
+        "local": { "$ref": "#zip" }
+      }
+    }
+  },
+  "properties": {
+    "home": { "$ref": "#address" },
+    "zip": { "$ref": "other.json#zip" },
+    "missing": { "$ref": "#zip" }
+  }
+}`
+	s, err := schema.Read(strings.NewReader(src))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	resolved, err := s.Properties["home"].Resolve(nil)
+	if !assert.NoError(t, err, "resolving anchor within document should succeed") {
+		return
+	}
+	if !assert.Equal(t, s.Definitions["address"], resolved, "resolved schema should match") {
+		return
+	}
+
+	other := s.Definitions["other"]
+	resolved, err = s.Properties["zip"].Resolve(nil)
+	if !assert.NoError(t, err, "resolving anchor in another document should succeed") {
+		return
+	}
+	if !assert.Equal(t, other.Definitions["zip"], resolved, "resolved schema should match") {
+		return
+	}
+
+	resolved, err = other.Definitions["local"].Resolve(nil)
+	if !assert.NoError(t, err, "resolving anchor within embedded document should succeed") {
+		return
+	}
+	if !assert.Equal(t, other.Definitions["zip"], resolved, "resolved schema should match") {
+		return
+	}
+
+	_, err = s.Properties["missing"].Resolve(nil)
+	if !assert.Error(t, err, "anchors from other documents should not be visible") {
+		return
+	}
+
+	if !assert.Equal(t, "http://example.com/root.json", s.Definitions["address"].Scope(), "anchors should not change the scope") {
+		return
+	}
+}
+
+func TestParentWiring(t *testing.T) {
+	const src = `{
+  "definitions": {
+    "target": {"type": "string"},
+    "def": {"$ref": "#/definitions/target"}
+  },
+  "properties": {
+    "prop": {"$ref": "#/definitions/target"}
+  },
+  "patternProperties": {
+    "^x-": {"$ref": "#/definitions/target"}
+  },
+  "additionalProperties": {"$ref": "#/definitions/target"},
+  "dependencies": {
+    "prop": {"$ref": "#/definitions/target"}
+  },
+  "items": [{"$ref": "#/definitions/target"}],
+  "additionalItems": {"$ref": "#/definitions/target"},
+  "allOf": [{"$ref": "#/definitions/target"}],
+  "anyOf": [{"$ref": "#/definitions/target"}],
+  "oneOf": [{"$ref": "#/definitions/target"}],
+  "not": {
+    "items": {"$ref": "#/definitions/target"}
+  }
+}`
+	s, err := schema.Read(strings.NewReader(src))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+	if !assert.NoError(t, s.Validate(), "tree should be consistent") {
+		return
+	}
+
+	target := s.Definitions["target"]
+	pointers := []string{
+		"#/definitions/def",
+		"#/properties/prop",
+		"#/patternProperties/^x-",
+		"#/additionalProperties",
+		"#/dependencies/prop",
+		"#/items/0",
+		"#/additionalItems",
+		"#/allOf/0",
+		"#/anyOf/0",
+		"#/oneOf/0",
+		"#/not/items",
+	}
+	for _, ptr := range pointers {
+		node, err := s.Lookup(ptr)
+		if !assert.NoError(t, err, "Lookup(%s) should succeed", ptr) {
+			return
+		}
+		if !assert.True(t, node.Root() == s, "%s: root should be the top-level schema", ptr) {
+			return
+		}
+		if !assert.Equal(t, ptr, node.Pointer(), "%s: pointer should match", ptr) {
+			return
+		}
+
+		resolved, err := node.Resolve(nil)
+		if !assert.NoError(t, err, "%s: Resolve should succeed", ptr) {
+			return
+		}
+		if !assert.True(t, resolved == target, "%s: reference should resolve against the root", ptr) {
+			return
+		}
+	}
+}
+
+func TestValidateTree(t *testing.T) {
+	const src = `{
+  "properties": {
+    "a": {"type": "string"},
+    "b": {"type": "integer"}
+  },
+  "patternProperties": {
+    "^x-": {"type": "string"}
+  }
+}`
+	s, err := schema.Read(strings.NewReader(src))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+	if !assert.NoError(t, s.Validate(), "tree should be consistent") {
+		return
+	}
+
+	// A schema that was not wired into the tree
+	s.Properties["c"] = schema.New()
+	if !assert.Error(t, s.Validate(), "unwired schema should be detected") {
+		return
+	}
+	delete(s.Properties, "c")
+
+	// A schema that was moved to another location
+	s.Properties["c"] = s.Properties["a"]
+	delete(s.Properties, "a")
+	if !assert.Error(t, s.Validate(), "moved schema should be detected") {
+		return
+	}
+	s.Properties["a"] = s.Properties["c"]
+	delete(s.Properties, "c")
+
+	// A schema that appears twice
+	s.Not = s.Properties["b"]
+	if !assert.Error(t, s.Validate(), "shared schema should be detected") {
+		return
+	}
+	s.Not = nil
+
+	if !assert.NoError(t, s.Validate(), "restored tree should be consistent") {
+		return
+	}
+	if !assert.NoError(t, s.Clone().Validate(), "clone should be consistent") {
+		return
+	}
+}
@@ -0,0 +1,268 @@
+package schema
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// IsValid returns true if the position has been set
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position in "file:line:column" format.
+// The filename is omitted if it is not known
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+
+	s := strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+	if p.Filename != "" {
+		s = p.Filename + ":" + s
+	}
+	return s
+}
+
+// Position returns the location of this schema within the source
+// document that it was read from. If the schema was not read from
+// a JSON document (e.g. it was created using Extract), the zero
+// value is returned.
+func (s *Schema) Position() Position {
+	return s.position
+}
+
+// KeywordPosition returns the location of the keyword `name` within
+// the source document that this schema was read from. If the keyword
+// does not exist, or the schema was not read from a JSON document,
+// the zero value is returned.
+func (s *Schema) KeywordPosition(name string) Position {
+	return s.keywordPos[name]
+}
+
+// sourceMap holds the offsets of each value within a JSON document,
+// keyed by their JSON pointer
+type sourceMap struct {
+	filename string
+	lines    []int          // offsets at which each line starts
+	values   map[string]int // offsets at which each value starts
+	keys     map[string]int // offsets at which each object member starts
+}
+
+func newSourceMap(data []byte, filename string) *sourceMap {
+	lines := []int{0}
+	for i, c := range data {
+		if c == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+
+	return &sourceMap{
+		filename: filename,
+		lines:    lines,
+		values:   make(map[string]int),
+		keys:     make(map[string]int),
+	}
+}
+
+func (src *sourceMap) position(offset int) Position {
+	// Find the last line that starts at or before offset
+	i := sort.Search(len(src.lines), func(i int) bool {
+		return src.lines[i] > offset
+	}) - 1
+
+	return Position{
+		Filename: src.filename,
+		Offset:   offset,
+		Line:     i + 1,
+		Column:   offset - src.lines[i] + 1,
+	}
+}
+
+// valuePosition returns the position at which the value pointed
+// to by `ptr` starts
+func (src *sourceMap) valuePosition(ptr string) Position {
+	offset, ok := src.values[ptr]
+	if !ok {
+		return Position{}
+	}
+	return src.position(offset)
+}
+
+// keyPosition returns the position at which the object member
+// pointed to by `ptr` starts. If `ptr` does not point to an object
+// member, the position of the value is returned
+func (src *sourceMap) keyPosition(ptr string) Position {
+	offset, ok := src.keys[ptr]
+	if !ok {
+		return src.valuePosition(ptr)
+	}
+	return src.position(offset)
+}
+
+// valueReader hands the bytes of `r` to a json.Decoder one at a time,
+// so that the decoder does not consume anything past the end of the
+// value that it decodes. The bytes that were read are kept in `buf`
+type valueReader struct {
+	r   io.ByteReader
+	buf bytes.Buffer
+	err error
+}
+
+func (r *valueReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	c, err := r.r.ReadByte()
+	if err != nil {
+		if err != io.EOF {
+			r.err = err
+		}
+		return 0, err
+	}
+	p[0] = c
+	r.buf.WriteByte(c)
+	return 1, nil
+}
+
+// readJSONValue reads the first JSON value in `in`, including the
+// whitespace that precedes it. The rest of the stream is left unread
+// if `in` is an io.ByteReader. Other readers are buffered, as reading
+// them a byte at a time could be slow
+func readJSONValue(in io.Reader) ([]byte, error) {
+	br, ok := in.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReader(in)
+	}
+	r := valueReader{r: br}
+
+	// Syntax errors are ignored here, as they are reported along
+	// with their positions by decodeWithPositions
+	var v json.RawMessage
+	json.NewDecoder(&r).Decode(&v)
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.buf.Bytes(), nil
+}
+
+// positionDecoder decodes a JSON document in the same manner as
+// json.Unmarshal would into an interface{} (with numbers decoded as
+// json.Number), while recording the position of every value that
+// it encounters
+type positionDecoder struct {
+	data   []byte
+	dec    *json.Decoder
+	source *sourceMap
+}
+
+func decodeWithPositions(data []byte, filename string) (interface{}, *sourceMap, error) {
+	d := positionDecoder{
+		data:   data,
+		dec:    json.NewDecoder(bytes.NewReader(data)),
+		source: newSourceMap(data, filename),
+	}
+	// Numbers are kept as json.Number, so that their exact
+	// representation is available when extracting the schema
+	d.dec.UseNumber()
+
+	v, err := d.decodeValue("")
+	if err != nil {
+		return nil, nil, err
+	}
+	return v, d.source, nil
+}
+
+// next returns the offset at which the next token starts
+func (d *positionDecoder) next() int {
+	offset := int(d.dec.InputOffset())
+	for offset < len(d.data) {
+		switch d.data[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+func (d *positionDecoder) token(ptr string) (json.Token, error) {
+	tok, err := d.dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		pe := &ParseError{Pointer: "#" + ptr, Err: errors.Wrap(err, "failed to decode JSON")}
+		if serr, ok := err.(*json.SyntaxError); ok && serr.Offset > 0 {
+			// Offset points right after the offending character
+			pe.Position = d.source.position(int(serr.Offset) - 1)
+		} else {
+			pe.Position = d.source.position(d.next())
+		}
+		return nil, pe
+	}
+	return tok, nil
+}
+
+func (d *positionDecoder) decodeValue(ptr string) (interface{}, error) {
+	d.source.values[ptr] = d.next()
+
+	tok, err := d.token(ptr)
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+
+	switch delim {
+	case '{':
+		m := make(map[string]interface{})
+		for d.dec.More() {
+			offset := d.next()
+			tok, err := d.token(ptr)
+			if err != nil {
+				return nil, err
+			}
+			key := tok.(string)
+			child := appendPointer(ptr, key)
+			d.source.keys[child] = offset
+
+			v, err := d.decodeValue(child)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+		}
+		if _, err := d.token(ptr); err != nil {
+			return nil, err
+		}
+		return m, nil
+	case '[':
+		l := []interface{}{}
+		for d.dec.More() {
+			v, err := d.decodeValue(appendPointer(ptr, strconv.Itoa(len(l))))
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, v)
+		}
+		if _, err := d.token(ptr); err != nil {
+			return nil, err
+		}
+		return l, nil
+	default:
+		return nil, errors.Errorf("unexpected delimiter %s", delim)
+	}
+}
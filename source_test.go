@@ -0,0 +1,140 @@
+package schema_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/lestrrat-go/jsschema/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPosition(t *testing.T) {
+	const src = `{
+  "type": "object",
+  "properties": {
+    "name": {
+      "type": "string",
+      "minLength": 1
+    }
+  }
+}`
+	s, err := schema.Read(strings.NewReader(src))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	if !assert.Equal(t, schema.Position{Offset: 0, Line: 1, Column: 1}, s.Position(), "root position should match") {
+		return
+	}
+
+	name := s.Properties["name"]
+	if !assert.Equal(t, "4:13", name.Position().String(), "subschema position should match") {
+		return
+	}
+	if !assert.Equal(t, "6:7", name.KeywordPosition("minLength").String(), "keyword position should match") {
+		return
+	}
+	if !assert.False(t, name.KeywordPosition("maxLength").IsValid(), "missing keyword should not have a position") {
+		return
+	}
+
+	s, err = schema.ReadFile(filepath.Join("test", "strlen.json"))
+	if !assert.NoError(t, err, "schema.ReadFile should succeed") {
+		return
+	}
+	if !assert.Equal(t, filepath.Join("test", "strlen.json"), s.Position().Filename, "filename should be recorded") {
+		return
+	}
+}
+
+func TestParseErrorPosition(t *testing.T) {
+	const src = `{
+  "properties": {
+    "name": {
+      "minLength": "one"
+    }
+  }
+}`
+	_, err := schema.Read(strings.NewReader(src))
+	if !assert.Error(t, err, "schema.Read should fail") {
+		return
+	}
+
+	perr, ok := err.(*schema.ParseError)
+	if !assert.True(t, ok, "error should be a ParseError") {
+		return
+	}
+	if !assert.Equal(t, "#/properties/name/minLength", perr.Pointer, "pointer should match") {
+		return
+	}
+	if !assert.Equal(t, "4:7", perr.Position.String(), "position should match") {
+		return
+	}
+
+	_, err = schema.Read(strings.NewReader("{\n  \"type\" \"string\"\n}"))
+	if !assert.Error(t, err, "schema.Read should fail") {
+		return
+	}
+
+	perr, ok = err.(*schema.ParseError)
+	if !assert.True(t, ok, "error should be a ParseError") {
+		return
+	}
+	if !assert.Equal(t, "2:10", perr.Position.String(), "position of syntax error should match") {
+		return
+	}
+}
+
+func TestValidationErrorPosition(t *testing.T) {
+	const src = `{
+  "type": "object",
+  "properties": {
+    "pets": {
+      "type": "array",
+      "items": {"$ref": "#/definitions/pet"}
+    }
+  },
+  "definitions": {
+    "pet": {
+      "type": "object",
+      "properties": {
+        "name": {
+          "type": "string",
+          "minLength": 1
+        }
+      }
+    }
+  }
+}`
+	s, err := schema.Read(strings.NewReader(src))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	data := map[string]interface{}{
+		"pets": []interface{}{
+			map[string]interface{}{"name": "Tama"},
+			map[string]interface{}{"name": ""},
+		},
+	}
+	err = validator.New(s).Validate(data)
+	if !assert.Error(t, err, "Validate should fail") {
+		return
+	}
+
+	verr, ok := err.(*validator.Error)
+	if !assert.True(t, ok, "error should be a validator.Error") {
+		return
+	}
+	if !assert.Equal(t, "#/pets/1/name", verr.Pointer, "pointer to the value should match") {
+		return
+	}
+	if !assert.Equal(t, "#/definitions/pet/properties/name", verr.SchemaPointer, "pointer to the schema should match") {
+		return
+	}
+	if !assert.Equal(t, "13:17", verr.Position.String(), "position of the schema should match") {
+		return
+	}
+}
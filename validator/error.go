@@ -0,0 +1,28 @@
+package validator
+
+import "github.com/lestrrat-go/jsschema"
+
+// Error is returned by Validate when a value does not conform to the
+// schema. It identifies the innermost schema that rejected the value,
+// along with the position of that schema within the source document
+// that it was read from.
+type Error struct {
+	Pointer       string          // JSON pointer to the offending value
+	SchemaPointer string          // JSON pointer to the schema that rejected the value
+	Position      schema.Position // location of the schema within its source, if available
+	Err           error
+}
+
+// Error returns the string representation of the error
+func (e *Error) Error() string {
+	msg := "value at " + e.Pointer + " does not match the schema at " + e.SchemaPointer
+	if e.Position.IsValid() {
+		msg += " (" + e.Position.String() + ")"
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+// Cause returns the underlying error
+func (e *Error) Cause() error {
+	return e.Err
+}
@@ -1,6 +1,10 @@
 package validator
 
 import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
 	"sync"
 
 	"github.com/lestrrat-go/jsschema"
@@ -12,12 +16,17 @@ import (
 // Validator is an object that wraps jsval.JSVal, and
 // can be used to validate an object against a schema
 type Validator struct {
-	lock   sync.Mutex
-	schema *schema.Schema
-	jsval  *jsval.JSVal
+	lock    sync.Mutex
+	schema  *schema.Schema
+	root    *schema.Schema // copy of the root of schema, for references
+	floats  *schema.Schema // counterpart of schema within root, that jsval is built from
+	jsval   *jsval.JSVal
+	subvals map[*schema.Schema]*jsval.JSVal // used to locate errors
+	rootRef bool                            // true if root refers to itself
 }
 
-// New creates a new Validator from a JSON Schema
+// New creates a new Validator from a JSON Schema. If `s` is part of
+// a larger schema, references are resolved against the root of it
 func New(s *schema.Schema) *Validator {
 	return &Validator{
 		schema: s,
@@ -30,34 +39,279 @@ func New(s *schema.Schema) *Validator {
 // reason this is exposed is for benchmarking), as it
 // is automatically called when `Validate` is called.
 func (v *Validator) Compile() (*jsval.JSVal, error) {
+	root, floats := floatSchema(v.schema)
+	return compile(floats, root)
+}
+
+func compile(s, root *schema.Schema) (*jsval.JSVal, error) {
 	b := builder.New()
-	jsv, err := b.Build(v.schema)
+	jsv, err := b.BuildWithCtx(s, root)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to build validator")
 	}
 	return jsv, nil
 }
 
+// floatSchema returns a copy of the root of `s`, along with the copy
+// of `s` within it. Their "enum" and "default" values hold float64 in
+// place of json.Number (see schema.WithUseNumber), as jsval infers the
+// type of a schema from the Go types of these values
+func floatSchema(s *schema.Schema) (*schema.Schema, *schema.Schema) {
+	root := s.Root().Clone()
+	schema.Walk(root, func(_ schema.Pointer, node *schema.Schema) error {
+		for i, e := range node.Enum {
+			node.Enum[i] = floatValue(e)
+		}
+		node.Default = floatValue(node.Default)
+		return nil
+	})
+
+	sub, err := root.Lookup(s.Pointer())
+	if err != nil {
+		// Should the copy not be found, `s` is validated on its own
+		sub, _ = floatSchema(s.Clone())
+		return sub, sub
+	}
+	return root, sub
+}
+
+// floatValue converts the json.Number values within `v` to float64
+func floatValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, x := range val {
+			m[k] = floatValue(x)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(val))
+		for i, x := range val {
+			l[i] = floatValue(x)
+		}
+		return l
+	}
+	return v
+}
+
 func (v *Validator) validator() (*jsval.JSVal, error) {
 	v.lock.Lock()
 	defer v.lock.Unlock()
 
 	if v.jsval == nil {
-		val, err := v.Compile()
+		root, floats := floatSchema(v.schema)
+		val, err := compile(floats, root)
 		if err != nil {
 			return nil, err
 		}
+		v.root = root
+		v.floats = floats
+		v.rootRef = refersToRoot(root)
 		v.jsval = val
 	}
 	return v.jsval, nil
 }
 
 // Validate takes an arbitrary piece of data and
-// validates it against the schema.
+// validates it against the schema. If the data does not conform
+// to the schema, the returned error is an *Error.
+//
+// Numbers within the data may be given as json.Number (see
+// json.Decoder.UseNumber). Unless the schema was read with
+// schema.WithUseNumber(false), such numbers are compared exactly
+// with the values of "enum", so that large integers and decimal
+// fractions that are equal as float64 are still told apart.
 func (v *Validator) Validate(x interface{}) error {
 	jsv, err := v.validator()
 	if err != nil {
 		return err
 	}
+
+	// jsval only understands numbers given as Go numeric types
+	fx := floatValue(x)
+	if err := jsv.Validate(fx); err != nil {
+		return v.locate(v.floats, fx, schema.Pointer("#"), err)
+	}
+	return checkEnums(v.schema, x, schema.Pointer("#"))
+}
+
+// check validates `x` against the subschema `s` of the copy of the
+// schema that jsval is built from
+func (v *Validator) check(s *schema.Schema, x interface{}) error {
+	if v.rootRef && s != v.root {
+		// jsval resolves "#" to the schema that it is built from, which
+		// would be `s`, so the error is reported by the enclosing schema
+		return nil
+	}
+
+	v.lock.Lock()
+	jsv, ok := v.subvals[s]
+	if !ok {
+		var err error
+		// References are resolved against the whole schema
+		jsv, err = builder.New().BuildWithCtx(s, v.floats)
+		if err != nil {
+			v.lock.Unlock()
+			return err
+		}
+		if v.subvals == nil {
+			v.subvals = make(map[*schema.Schema]*jsval.JSVal)
+		}
+		v.subvals[s] = jsv
+	}
+	v.lock.Unlock()
+
 	return jsv.Validate(x)
 }
+
+// refersToRoot returns true if `s` contains a reference to itself as a
+// whole
+func refersToRoot(s *schema.Schema) bool {
+	var found bool
+	schema.Walk(s, func(_ schema.Pointer, node *schema.Schema) error {
+		if node.Reference == "#" {
+			found = true
+		}
+		return nil
+	})
+	return found
+}
+
+// locate finds the innermost schema that rejects `x`, given that `s`
+// rejects it with `err`
+func (v *Validator) locate(s *schema.Schema, x interface{}, ptr schema.Pointer, err error) error {
+	s = resolve(s)
+	for _, sub := range subjects(s, x, ptr) {
+		if serr := v.check(sub.schema, sub.value); serr != nil {
+			return v.locate(sub.schema, sub.value, sub.pointer, serr)
+		}
+	}
+
+	return &Error{
+		Pointer:       ptr.String(),
+		SchemaPointer: s.Pointer(),
+		Position:      s.Position(),
+		Err:           err,
+	}
+}
+
+// checkEnums compares `x` with the values of "enum" exactly, which
+// jsval can only do once the numbers have been converted to float64
+func checkEnums(s *schema.Schema, x interface{}, ptr schema.Pointer) error {
+	s = resolve(s)
+	if len(s.Enum) > 0 && !s.EnumContains(x) {
+		return &Error{
+			Pointer:       ptr.String(),
+			SchemaPointer: s.Pointer(),
+			Position:      s.KeywordPosition("enum"),
+			Err:           errors.New("value is not in enumeration"),
+		}
+	}
+
+	for _, sub := range subjects(s, x, ptr) {
+		if err := checkEnums(sub.schema, sub.value, sub.pointer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve returns the schema that `s` refers to, if any
+func resolve(s *schema.Schema) *schema.Schema {
+	if s.Reference != "" {
+		if r, err := s.Resolve(nil); err == nil {
+			return r
+		}
+	}
+	return s
+}
+
+// subject is a value, along with a subschema that it must conform to
+type subject struct {
+	schema  *schema.Schema
+	value   interface{}
+	pointer schema.Pointer
+}
+
+// subjects returns the subschemas of `s` that `x`, or its properties
+// and items, must conform to regardless of the other branches of the
+// schema: those of "allOf", and those that apply to the properties
+// and items of `x`. The branches of "anyOf", "oneOf" and "not" are
+// not included, as they do not pinpoint a single schema at fault.
+func subjects(s *schema.Schema, x interface{}, ptr schema.Pointer) []subject {
+	var l []subject
+	for _, child := range s.AllOf {
+		l = append(l, subject{schema: child, value: x, pointer: ptr})
+	}
+
+	switch val := x.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			for _, child := range propertySchemas(s, k) {
+				l = append(l, subject{schema: child, value: val[k], pointer: ptr.Append(k)})
+			}
+		}
+	case []interface{}:
+		for i, item := range val {
+			if child := itemSchema(s, i); child != nil {
+				l = append(l, subject{schema: child, value: item, pointer: ptr.Append(strconv.Itoa(i))})
+			}
+		}
+	}
+	return l
+}
+
+// propertySchemas returns the subschemas of `s` that apply to the
+// property `name`
+func propertySchemas(s *schema.Schema, name string) []*schema.Schema {
+	var l []*schema.Schema
+	if child, ok := s.Properties[name]; ok {
+		l = append(l, child)
+	}
+
+	var matched []*regexp.Regexp
+	for re := range s.PatternProperties {
+		if re.MatchString(name) {
+			matched = append(matched, re)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].String() < matched[j].String()
+	})
+	for _, re := range matched {
+		l = append(l, s.PatternProperties[re])
+	}
+
+	if len(l) == 0 && s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		l = append(l, s.AdditionalProperties.Schema)
+	}
+	return l
+}
+
+// itemSchema returns the subschema of `s` that applies to the item
+// at index `i`, or nil if there is none
+func itemSchema(s *schema.Schema, i int) *schema.Schema {
+	if s.Items == nil || len(s.Items.Schemas) == 0 {
+		return nil
+	}
+	if !s.Items.TupleMode {
+		return s.Items.Schemas[0]
+	}
+	if i < len(s.Items.Schemas) {
+		return s.Items.Schemas[i]
+	}
+	if s.AdditionalItems != nil {
+		return s.AdditionalItems.Schema
+	}
+	return nil
+}
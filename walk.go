@@ -0,0 +1,113 @@
+package schema
+
+import (
+	"github.com/lestrrat-go/jsschema/internal/option"
+	"github.com/pkg/errors"
+)
+
+// WalkFunc is the type of the function called by Walk for each schema.
+// `path` is the location of `node` relative to the schema that Walk
+// started from.
+type WalkFunc func(path Pointer, node *Schema) error
+
+// SkipSubtree can be returned by the WalkFunc called upon entering a
+// schema, in order to skip its subschemas. It is not returned as an
+// error by Walk.
+var SkipSubtree = errors.New("skip this subtree")
+
+// WalkOption is an option that can be passed to Walk
+type WalkOption interface {
+	option.Interface
+}
+
+// WithLeave specifies a function that Walk calls after all subschemas
+// of a schema have been visited. It is not called for schemas whose
+// subtree was skipped.
+func WithLeave(fn WalkFunc) WalkOption {
+	return option.New(optkeyLeave, fn)
+}
+
+// WithFollowReferences specifies if Walk should visit the schemas that
+// references point to. The referenced schema is visited as a child of
+// the schema containing the reference, with "$ref" appended to the path.
+// References that lead back to a schema that is being visited are not
+// followed, so that recursive schemas do not result in infinite loops.
+func WithFollowReferences(b bool) WalkOption {
+	return option.New(optkeyFollowReferences, b)
+}
+
+// Walk visits the schema `s` and every schema contained within it,
+// depth first, calling `fn` upon entering each of them. Subschemas are
+// visited in a deterministic order: by keyword, and then by name or by
+// index. If `fn` returns SkipSubtree, the subschemas of that schema are
+// skipped. Any other error stops the walk, and is returned by Walk.
+func Walk(s *Schema, fn WalkFunc, options ...WalkOption) error {
+	w := walker{
+		enter:  fn,
+		active: make(map[*Schema]struct{}),
+	}
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyFollowReferences:
+			w.followRefs = o.Value().(bool)
+		case optkeyLeave:
+			w.leave = o.Value().(WalkFunc)
+		}
+	}
+	return w.walk(Pointer("#"), s)
+}
+
+type walker struct {
+	enter      WalkFunc
+	leave      WalkFunc
+	followRefs bool
+	active     map[*Schema]struct{} // schemas being visited
+}
+
+func (w *walker) walk(path Pointer, s *Schema) error {
+	if s == nil {
+		return nil
+	}
+
+	if w.enter != nil {
+		if err := w.enter(path, s); err != nil {
+			if err == SkipSubtree {
+				return nil
+			}
+			return err
+		}
+	}
+
+	w.active[s] = struct{}{}
+	defer delete(w.active, s)
+
+	var err error
+	s.eachSubschema(func(tokens []string, v *Schema) {
+		if err != nil {
+			return
+		}
+		err = w.walk(path.Append(tokens...), v)
+	})
+	if err != nil {
+		return err
+	}
+
+	if w.followRefs && s.Reference != "" {
+		target, err := s.Resolve(nil)
+		if err != nil {
+			return errors.Wrapf(err, "failed to follow reference at %s", path)
+		}
+		if _, ok := w.active[target]; !ok {
+			if err := w.walk(path.Append("$ref"), target); err != nil {
+				return err
+			}
+		}
+	}
+
+	if w.leave != nil {
+		if err := w.leave(path, s); err != nil && err != SkipSubtree {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package schema_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/stretchr/testify/assert"
+)
+
+const walkSchema = `{
+  "definitions": {
+    "node": {
+      "properties": {
+        "children": {"type": "array", "items": {"$ref": "#/definitions/node"}}
+      }
+    }
+  },
+  "properties": {
+    "root": {"$ref": "#/definitions/node"},
+    "name": {"type": "string"}
+  },
+  "patternProperties": {
+    "^x-": {"type": "string"}
+  },
+  "dependencies": {
+    "name": {"required": ["root"]},
+    "root": ["name"]
+  },
+  "not": {"type": "null"}
+}`
+
+func TestWalk(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(walkSchema))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	var paths []string
+	nodes := make(map[string]*schema.Schema)
+	err = schema.Walk(s, func(path schema.Pointer, node *schema.Schema) error {
+		paths = append(paths, path.String())
+		nodes[path.String()] = node
+		return nil
+	})
+	if !assert.NoError(t, err, "Walk should succeed") {
+		return
+	}
+
+	expected := []string{
+		"#",
+		"#/definitions/node",
+		"#/definitions/node/properties/children",
+		"#/definitions/node/properties/children/items",
+		"#/properties/name",
+		"#/properties/root",
+		"#/patternProperties/^x-",
+		"#/dependencies/name",
+		"#/not",
+	}
+	if !assert.Equal(t, expected, paths, "paths should match") {
+		return
+	}
+
+	for _, path := range paths {
+		node, err := s.Lookup(path)
+		if !assert.NoError(t, err, "Lookup(%s) should succeed", path) {
+			return
+		}
+		if !assert.True(t, nodes[path] == node, "path %s should locate the node", path) {
+			return
+		}
+		if path == "#" {
+			continue
+		}
+		if !assert.Equal(t, path, node.Pointer(), "pointer should match the path") {
+			return
+		}
+	}
+}
+
+func TestWalkHooks(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(walkSchema))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	var events []string
+	err = schema.Walk(s, func(path schema.Pointer, node *schema.Schema) error {
+		events = append(events, "enter "+path.String())
+		if path == "#/definitions/node" {
+			return schema.SkipSubtree
+		}
+		return nil
+	}, schema.WithLeave(func(path schema.Pointer, node *schema.Schema) error {
+		events = append(events, "leave "+path.String())
+		return nil
+	}))
+	if !assert.NoError(t, err, "Walk should succeed") {
+		return
+	}
+
+	expected := []string{
+		"enter #",
+		"enter #/definitions/node",
+		"enter #/properties/name",
+		"leave #/properties/name",
+		"enter #/properties/root",
+		"leave #/properties/root",
+		"enter #/patternProperties/^x-",
+		"leave #/patternProperties/^x-",
+		"enter #/dependencies/name",
+		"leave #/dependencies/name",
+		"enter #/not",
+		"leave #/not",
+		"leave #",
+	}
+	if !assert.Equal(t, expected, events, "events should match") {
+		return
+	}
+
+	stop := errors.New("stop")
+	err = schema.Walk(s, func(path schema.Pointer, node *schema.Schema) error {
+		if path == "#/properties/name" {
+			return stop
+		}
+		return nil
+	})
+	if !assert.Equal(t, stop, err, "Walk should return the error") {
+		return
+	}
+}
+
+func TestWalkFollowReferences(t *testing.T) {
+	s, err := schema.Read(strings.NewReader(walkSchema))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	var paths []string
+	err = schema.Walk(s, func(path schema.Pointer, node *schema.Schema) error {
+		if strings.HasPrefix(path.String(), "#/properties/root") {
+			paths = append(paths, path.String())
+		}
+		return nil
+	}, schema.WithFollowReferences(true))
+	if !assert.NoError(t, err, "Walk should succeed") {
+		return
+	}
+
+	// The recursive reference within the node definition is not
+	// followed a second time
+	expected := []string{
+		"#/properties/root",
+		"#/properties/root/$ref",
+		"#/properties/root/$ref/properties/children",
+		"#/properties/root/$ref/properties/children/items",
+	}
+	if !assert.Equal(t, expected, paths, "paths should match") {
+		return
+	}
+
+	s.Properties["name"].Reference = "#/definitions/missing"
+	err = schema.Walk(s, func(path schema.Pointer, node *schema.Schema) error {
+		return nil
+	}, schema.WithFollowReferences(true))
+	if !assert.Error(t, err, "Walk should fail for unresolvable references") {
+		return
+	}
+}
@@ -0,0 +1,318 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ReadYAML reads a schema written in YAML from `in` and parses its
+// content to create a new Schema object. Anchors, aliases and merge
+// keys are resolved, and non-string mapping keys (e.g. `200:`) are
+// converted to their string representation.
+func ReadYAML(in io.Reader, options ...ReadOption) (*Schema, error) {
+	return read(in, "", true, options)
+}
+
+// IsYAMLFile returns true if the filename has a ".yaml" or ".yml"
+// extension, in which case ReadFile parses it as YAML
+func IsYAMLFile(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// DecodeYAML converts a YAML document into the same structure that
+// decoding the equivalent JSON document into an interface{} would
+// produce, with numbers as json.Number. As in ReadYAML, anchors,
+// aliases and merge keys are resolved, and non-string mapping keys
+// are converted to their string representation.
+func DecodeYAML(data []byte) (interface{}, error) {
+	v, _, err := decodeYAMLWithPositions(data, "")
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (s *Schema) unmarshalYAML(data []byte, filename string, ctx *extractContext) error {
+	v, src, err := decodeYAMLWithPositions(data, filename)
+	if err != nil {
+		return err
+	}
+	return s.extractDocument(v, src, ctx)
+}
+
+// yamlDecoder converts a YAML document into the same structure that
+// decoding the equivalent JSON document would produce, while recording
+// the position of every value that it encounters
+type yamlDecoder struct {
+	source  *sourceMap
+	depth   int
+	aliased int // greater than zero while expanding an alias
+	copies  int // number of values expanded from aliases
+}
+
+const (
+	// maxYAMLDepth limits the nesting of the converted document, so
+	// that recursive aliases cannot make the conversion run forever
+	maxYAMLDepth = 1000
+	// maxYAMLCopies limits the number of values that aliases expand
+	// to, so that aliases of aliases (e.g. "billion laughs" documents)
+	// cannot exhaust memory
+	maxYAMLCopies = 100000
+)
+
+func decodeYAMLWithPositions(data []byte, filename string) (interface{}, *sourceMap, error) {
+	d := yamlDecoder{source: newSourceMap(data, filename)}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		pe := &ParseError{
+			Pointer:  "#",
+			Position: Position{Filename: filename},
+			Err:      errors.Wrap(err, "failed to decode YAML"),
+		}
+		// The YAML parser only reports the line of syntax errors
+		var line int
+		if _, serr := fmt.Sscanf(err.Error(), "yaml: line %d:", &line); serr == nil {
+			pe.Position = d.source.position(d.source.offset(line, 1))
+		}
+		return nil, nil, pe
+	}
+
+	v, err := d.decodeNode(&doc, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return v, d.source, nil
+}
+
+// offset converts the 1-based line and column reported by the YAML
+// parser into an offset within the document
+func (src *sourceMap) offset(line, column int) int {
+	if line < 1 || line > len(src.lines) {
+		return 0
+	}
+	return src.lines[line-1] + column - 1
+}
+
+func (d *yamlDecoder) fail(n *yaml.Node, ptr string, err error) error {
+	return &ParseError{
+		Pointer:  "#" + ptr,
+		Position: d.source.position(d.source.offset(n.Line, n.Column)),
+		Err:      err,
+	}
+}
+
+func (d *yamlDecoder) decodeNode(n *yaml.Node, ptr string) (interface{}, error) {
+	if d.depth > maxYAMLDepth {
+		return nil, d.fail(n, ptr, errors.New("document is nested too deeply"))
+	}
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.aliased > 0 {
+		if d.copies++; d.copies > maxYAMLCopies {
+			return nil, d.fail(n, ptr, errors.New("aliases expand to too many values"))
+		}
+	}
+
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, d.fail(n, ptr, errors.New("empty document"))
+		}
+		return d.decodeNode(n.Content[0], ptr)
+	case yaml.AliasNode:
+		d.aliased++
+		defer func() { d.aliased-- }()
+		return d.decodeNode(n.Alias, ptr)
+	case yaml.MappingNode:
+		return d.decodeMapping(n, ptr)
+	case yaml.SequenceNode:
+		d.source.values[ptr] = d.source.offset(n.Line, n.Column)
+		l := make([]interface{}, len(n.Content))
+		for i, c := range n.Content {
+			v, err := d.decodeNode(c, appendPointer(ptr, strconv.Itoa(i)))
+			if err != nil {
+				return nil, err
+			}
+			l[i] = v
+		}
+		return l, nil
+	case yaml.ScalarNode:
+		d.source.values[ptr] = d.source.offset(n.Line, n.Column)
+		return d.decodeScalar(n, ptr)
+	}
+	return nil, d.fail(n, ptr, errors.Errorf("unsupported YAML node kind %d", n.Kind))
+}
+
+func (d *yamlDecoder) decodeMapping(n *yaml.Node, ptr string) (interface{}, error) {
+	d.source.values[ptr] = d.source.offset(n.Line, n.Column)
+
+	m := make(map[string]interface{})
+	var merged []map[string]interface{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if k.Kind != yaml.ScalarNode {
+			return nil, d.fail(k, ptr, errors.New("mapping keys must be scalars"))
+		}
+
+		if k.ShortTag() == "!!merge" {
+			l, err := d.decodeMerge(v, ptr)
+			if err != nil {
+				return nil, err
+			}
+			merged = append(merged, l...)
+			continue
+		}
+
+		// Non-string keys are stored using their literal representation
+		child := appendPointer(ptr, k.Value)
+		d.source.keys[child] = d.source.offset(k.Line, k.Column)
+		cv, err := d.decodeNode(v, child)
+		if err != nil {
+			return nil, err
+		}
+		m[k.Value] = cv
+	}
+
+	// Explicit keys take precedence over merged ones, and earlier
+	// merged mappings take precedence over later ones
+	for _, mm := range merged {
+		for k, v := range mm {
+			if _, ok := m[k]; !ok {
+				m[k] = v
+			}
+		}
+	}
+	return m, nil
+}
+
+// decodeMerge decodes the value of a merge key (`<<`), which is
+// either a mapping or a sequence of mappings
+func (d *yamlDecoder) decodeMerge(n *yaml.Node, ptr string) ([]map[string]interface{}, error) {
+	nodes := []*yaml.Node{n}
+	if n.Kind == yaml.SequenceNode {
+		nodes = n.Content
+	}
+
+	var l []map[string]interface{}
+	for _, c := range nodes {
+		v, err := d.decodeNode(c, ptr)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, d.fail(c, ptr, errors.New("merge key value must be a mapping"))
+		}
+		l = append(l, m)
+	}
+	return l, nil
+}
+
+func (d *yamlDecoder) decodeScalar(n *yaml.Node, ptr string) (interface{}, error) {
+	switch n.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var b bool
+		if err := n.Decode(&b); err != nil {
+			return nil, d.fail(n, ptr, err)
+		}
+		return b, nil
+	case "!!int":
+		// Numbers are kept as json.Number, just like when decoding JSON
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			return nil, d.fail(n, ptr, err)
+		}
+		switch v := v.(type) {
+		case int:
+			return json.Number(strconv.Itoa(v)), nil
+		case int64:
+			return json.Number(strconv.FormatInt(v, 10)), nil
+		case uint64:
+			return json.Number(strconv.FormatUint(v, 10)), nil
+		}
+		return nil, d.fail(n, ptr, errors.Errorf("integer %s is out of range", n.Value))
+	case "!!float":
+		var f float64
+		if err := n.Decode(&f); err != nil {
+			return nil, d.fail(n, ptr, err)
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, d.fail(n, ptr, errors.Errorf("%s can not be represented in JSON", n.Value))
+		}
+		// Keep the literal if it is a valid JSON number, so that
+		// exact values survive
+		if _, err := strconv.ParseFloat(n.Value, 64); err == nil && json.Valid([]byte(n.Value)) {
+			return json.Number(n.Value), nil
+		}
+		return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), nil
+	}
+	// Strings, timestamps, and anything else are kept as strings
+	return n.Value, nil
+}
+
+// MarshalYAML returns the YAML representation of the schema. It
+// implements the yaml.Marshaler interface of gopkg.in/yaml.v3. The
+// keywords appear in the same order as they do in MarshalJSON
+func (s *Schema) MarshalYAML() (interface{}, error) {
+	buf, err := s.MarshalJSON()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal schema")
+	}
+	return jsonToYAMLNode(buf)
+}
+
+// JSONToYAML converts a JSON document into YAML, keeping the order of
+// object members. Nested nodes are indented by `indent` spaces
+func JSONToYAML(buf []byte, indent int) ([]byte, error) {
+	n, err := jsonToYAMLNode(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(indent)
+	if err := enc.Encode(n); err != nil {
+		return nil, errors.Wrap(err, "failed to encode YAML")
+	}
+	if err := enc.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to encode YAML")
+	}
+	return out.Bytes(), nil
+}
+
+// jsonToYAMLNode converts a JSON document into a YAML node, keeping
+// the order of object members
+func jsonToYAMLNode(buf []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(buf, &doc); err != nil {
+		return nil, errors.Wrap(err, "failed to convert JSON to YAML")
+	}
+	clearYAMLStyle(&doc)
+	return doc.Content[0], nil
+}
+
+// clearYAMLStyle resets the flow style that the nodes inherited from
+// the JSON document, so that they are written in block style
+func clearYAMLStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		clearYAMLStyle(c)
+	}
+}
@@ -0,0 +1,165 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jsschema"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestReadYAML(t *testing.T) {
+	s, err := schema.ReadFile(filepath.Join("test", "anchors.yaml"))
+	if !assert.NoError(t, err, "schema.ReadFile should succeed") {
+		return
+	}
+
+	if !assert.Equal(t, schema.PrimitiveTypes{schema.ObjectType}, s.Type, "type should match") {
+		return
+	}
+
+	first := s.Properties["first_name"]
+	if !assert.Equal(t, 255, first.MaxLength.Val, "aliases should be resolved") {
+		return
+	}
+
+	last := s.Properties["last_name"]
+	if !assert.Equal(t, 1, last.MinLength.Val, "merged keys should be resolved") {
+		return
+	}
+	if !assert.Equal(t, 64, last.MaxLength.Val, "explicit keys should override merged keys") {
+		return
+	}
+
+	if !assert.True(t, s.Properties["status"].EnumContains(404), "integers should be read as numbers") {
+		return
+	}
+	if !assert.Equal(t, "0.01", string(s.Properties["ratio"].MultipleOf.Exact), "exact number should be kept") {
+		return
+	}
+
+	if !assert.Len(t, s.PatternProperties, 1, "non-string keys should be converted") {
+		return
+	}
+	for rx := range s.PatternProperties {
+		if !assert.Equal(t, "200", rx.String(), "non-string key should be converted to its literal") {
+			return
+		}
+	}
+
+	pos := last.KeywordPosition("maxLength")
+	if !assert.Equal(t, []int{13, 5}, []int{pos.Line, pos.Column}, "keyword position should be recorded") {
+		return
+	}
+}
+
+func TestReadYAMLError(t *testing.T) {
+	_, err := schema.ReadYAML(strings.NewReader("type: object\nminProperties: many\n"))
+	if !assert.Error(t, err, "schema.ReadYAML should fail") {
+		return
+	}
+
+	perr, ok := err.(*schema.ParseError)
+	if !assert.True(t, ok, "error should be a *schema.ParseError") {
+		return
+	}
+	if !assert.Equal(t, "#/minProperties", perr.Pointer, "pointer should match") {
+		return
+	}
+	if !assert.Equal(t, 2, perr.Position.Line, "line should match") {
+		return
+	}
+
+	_, err = schema.ReadYAML(strings.NewReader("- type: object\n"))
+	if !assert.Error(t, err, "schema.ReadYAML should fail for non-mappings") {
+		return
+	}
+}
+
+func TestDecodeYAMLAliasBomb(t *testing.T) {
+	src := "a: &a [x, x, x, x, x, x, x, x, x, x]\n"
+	for i := 'b'; i <= 'i'; i++ {
+		prev := string(i - 1)
+		src += fmt.Sprintf("%c: &%c [*%s, *%s, *%s, *%s, *%s, *%s, *%s, *%s, *%s, *%s]\n", i, i, prev, prev, prev, prev, prev, prev, prev, prev, prev, prev)
+	}
+
+	_, err := schema.DecodeYAML([]byte(src))
+	if !assert.Error(t, err, "schema.DecodeYAML should fail") {
+		return
+	}
+	if !assert.Contains(t, err.Error(), "too many values", "error should explain the failure") {
+		return
+	}
+}
+
+func TestMarshalYAML(t *testing.T) {
+	const src = `{
+  "type": "object",
+  "properties": {
+    "name": {"type": "string", "enum": ["true", "1"]},
+    "age": {"type": "integer", "minimum": 0}
+  },
+  "required": ["name"]
+}`
+	s, err := schema.Read(strings.NewReader(src))
+	if !assert.NoError(t, err, "schema.Read should succeed") {
+		return
+	}
+
+	buf, err := yaml.Marshal(s)
+	if !assert.NoError(t, err, "yaml.Marshal should succeed") {
+		return
+	}
+
+	s2, err := schema.ReadYAML(strings.NewReader(string(buf)))
+	if !assert.NoError(t, err, "schema.ReadYAML should succeed") {
+		return
+	}
+
+	expected, err := json.Marshal(s)
+	if !assert.NoError(t, err, "json.Marshal should succeed") {
+		return
+	}
+	actual, err := json.Marshal(s2)
+	if !assert.NoError(t, err, "json.Marshal should succeed") {
+		return
+	}
+	if !assert.JSONEq(t, string(expected), string(actual), "schema should survive a YAML round trip") {
+		return
+	}
+}
+
+func TestDecodeYAML(t *testing.T) {
+	v, err := schema.DecodeYAML([]byte("base: &base\n  200: ok\n  true: yes\nother:\n  <<: *base\n  404: missing\n"))
+	if !assert.NoError(t, err, "schema.DecodeYAML should succeed") {
+		return
+	}
+
+	buf, err := json.Marshal(v)
+	if !assert.NoError(t, err, "json.Marshal should succeed") {
+		return
+	}
+	expected := `{
+  "base": {"200": "ok", "true": "yes"},
+  "other": {"200": "ok", "true": "yes", "404": "missing"}
+}`
+	if !assert.JSONEq(t, expected, string(buf), "decoded document should match") {
+		return
+	}
+}
+
+func TestJSONToYAML(t *testing.T) {
+	buf, err := schema.JSONToYAML([]byte(`{"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]}`), 2)
+	if !assert.NoError(t, err, "schema.JSONToYAML should succeed") {
+		return
+	}
+
+	expected := "type: object\nproperties:\n  name:\n    type: string\nrequired:\n  - name\n"
+	if !assert.Equal(t, expected, string(buf), "members should keep their order") {
+		return
+	}
+}